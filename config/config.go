@@ -1,17 +1,112 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// Environments a deployment can run as; each may have an app.<env>.yml.
+const (
+	EnvDev     = "dev"
+	EnvTestnet = "testnet"
+	EnvProd    = "prod"
+)
+
+// C holds the loaded configuration. It is zero until Init has run.
+var C = &Config{}
+
+// Init loads the configuration and exits when it is incomplete or invalid.
 func Init() {
-	viper.SetConfigName("app")
-	viper.SetConfigType("yml")
-	viper.AddConfigPath(".")
-	err := viper.ReadInConfig()
+	cfg, err := Load()
 	if err != nil {
-		log.Fatalf("error on parsing configuration file: %s", err)
+		log.Fatalf("invalid configuration: %s", err)
+	}
+	*C = *cfg
+	log.Printf("⚙️  Configuration loaded for %s", C.Env)
+}
+
+// Load merges the configuration layers into the global viper instance, each
+// overriding the one before:
+//
+//  1. built-in defaults
+//  2. app.yml, then app.<env>.yml, from EXDEX_CONFIG_DIR (default ".")
+//  3. environment variables, EXDEX_ plus the key with dots as underscores,
+//     e.g. EXDEX_JWT_SECRET or EXDEX_BINANCE_APISECRET
+//  4. secret files in EXDEX_SECRETS_DIR (default /run/secrets), one per
+//     secret key and named after it, e.g. jwt.secret
+//
+// The environment comes from EXDEX_ENV, or env in the files, and must be
+// set. The merged result is decoded into a Config and validated.
+func Load() (*Config, error) {
+	v := viper.GetViper()
+	setDefaults(v)
+
+	v.SetEnvPrefix("EXDEX")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	dir := os.Getenv("EXDEX_CONFIG_DIR")
+	if dir == "" {
+		dir = "."
+	}
+	v.SetConfigType("yml")
+	v.AddConfigPath(dir)
+
+	v.SetConfigName("app")
+	if err := v.ReadInConfig(); err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("reading app.yml: %w", err)
+	}
+
+	if env := strings.ToLower(v.GetString("env")); env != "" {
+		v.SetConfigName("app." + env)
+		if err := v.MergeInConfig(); err != nil && !isNotFound(err) {
+			return nil, fmt.Errorf("reading app.%s.yml: %w", env, err)
+		}
+	}
+
+	if err := readSecretFiles(v); err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("decoding configuration: %w", err)
+	}
+	cfg.Env = strings.ToLower(cfg.Env)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func isNotFound(err error) bool {
+	var notFound viper.ConfigFileNotFoundError
+	return errors.As(err, &notFound)
+}
+
+// readSecretFiles overrides secret keys with the contents of same-named
+// files, as mounted by Docker or Kubernetes secrets. A missing directory or
+// file is not an error.
+func readSecretFiles(v *viper.Viper) error {
+	dir := os.Getenv("EXDEX_SECRETS_DIR")
+	if dir == "" {
+		dir = "/run/secrets"
+	}
+	for _, key := range secretKeys {
+		data, err := os.ReadFile(filepath.Join(dir, key))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading secret %s: %w", key, err)
+		}
+		v.Set(key, strings.TrimSpace(string(data)))
 	}
+	return nil
 }
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config is the typed view of every setting. Init fills C once the layers
+// are merged and the result has passed Validate.
+type Config struct {
+	Env       string          `mapstructure:"env"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Mongo     MongoConfig     `mapstructure:"mongo"`
+	Cron      CronConfig      `mapstructure:"cron"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Binance   BinanceConfig   `mapstructure:"binance"`
+	Paper     PaperConfig     `mapstructure:"paper"`
+	APIKeys   APIKeysConfig   `mapstructure:"api_keys"`
+	Identity  IdentityConfig  `mapstructure:"identity"`
+	TwoFactor TwoFactorConfig `mapstructure:"two_factor"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+type ServerConfig struct {
+	Port string `mapstructure:"port"`
+	// ShutdownTimeout bounds the whole shutdown, draining included.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// RequestTimeout is the deadline of each request's context, which
+	// upstream and database calls made for it inherit.
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+}
+
+type MongoConfig struct {
+	URI            string        `mapstructure:"uri"`
+	DBName         string        `mapstructure:"dbname"`
+	Username       string        `mapstructure:"username"`
+	Password       string        `mapstructure:"password"`
+	Timeouts       MongoTimeouts `mapstructure:"timeouts"`
+	SlowQuery      time.Duration `mapstructure:"slow_query"`
+	MigrateOnStart bool          `mapstructure:"migrate_on_start"`
+}
+
+type MongoTimeouts struct {
+	Read  time.Duration `mapstructure:"read"`
+	Write time.Duration `mapstructure:"write"`
+	Count time.Duration `mapstructure:"count"`
+}
+
+type CronConfig struct {
+	IsRunner bool `mapstructure:"is_runner"`
+}
+
+type JWTConfig struct {
+	Secret            string        `mapstructure:"secret"`
+	Expiration        time.Duration `mapstructure:"expiration"`
+	RefreshExpiration time.Duration `mapstructure:"refresh_expiration"`
+}
+
+type BinanceConfig struct {
+	APIKey     string        `mapstructure:"apiKey"`
+	APISecret  string        `mapstructure:"apiSecret"`
+	BaseURL    string        `mapstructure:"baseURL"`
+	WSBaseURL  string        `mapstructure:"wsBaseURL"`
+	Symbol     string        `mapstructure:"symbol"`
+	RecvWindow int64         `mapstructure:"recvWindow"` // milliseconds
+	Limits     BinanceLimits `mapstructure:"limits"`
+}
+
+type BinanceLimits struct {
+	WeightPerMinute int           `mapstructure:"weight_per_minute"`
+	OrdersPer10s    int           `mapstructure:"orders_per_10s"`
+	OrdersPerDay    int           `mapstructure:"orders_per_day"`
+	MaxWait         time.Duration `mapstructure:"max_wait"`
+}
+
+type PaperConfig struct {
+	FeeRate         float64            `mapstructure:"fee_rate"`
+	InitialBalances map[string]float64 `mapstructure:"initial_balances"`
+}
+
+type APIKeysConfig struct {
+	EncryptionKey string `mapstructure:"encryption_key"`
+}
+
+type IdentityConfig struct {
+	Provider  string         `mapstructure:"provider"`
+	BaseURL   string         `mapstructure:"base_url"`
+	Timeout   time.Duration  `mapstructure:"timeout"`
+	FakeUsers []FakeIdentity `mapstructure:"fake_users"`
+}
+
+// FakeIdentity is a token the fake identity provider accepts.
+type FakeIdentity struct {
+	Token         string `mapstructure:"token"`
+	ID            uint   `mapstructure:"id"`
+	Email         string `mapstructure:"email"`
+	FullName      string `mapstructure:"full_name"`
+	AccountNumber string `mapstructure:"account_number"`
+	Role          string `mapstructure:"role"`
+}
+
+type TwoFactorConfig struct {
+	Issuer        string        `mapstructure:"issuer"`
+	EncryptionKey string        `mapstructure:"encryption_key"`
+	StepUpTTL     time.Duration `mapstructure:"step_up_ttl"`
+	Required      bool          `mapstructure:"required"`
+}
+
+// RateLimitConfig only holds the master switch. Buckets are named by the
+// routes that use them, so their overrides (rate_limit.<name>.max and
+// rate_limit.<name>.window) are looked up by name.
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// secretKeys are the settings that must never be committed. They can be
+// read from files in the secrets directory, named after the key.
+var secretKeys = []string{
+	"jwt.secret",
+	"binance.apiKey",
+	"binance.apiSecret",
+	"api_keys.encryption_key",
+	"two_factor.encryption_key",
+	"mongo.password",
+}
+
+// setDefaults is the bottom layer. Every key has a default, even an empty
+// one, so environment variables can override keys no file mentions.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("env", "") // required, so a deploy cannot fall back to dev
+	v.SetDefault("server.port", "3003")
+	v.SetDefault("server.shutdown_timeout", "30s")
+	v.SetDefault("server.request_timeout", "30s")
+
+	v.SetDefault("mongo.uri", "mongodb://localhost:27017")
+	v.SetDefault("mongo.dbname", "exdex")
+	v.SetDefault("mongo.username", "")
+	v.SetDefault("mongo.password", "")
+	v.SetDefault("mongo.timeouts.read", "10s")
+	v.SetDefault("mongo.timeouts.write", "10s")
+	v.SetDefault("mongo.timeouts.count", "5s")
+	v.SetDefault("mongo.slow_query", "500ms")
+	v.SetDefault("mongo.migrate_on_start", true)
+
+	v.SetDefault("cron.is_runner", false)
+
+	v.SetDefault("jwt.secret", "")
+	v.SetDefault("jwt.expiration", "2h")
+	v.SetDefault("jwt.refresh_expiration", "720h")
+
+	v.SetDefault("binance.apiKey", "")
+	v.SetDefault("binance.apiSecret", "")
+	v.SetDefault("binance.baseURL", "")
+	v.SetDefault("binance.wsBaseURL", "")
+	v.SetDefault("binance.symbol", "BTCUSDT")
+	v.SetDefault("binance.recvWindow", 5000)
+	v.SetDefault("binance.limits.weight_per_minute", 6000)
+	v.SetDefault("binance.limits.orders_per_10s", 100)
+	v.SetDefault("binance.limits.orders_per_day", 200000)
+	v.SetDefault("binance.limits.max_wait", "2s")
+
+	v.SetDefault("paper.fee_rate", 0.001)
+	v.SetDefault("paper.initial_balances", map[string]float64{"USDT": 10000})
+
+	v.SetDefault("api_keys.encryption_key", "")
+
+	v.SetDefault("identity.provider", "exdex")
+	v.SetDefault("identity.base_url", "https://api.exdex.com")
+	v.SetDefault("identity.timeout", "10s")
+	v.SetDefault("identity.fake_users", []map[string]interface{}{})
+
+	v.SetDefault("two_factor.issuer", "EXDEX")
+	v.SetDefault("two_factor.encryption_key", "")
+	v.SetDefault("two_factor.step_up_ttl", "10m")
+	v.SetDefault("two_factor.required", false)
+
+	v.SetDefault("rate_limit.enabled", true)
+}
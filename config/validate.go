@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// placeholderMarkers flag values copied from examples and never replaced.
+var placeholderMarkers = []string{"change-me", "changeme", "your_", "your-", "placeholder", "<"}
+
+// devOnlyMarker flags the secrets app.dev.yml ships with. They are only
+// accepted in the dev environment.
+const devOnlyMarker = "dev-only"
+
+// IsPlaceholder reports whether value looks like an example value rather
+// than a real secret.
+func IsPlaceholder(value string) bool {
+	v := strings.ToLower(value)
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(v, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate reports every problem at once, so a bad deploy can be fixed in
+// one go.
+func (c *Config) Validate() error {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	required := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			fail("%s is required", key)
+		}
+	}
+	secret := func(key, value string) {
+		required(key, value)
+		if IsPlaceholder(value) {
+			fail("%s is still a placeholder", key)
+		}
+		if c.Env != EnvDev && strings.Contains(strings.ToLower(value), devOnlyMarker) {
+			fail("%s is a dev-only value, set a real one for %s", key, c.Env)
+		}
+	}
+	positive := func(key string, value time.Duration) {
+		if value <= 0 {
+			fail("%s must be a positive duration", key)
+		}
+	}
+
+	switch c.Env {
+	case EnvDev, EnvTestnet, EnvProd:
+	case "":
+		fail("env is required: set EXDEX_ENV to %s, %s or %s", EnvDev, EnvTestnet, EnvProd)
+	default:
+		fail("env must be one of %s, %s, %s, got %q", EnvDev, EnvTestnet, EnvProd, c.Env)
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		fail("server.port must be a port number, got %q", c.Server.Port)
+	}
+	positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+
+	required("mongo.uri", c.Mongo.URI)
+	required("mongo.dbname", c.Mongo.DBName)
+	if c.Mongo.Password != "" && IsPlaceholder(c.Mongo.Password) {
+		fail("mongo.password is still a placeholder")
+	}
+	positive("mongo.timeouts.read", c.Mongo.Timeouts.Read)
+	positive("mongo.timeouts.write", c.Mongo.Timeouts.Write)
+	positive("mongo.timeouts.count", c.Mongo.Timeouts.Count)
+
+	secret("jwt.secret", c.JWT.Secret)
+	positive("jwt.expiration", c.JWT.Expiration)
+	positive("jwt.refresh_expiration", c.JWT.RefreshExpiration)
+
+	secret("binance.apiKey", c.Binance.APIKey)
+	secret("binance.apiSecret", c.Binance.APISecret)
+	endpoint := func(key, value string) {
+		if value == "" {
+			fail("%s is required", key)
+		} else if u, err := url.Parse(value); err != nil || u.Host == "" {
+			fail("%s is not a valid URL: %q", key, value)
+		}
+	}
+	endpoint("binance.baseURL", c.Binance.BaseURL)
+	endpoint("binance.wsBaseURL", c.Binance.WSBaseURL)
+	// Binance rejects recvWindow above 60 seconds.
+	if c.Binance.RecvWindow < 1 || c.Binance.RecvWindow > 60000 {
+		fail("binance.recvWindow must be between 1 and 60000 ms, got %d", c.Binance.RecvWindow)
+	}
+
+	secret("api_keys.encryption_key", c.APIKeys.EncryptionKey)
+	secret("two_factor.encryption_key", c.TwoFactor.EncryptionKey)
+
+	switch c.Identity.Provider {
+	case "exdex":
+		required("identity.base_url", c.Identity.BaseURL)
+	case "fake":
+	default:
+		fail("identity.provider must be exdex or fake, got %q", c.Identity.Provider)
+	}
+
+	if c.Env == EnvProd {
+		if strings.Contains(c.Binance.BaseURL, "testnet") || strings.Contains(c.Binance.WSBaseURL, "testnet") {
+			fail("binance URLs point at the testnet in prod")
+		}
+		if c.Identity.Provider == "fake" {
+			fail("identity.provider fake is not allowed in prod")
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("\n  - " + strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionIndexes declares the indexes a collection should have.
+type CollectionIndexes struct {
+	Collection string
+	Indexes    []mongo.IndexModel
+}
+
+func index(name string, keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+}
+
+func unique(name string, keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name).SetUnique(true)}
+}
+
+// Registry lists every index the services rely on. EnsureIndexes creates the
+// missing ones at startup; to change an existing index, give it a new name
+// and drop the old one in a migration.
+var Registry = []CollectionIndexes{
+	{"users", []mongo.IndexModel{
+		unique("unique_email", bson.D{{Key: "email", Value: 1}}),
+		unique("unique_exdex_user_id", bson.D{{Key: "exdex_user_id", Value: 1}}),
+		index("created_at", bson.D{{Key: "created_at", Value: -1}}),
+	}},
+	{"orders", []mongo.IndexModel{
+		index("user_id_created_at", bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}),
+		index("order_id", bson.D{{Key: "order_id", Value: 1}}),
+		index("order_list_id", bson.D{{Key: "order_list_id", Value: 1}}),
+		index("parent_id", bson.D{{Key: "parent_id", Value: 1}}),
+		index("paper_status", bson.D{{Key: "paper", Value: 1}, {Key: "status", Value: 1}}),
+		index("created_at", bson.D{{Key: "created_at", Value: -1}}),
+	}},
+	{"refresh_tokens", []mongo.IndexModel{
+		unique("unique_token_hash", bson.D{{Key: "token_hash", Value: 1}}),
+		index("user_id_expires_at", bson.D{{Key: "user_id", Value: 1}, {Key: "expires_at", Value: 1}}),
+		index("family_id", bson.D{{Key: "family_id", Value: 1}}),
+	}},
+	{"revoked_tokens", []mongo.IndexModel{
+		index("kind_value", bson.D{{Key: "kind", Value: 1}, {Key: "value", Value: 1}}),
+		// Revocations are only needed until the token would expire anyway.
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("ttl_expires_at").SetExpireAfterSeconds(0)},
+	}},
+	{"api_keys", []mongo.IndexModel{
+		unique("unique_key_hash", bson.D{{Key: "key_hash", Value: 1}}),
+		index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+	}},
+	{"api_nonces", []mongo.IndexModel{
+		unique("unique_nonce", bson.D{{Key: "nonce", Value: 1}}),
+		// A signature only needs remembering while its timestamp is valid.
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("ttl_expires_at").SetExpireAfterSeconds(0)},
+	}},
+	{"step_ups", []mongo.IndexModel{
+		unique("unique_session_id", bson.D{{Key: "session_id", Value: 1}}),
+	}},
+	{"two_factor_failures", []mongo.IndexModel{
+		unique("unique_user_id", bson.D{{Key: "user_id", Value: 1}}),
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("ttl_expires_at").SetExpireAfterSeconds(0)},
+	}},
+	{"paper_accounts", []mongo.IndexModel{
+		unique("unique_user_id", bson.D{{Key: "user_id", Value: 1}}),
+	}},
+	{"signal_webhooks", []mongo.IndexModel{
+		unique("unique_user_id", bson.D{{Key: "user_id", Value: 1}}),
+		index("token_hash", bson.D{{Key: "token_hash", Value: 1}}),
+	}},
+	{"signal_logs", []mongo.IndexModel{
+		index("user_id_created_at", bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}),
+	}},
+	{"copy_leaders", []mongo.IndexModel{
+		unique("unique_user_id", bson.D{{Key: "user_id", Value: 1}}),
+		index("active", bson.D{{Key: "active", Value: 1}}),
+	}},
+	{"copy_subscriptions", []mongo.IndexModel{
+		unique("unique_leader_follower", bson.D{{Key: "leader_id", Value: 1}, {Key: "follower_id", Value: 1}}),
+		index("follower_id", bson.D{{Key: "follower_id", Value: 1}}),
+		index("leader_id_status", bson.D{{Key: "leader_id", Value: 1}, {Key: "status", Value: 1}}),
+	}},
+	{"copy_orders", []mongo.IndexModel{
+		index("subscription_id_created_at", bson.D{{Key: "subscription_id", Value: 1}, {Key: "created_at", Value: -1}}),
+		index("order_id", bson.D{{Key: "order_id", Value: 1}}),
+		unique("unique_subscription_source_fill", bson.D{{Key: "subscription_id", Value: 1}, {Key: "source_order_id", Value: 1}, {Key: "source_trade_id", Value: 1}}),
+	}},
+	{"dca_plans", []mongo.IndexModel{
+		index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+		index("status_next_run_at", bson.D{{Key: "status", Value: 1}, {Key: "next_run_at", Value: 1}}),
+	}},
+	{"dca_runs", []mongo.IndexModel{
+		index("plan_id_executed_at", bson.D{{Key: "plan_id", Value: 1}, {Key: "executed_at", Value: -1}}),
+	}},
+	{"grid_bots", []mongo.IndexModel{
+		index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+		index("status", bson.D{{Key: "status", Value: 1}}),
+		index("levels_order_id", bson.D{{Key: "levels.order_id", Value: 1}}),
+	}},
+	{"algo_orders", []mongo.IndexModel{
+		index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+		index("status", bson.D{{Key: "status", Value: 1}}),
+	}},
+	{"strategy_instances", []mongo.IndexModel{
+		index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+		index("status", bson.D{{Key: "status", Value: 1}}),
+	}},
+	{"backtest_runs", []mongo.IndexModel{
+		index("user_id_created_at", bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}),
+	}},
+	{"backtest_trades", []mongo.IndexModel{
+		index("run_id_time", bson.D{{Key: "run_id", Value: 1}, {Key: "time", Value: 1}}),
+	}},
+	{"candles", []mongo.IndexModel{
+		unique("unique_symbol_interval_open_time", bson.D{{Key: "symbol", Value: 1}, {Key: "interval", Value: 1}, {Key: "open_time", Value: 1}}),
+	}},
+	{"schema_migrations", []mongo.IndexModel{
+		unique("unique_version", bson.D{{Key: "version", Value: 1}}),
+	}},
+}
+
+// EnsureIndexes creates every index in Registry that db does not have yet.
+// Creating an index that already exists with the same definition is a no-op.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, c := range Registry {
+		if _, err := db.Collection(c.Collection).Indexes().CreateMany(ctx, c.Indexes); err != nil {
+			return fmt.Errorf("indexes on %s: %w", c.Collection, err)
+		}
+	}
+	log.Printf("✅ Indexes ensured on %d collections.", len(Registry))
+	return nil
+}
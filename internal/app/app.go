@@ -1,14 +1,20 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"sync"
 
 	"exdex/config"
 	"exdex/internal/router"
 	"exdex/internal/src/handler"
 	"exdex/internal/src/repository"
+	"exdex/internal/src/services"
 	database "exdex/server/databases"
 	"exdex/server/info"
+	"exdex/server/lifecycle"
+	"exdex/server/middleware"
 	"exdex/server/validator"
 )
 
@@ -20,27 +26,152 @@ type impl struct {
 	r router.Router
 }
 
+// Start runs the server until SIGINT or SIGTERM, then shuts it down within
+// server.shutdown_timeout.
 func (i *impl) Start() {
-
 	i.Init()
-	i.r.Start()
-
+	m := lifecycle.New()
+	i.register(m)
+	if err := m.Run(config.C.Server.ShutdownTimeout); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	log.Println("✅ Server stopped")
 }
+
 func NewApp(rout router.Router) App {
 	return &impl{
 		r: rout,
 	}
 }
 
-var once sync.Once
+var (
+	once          sync.Once
+	bootstrapOnce sync.Once
+)
 
-func (i *impl) Init() {
-	once.Do(func() {
+// Bootstrap loads configuration and connects the database and repository,
+// everything a command needs short of serving. With runMigrations false,
+// pending migrations are left for the migrate command to apply.
+func Bootstrap(runMigrations bool) {
+	bootstrapOnce.Do(func() {
 		config.Init()
+		if !runMigrations {
+			config.C.Mongo.MigrateOnStart = false
+		}
 		validator.Init()
 		database.Init()
 		info.ServerInfoInit()
 		repository.Init()
-		go handler.WsInit()
 	})
 }
+
+func (i *impl) Init() {
+	once.Do(func() {
+		Bootstrap(true)
+		middleware.SetRevocationCheck(services.IsAccessTokenRevoked)
+	})
+}
+
+// register wires the subsystems in start order; they stop in reverse. On
+// shutdown new orders are refused first, then schedulers stop, in-flight
+// requests drain, and the streams they may still rely on close last.
+func (i *impl) register(m *lifecycle.Manager) {
+	m.Register(lifecycle.Hook{
+		Name: "mongo",
+		Stop: database.Close,
+	})
+
+	userStream := background(m, "user data stream", handler.WsInit)
+	m.Register(lifecycle.Hook{
+		Name:  "user data stream",
+		Start: userStream.start,
+		Stop:  userStream.stop,
+	})
+
+	marketData := background(m, "market data hub", func(ctx context.Context) error {
+		services.MarketData.Run(ctx)
+		return nil
+	})
+	m.Register(lifecycle.Hook{
+		Name:  "websocket hubs",
+		Start: marketData.start,
+		Stop: func(ctx context.Context) error {
+			services.UserEvents.Close()
+			return marketData.stop(ctx)
+		},
+	})
+
+	m.Register(lifecycle.Hook{
+		Name: "http",
+		Start: func(context.Context) error {
+			go func() {
+				if err := i.r.Start(); err != nil {
+					m.Fail(fmt.Errorf("http server: %w", err))
+				}
+			}()
+			return nil
+		},
+		Stop: i.r.Shutdown,
+	})
+
+	m.Register(lifecycle.Hook{
+		Name: "schedulers",
+		Start: func(context.Context) error {
+			if config.C.Cron.IsRunner {
+				services.StartCopyTrading()
+				services.StartPaperMatcher()
+				services.StartGridMonitor()
+				services.StartDCAScheduler()
+				services.ResumeAlgoOrders()
+				services.StartStrategyRuntime()
+			}
+			return nil
+		},
+		Stop: services.StopWorkers,
+	})
+
+	m.Register(lifecycle.Hook{
+		Name: "order intake",
+		Stop: func(context.Context) error {
+			services.StopNewOrders()
+			return nil
+		},
+	})
+}
+
+// task is a long-running subsystem with its own context.
+type task struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	run    func(ctx context.Context)
+}
+
+// background wraps run as a task. An error from run makes m shut down.
+func background(m *lifecycle.Manager, name string, run func(ctx context.Context) error) *task {
+	return &task{run: func(ctx context.Context) {
+		if err := run(ctx); err != nil && ctx.Err() == nil {
+			m.Fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}}
+}
+
+func (t *task) start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		t.run(ctx)
+	}()
+	return nil
+}
+
+func (t *task) stop(ctx context.Context) error {
+	t.cancel()
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
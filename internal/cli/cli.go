@@ -0,0 +1,261 @@
+// Package cli implements the server binary's subcommands. Every command
+// goes through app.Bootstrap, so it sees the same configuration and
+// repository as the running server.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"exdex/internal/app"
+	"exdex/internal/router"
+	"exdex/internal/src/services"
+	"exdex/migrations"
+	database "exdex/server/databases"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+// commands is filled in init because the commands print their own usage.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"serve":         {"serve", serve},
+		"migrate":       {"migrate up | down [--steps N] | status", migrate},
+		"reconcile":     {"reconcile --user ID", reconcile},
+		"create-admin":  {"create-admin --email EMAIL [--exdex-user-id ID]", createAdmin},
+		"replay-events": {"replay-events --from FILE", replayEvents},
+		"rotate-keys":   {"rotate-keys [--old-api-key KEY] [--old-two-factor-key KEY]", rotateKeys},
+	}
+}
+
+// errUsage means the arguments were wrong; the usage has been printed.
+var errUsage = errors.New("usage")
+
+// Run executes the command named by args[0] and returns the exit code.
+// Without arguments the server is started.
+func Run(args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(os.Stdout)
+		return 0
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, args); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Usage: exdex <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+}
+
+// flags returns a flag set for the named command that reports errors
+// instead of exiting.
+func flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: exdex %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func serve(ctx context.Context, args []string) error {
+	if err := flags("serve").Parse(args); err != nil {
+		return err
+	}
+	app.NewApp(router.NewRouter()).Start()
+	return nil
+}
+
+func migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: exdex %s\n", commands["migrate"].usage)
+		return errUsage
+	}
+	sub, args := args[0], args[1:]
+	fs := flags("migrate")
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	app.Bootstrap(false)
+	db := database.DB
+
+	switch sub {
+	case "up":
+		ran, err := migrations.Up(ctx, db)
+		for _, m := range ran {
+			fmt.Printf("applied %d %s\n", m.Version, m.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("already up to date")
+		}
+		return err
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("--steps must be at least 1")
+		}
+		reverted, err := migrations.Down(ctx, db, *steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %d %s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		states, err := migrations.Status(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			applied := "pending"
+			switch {
+			case s.AppliedAt != nil:
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			case s.InProgressSince != nil:
+				applied = "in progress since " + s.InProgressSince.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-30s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\nUsage: exdex %s\n", sub, commands["migrate"].usage)
+		return errUsage
+	}
+}
+
+func reconcile(ctx context.Context, args []string) error {
+	fs := flags("reconcile")
+	userID := fs.String("user", "", "id of the user whose open orders to reconcile")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *userID == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	app.Bootstrap(false)
+	res, err := services.ReconcileOrders(ctx, *userID)
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func createAdmin(ctx context.Context, args []string) error {
+	fs := flags("create-admin")
+	email := fs.String("email", "", "email of the user to make an admin")
+	exdexUserID := fs.Uint("exdex-user-id", 0, "EXDEX user id, to create the user if they do not exist yet")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*email) == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	app.Bootstrap(false)
+	user, err := services.AdminServices{}.CreateAdmin(ctx, *email, *exdexUserID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (%s) is now an admin\n", user.Email, user.ID)
+	return nil
+}
+
+func replayEvents(ctx context.Context, args []string) error {
+	fs := flags("replay-events")
+	from := fs.String("from", "", "file of user-data stream events, one JSON object per line")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		fs.Usage()
+		return errUsage
+	}
+	f, err := os.Open(*from)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	app.Bootstrap(false)
+	res, err := services.ReplayUserEvents(ctx, f)
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func rotateKeys(ctx context.Context, args []string) error {
+	fs := flags("rotate-keys")
+	oldAPIKey := fs.String("old-api-key", "", "previous api_keys.encryption_key")
+	oldTwoFactorKey := fs.String("old-two-factor-key", "", "previous two_factor.encryption_key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *oldAPIKey == "" && *oldTwoFactorKey == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	app.Bootstrap(false)
+	res, err := services.RotateEncryptionKeys(ctx, *oldAPIKey, *oldTwoFactorKey)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(res); err != nil {
+		return err
+	}
+	if len(res.Failed) > 0 {
+		return fmt.Errorf("%d secret(s) could not be rotated", len(res.Failed))
+	}
+	return nil
+}
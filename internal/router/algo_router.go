@@ -0,0 +1,21 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
+)
+
+func (i *impel) algoRouter(r fiber.Router) {
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+	limit := i.orderLimit
+
+	r.Post("/", write, limit, handler.CreateAlgoOrder)
+	r.Get("/", handler.GetAlgoOrders)
+	r.Get("/:id", handler.GetAlgoOrder)
+	r.Post("/:id/pause", write, handler.PauseAlgoOrder)
+	r.Post("/:id/resume", write, limit, handler.ResumeAlgoOrder)
+	r.Post("/:id/cancel", write, handler.CancelAlgoOrder)
+}
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+)
+
+func (i *impel) apiKeyRouter(r fiber.Router) {
+	r.Post("/", middleware.RequireStepUp(), handler.CreateAPIKey)
+	r.Get("/", handler.GetAPIKeys)
+	r.Delete("/:id", handler.RevokeAPIKey)
+}
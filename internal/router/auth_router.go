@@ -1,11 +1,38 @@
 package router
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 
 	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
 )
 
 func (i *impel) authRouter(r fiber.Router) {
-	r.Post("/exdex/check", handler.CheckExdexUserInfo)
+	r.Post("/exdex/check", middleware.RateLimit("login", 20, time.Minute), handler.CheckExdexUserInfo)
+	r.Post("/token/refresh", middleware.RateLimit("refresh", 30, time.Minute), handler.RefreshToken)
+}
+
+func (i *impel) sessionRouter(r fiber.Router) {
+	sessionOnly := middleware.SessionOnly()
+
+	r.Post("/logout", sessionOnly, handler.Logout)
+	r.Post("/logout/all", sessionOnly, handler.LogoutAll)
+	r.Get("/sessions", sessionOnly, handler.GetSessions)
+}
+
+func (i *impel) adminRouter(r fiber.Router) {
+	r.Get("/roles", handler.GetRoles)
+	r.Get("/upstreams", handler.GetUpstreamStatus)
+
+	r.Get("/users", middleware.RequirePermission(rbac.PermUsersRead), handler.AdminListUsers)
+	r.Get("/users/:id", middleware.RequirePermission(rbac.PermUsersRead), handler.AdminGetUser)
+	r.Put("/users/:id/role", middleware.RequirePermission(rbac.PermUsersManage), middleware.RequireStepUp(), handler.AdminSetUserRole)
+	r.Post("/users/:id/suspend", middleware.RequirePermission(rbac.PermUsersManage), handler.AdminSuspendUser)
+	r.Post("/users/:id/unsuspend", middleware.RequirePermission(rbac.PermUsersManage), handler.AdminUnsuspendUser)
+	r.Post("/users/:id/sessions/revoke", middleware.RequirePermission(rbac.PermSessionsRevoke), handler.AdminRevokeUserSessions)
+
+	r.Get("/orders", middleware.RequirePermission(rbac.PermOrdersReadAny), handler.AdminListOrders)
 }
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+)
+
+func (i *impel) backtestRouter(r fiber.Router) {
+	r.Post("/", handler.StartBacktest)
+	r.Get("/", handler.GetBacktests)
+	r.Get("/:id", handler.GetBacktest)
+	r.Get("/:id/trades", handler.GetBacktestTrades)
+}
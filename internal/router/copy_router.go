@@ -0,0 +1,27 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
+)
+
+func (i *impel) copyRouter(r fiber.Router) {
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+	limit := i.orderLimit
+
+	r.Get("/leaders", handler.GetCopyLeaders)
+	r.Get("/leader", handler.GetMyCopyLeader)
+	r.Post("/leader", middleware.RequirePermission(rbac.PermCopyLead), handler.BecomeCopyLeader)
+	r.Delete("/leader", handler.StopCopyLeading)
+
+	r.Post("/subscriptions", write, limit, handler.FollowCopyLeader)
+	r.Get("/subscriptions", handler.GetCopySubscriptions)
+	r.Post("/subscriptions/:id/pause", write, handler.PauseCopySubscription)
+	r.Post("/subscriptions/:id/resume", write, limit, handler.ResumeCopySubscription)
+	r.Delete("/subscriptions/:id", write, handler.UnfollowCopyLeader)
+	r.Get("/subscriptions/:id/orders", handler.GetCopyOrders)
+	r.Get("/subscriptions/:id/performance", handler.GetCopyPerformance)
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
+)
+
+func (i *impel) dcaRouter(r fiber.Router) {
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+	limit := i.orderLimit
+
+	r.Post("/", write, limit, handler.CreateDCAPlan)
+	r.Get("/", handler.GetDCAPlans)
+	r.Get("/:id", handler.GetDCAPlan)
+	r.Get("/:id/runs", handler.GetDCARuns)
+	r.Post("/:id/pause", write, handler.PauseDCAPlan)
+	r.Post("/:id/resume", write, limit, handler.ResumeDCAPlan)
+	r.Delete("/:id", write, handler.DeleteDCAPlan)
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
+)
+
+func (i *impel) gridRouter(r fiber.Router) {
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+	limit := i.orderLimit
+
+	r.Post("/", write, limit, handler.CreateGridBot)
+	r.Get("/", handler.GetGridBots)
+	r.Get("/:id", handler.GetGridBot)
+	r.Post("/:id/stop", write, limit, handler.StopGridBot)
+}
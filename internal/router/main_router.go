@@ -1,34 +1,51 @@
 package router
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
-	"github.com/spf13/viper"
 
+	"exdex/config"
 	"exdex/internal/src/handler"
 	"exdex/server/middleware"
+	"exdex/server/rbac"
 )
 
 type Ctx = *fiber.Ctx
 
 type Router interface {
-	Start()
+	// Start registers the routes and serves until Shutdown is called.
+	Start() error
+	// Shutdown stops accepting connections and waits for in-flight
+	// requests to finish, or for ctx to expire.
+	Shutdown(ctx context.Context) error
 }
 
 type impel struct {
 	fiver *fiber.App
+	// orderLimit is the one "orders" bucket shared by every route that
+	// places orders, on top of the per-user limit.
+	orderLimit fiber.Handler
 }
 
-func (i *impel) Start() {
+func (i *impel) Start() error {
 	app := i.fiver
+	i.orderLimit = middleware.RateLimit("orders", 60, time.Minute)
+	app.Use(middleware.Metrics())
+	app.Use(middleware.RequestContext(config.C.Server.RequestTimeout))
+	app.Use(middleware.RefuseWritesWhileDraining())
 	app.Get("/", func(c Ctx) error {
 		return c.SendString("Hello, World!")
 	})
+	app.Get("/healthz", handler.Healthz)
+	app.Get("/readyz", handler.Readyz)
+	app.Get("/metrics", handler.Metrics)
 
 	app.Get("/ws", websocket.New(handler.WebSocketHandler))
+	app.Get("/ws/private", handler.PrivateWSUpgrade, websocket.New(handler.PrivateWebSocketHandler))
 
 	// Example group
 	v1 := app.Group("/v1")
@@ -36,10 +53,15 @@ func (i *impel) Start() {
 		api := v1.Group("api")
 		{
 			i.authRouter(api)
+			signal := api.Group("signal")
+			{
+				i.signalRouter(signal)
+			}
 		}
 
-		auth := v1.Group("auth", middleware.JWTMiddleware())
+		auth := v1.Group("auth", middleware.AuthMiddleware(), middleware.RateLimit("user", 300, time.Minute))
 		{
+			i.sessionRouter(auth)
 			trade := auth.Group("tarde")
 			{
 				i.tradeRouter(trade, app)
@@ -48,25 +70,66 @@ func (i *impel) Start() {
 			{
 				i.orderRouter(order)
 			}
+			ws := auth.Group("ws")
+			{
+				i.wsRouter(ws)
+			}
+			signal := auth.Group("signal")
+			{
+				i.signalSettingsRouter(signal)
+			}
+			dca := auth.Group("dca")
+			{
+				i.dcaRouter(dca)
+			}
+			grid := auth.Group("grid")
+			{
+				i.gridRouter(grid)
+			}
+			algo := auth.Group("algo")
+			{
+				i.algoRouter(algo)
+			}
+			paper := auth.Group("paper")
+			{
+				i.paperRouter(paper)
+			}
+			backtest := auth.Group("backtest")
+			{
+				i.backtestRouter(backtest)
+			}
+			strategy := auth.Group("strategy")
+			{
+				i.strategyRouter(strategy)
+			}
+			copyTrading := auth.Group("copy")
+			{
+				i.copyRouter(copyTrading)
+			}
+			apiKeys := auth.Group("api-keys", middleware.SessionOnly())
+			{
+				i.apiKeyRouter(apiKeys)
+			}
+			twoFactor := auth.Group("2fa", middleware.SessionOnly(), middleware.RateLimit("two_factor", 10, time.Minute))
+			{
+				i.twoFactorRouter(twoFactor)
+			}
 		}
 
-	}
-
-	// addr := fmt.Sprintf(":%s", viper.GetString("server.port"))
+		admin := v1.Group("admin", middleware.JWTMiddleware(), middleware.RequirePermission(rbac.PermAdminAccess), middleware.RateLimit("admin", 120, time.Minute))
+		{
+			i.adminRouter(admin)
+		}
 
-	// Start server in goroutine
-	// go func() {
-	// 	fmt.Printf("🚀 Server starting on port %s\n", viper.GetString("server.port"))
-	// 	if err := app.Listen(addr); err != nil {
-	// 		log.Printf("❌ Server error: %v\n", err)
-	// 	}
-	// }()
+	}
 
 	// ✅ Print all routes
 	PrintEndpoints(app)
-	log.Fatal(i.fiver.Listen(fmt.Sprintf(":%s", viper.GetString("server.port"))))
-	// Wait for shutdown signal and call graceful shutdown
-	// waitForShutdown(app)
+	return i.fiver.Listen(fmt.Sprintf(":%s", config.C.Server.Port))
+}
+
+func (i *impel) Shutdown(ctx context.Context) error {
+	return i.fiver.ShutdownWithContext(ctx)
 }
 
 func PrintEndpoints(app *fiber.App) {
@@ -78,31 +141,8 @@ func PrintEndpoints(app *fiber.App) {
 	}
 }
 
-// Separate function for graceful shutdown
-
 func NewRouter() Router {
 	return &impel{
 		fiver: fiber.New(),
 	}
 }
-
-// func waitForShutdown(app *fiber.App) {
-// 	quit := make(chan os.Signal, 1)
-// 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-// 	<-quit
-
-// 	fmt.Println("🛑 Shutdown signal received")
-
-// 	// Context with timeout for cleanup
-// 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-// 	defer cancel()
-
-// 	if err := app.Shutdown(); err != nil {
-// 		log.Printf("❌ Error during shutdown: %v\n", err)
-// 	}
-
-// 	select {
-// 	case <-ctx.Done():
-// 		fmt.Println("✅ Server gracefully stopped")
-// 	}
-// }
@@ -5,18 +5,24 @@ import (
 	"github.com/gofiber/websocket/v2"
 
 	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
 )
 
 func (i *impel) orderRouter(r fiber.Router) {
-	r.Post("/market", handler.OrderHandler)
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+	read := middleware.RequirePermission(rbac.PermOrdersRead)
+	limit := i.orderLimit
+
+	r.Post("/market", write, limit, handler.OrderHandler)
 	r.Get("/price", handler.GetOrderPriceHandler)
-	r.Post("/cancel-oco", handler.GetOrderPriceHandler)
-	r.Get("/history", handler.GetOrderAllHistory)
+	r.Post("/cancel-oco", write, limit, handler.GetOrderPriceHandler)
+	r.Get("/history", read, handler.GetOrderAllHistory)
 
-	r.Post("/market-order", handler.MarketOrder)
-	r.Post("/market-order-tpsl", handler.MarketOrderTPSL)
-	r.Post("/limit-order", handler.LimitOrder)
-	r.Post("/limit-order-tpsl", handler.LimitOrderTPSL)
+	r.Post("/market-order", write, limit, handler.MarketOrder)
+	r.Post("/market-order-tpsl", write, limit, handler.MarketOrderTPSL)
+	r.Post("/limit-order", write, limit, handler.LimitOrder)
+	r.Post("/limit-order-tpsl", write, limit, handler.LimitOrderTPSL)
 
 	// inside Start()
 	r.Use("/ws", func(c *fiber.Ctx) error {
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
+)
+
+func (i *impel) paperRouter(r fiber.Router) {
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+
+	r.Get("/account", handler.GetPaperAccount)
+	r.Post("/reset", write, handler.ResetPaperAccount)
+	r.Post("/mode", write, handler.SetPaperMode)
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
+)
+
+// signalRouter is public; the signal token authenticates the caller.
+func (i *impel) signalRouter(r fiber.Router) {
+	// Alerts come from shared infrastructure, so limit per token, not per IP.
+	byToken := func(c *fiber.Ctx) string {
+		if token := c.Params("token"); token != "" {
+			return "token:" + token
+		}
+		return "ip:" + c.IP()
+	}
+	limit := middleware.RateLimitBy("signal", 30, time.Minute, byToken)
+	// Executed alerts place orders, so they also count against the owner's
+	// orders limit, shared with their other order routes.
+	owner := middleware.SignalOwner()
+
+	r.Post("/", limit, owner, i.orderLimit, handler.ReceiveSignal)
+	r.Post("/:token", limit, owner, i.orderLimit, handler.ReceiveSignal)
+}
+
+func (i *impel) signalSettingsRouter(r fiber.Router) {
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+
+	r.Post("/token", write, handler.CreateSignalToken)
+	r.Delete("/token", write, handler.DisableSignalToken)
+	r.Get("/logs", handler.GetSignalLogs)
+}
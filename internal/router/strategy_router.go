@@ -0,0 +1,23 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+	"exdex/server/middleware"
+	"exdex/server/rbac"
+)
+
+func (i *impel) strategyRouter(r fiber.Router) {
+	write := middleware.RequirePermission(rbac.PermOrdersWrite)
+	limit := i.orderLimit
+
+	r.Post("/", write, limit, handler.CreateStrategy)
+	r.Get("/", handler.GetStrategies)
+	r.Get("/:id", handler.GetStrategyStatus)
+	r.Get("/:id/status", handler.GetStrategyStatus)
+	r.Post("/:id/start", write, limit, handler.StartStrategy)
+	r.Post("/:id/stop", write, handler.StopStrategy)
+	r.Post("/:id/pause", write, handler.PauseStrategy)
+	r.Delete("/:id", write, handler.DeleteStrategy)
+}
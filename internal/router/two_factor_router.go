@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+)
+
+func (i *impel) twoFactorRouter(r fiber.Router) {
+	r.Get("/status", handler.GetTwoFactorStatus)
+	r.Post("/enroll", handler.EnrollTwoFactor)
+	r.Post("/confirm", handler.ConfirmTwoFactor)
+	r.Post("/verify", handler.VerifyTwoFactor)
+	r.Post("/disable", handler.DisableTwoFactor)
+	r.Post("/recovery-codes", handler.RegenerateRecoveryCodes)
+}
@@ -0,0 +1,11 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/handler"
+)
+
+func (i *impel) wsRouter(r fiber.Router) {
+	r.Post("/ticket", handler.IssueWSTicket)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	"exdex/server/rbac"
+	response "exdex/server/responses"
+)
+
+func pagination(c *fiber.Ctx) (int, int) {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 20
+	}
+	return page, limit
+}
+
+func GetRoles(c *fiber.Ctx) error {
+	roles := make(fiber.Map, len(rbac.Roles))
+	for _, r := range rbac.Roles {
+		roles[r] = rbac.Permissions(r)
+	}
+	return response.SuccessResponse(c, "successfully", roles)
+}
+
+func AdminListUsers(c *fiber.Ctx) error {
+	page, limit := pagination(c)
+
+	adminServices := services.AdminServices{}
+	users, total, err := adminServices.ListUsers(c.UserContext(), c.Query("q"), page, limit)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"data":  users,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func AdminGetUser(c *fiber.Ctx) error {
+	adminServices := services.AdminServices{}
+	user, err := adminServices.GetUser(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", user)
+}
+
+func AdminSetUserRole(c *fiber.Ctx) error {
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Role == "" {
+		return response.ErrorMessage(c, constant.BADREQUEST, errors.New("role is required"))
+	}
+	if c.Params("id") == c.Locals("userID") {
+		return response.ErrorMessage(c, constant.BADREQUEST, errors.New("you cannot change your own role"))
+	}
+
+	adminServices := services.AdminServices{}
+	user, err := adminServices.SetRole(c.UserContext(), c.Params("id"), body.Role)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Role updated", user)
+}
+
+func AdminSuspendUser(c *fiber.Ctx) error {
+	return setUserSuspended(c, true)
+}
+
+func AdminUnsuspendUser(c *fiber.Ctx) error {
+	return setUserSuspended(c, false)
+}
+
+func setUserSuspended(c *fiber.Ctx, suspended bool) error {
+	adminServices := services.AdminServices{}
+	user, err := adminServices.SetSuspended(c.UserContext(), c.Params("id"), suspended)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	message := "User reinstated"
+	if suspended {
+		message = "User suspended"
+	}
+	return response.SuccessResponse(c, message, user)
+}
+
+func AdminListOrders(c *fiber.Ctx) error {
+	page, limit := pagination(c)
+
+	adminServices := services.AdminServices{}
+	orders, total, err := adminServices.ListOrders(c.UserContext(), c.Query("user_id"), c.Query("symbol"), page, limit)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"data":  orders,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
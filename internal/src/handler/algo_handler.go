@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	response "exdex/server/responses"
+)
+
+func CreateAlgoOrder(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	var req models.AlgoOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	algoServices := services.AlgoServices{}
+	order, err := algoServices.Create(c.UserContext(), userID, req)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Algo order started", order)
+}
+
+func GetAlgoOrders(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	algoServices := services.AlgoServices{}
+	orders, err := algoServices.List(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", orders)
+}
+
+// GetAlgoOrder returns the parent order with its progress and child orders.
+func GetAlgoOrder(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	algoServices := services.AlgoServices{}
+	order, err := algoServices.Get(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	children, err := algoServices.Children(c.UserContext(), order)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"order":    order,
+		"children": children,
+	})
+}
+
+func PauseAlgoOrder(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	algoServices := services.AlgoServices{}
+	order, err := algoServices.Pause(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Algo order paused", order)
+}
+
+func ResumeAlgoOrder(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	algoServices := services.AlgoServices{}
+	order, err := algoServices.Resume(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Algo order resumed", order)
+}
+
+func CancelAlgoOrder(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	algoServices := services.AlgoServices{}
+	order, err := algoServices.Cancel(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Algo order canceled", order)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	response "exdex/server/responses"
+)
+
+// CreateAPIKey returns the key and secret. They are not retrievable later.
+func CreateAPIKey(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	var req models.APIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	apiKeyServices := services.APIKeyServices{}
+	created, err := apiKeyServices.Create(c.UserContext(), userID, req)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "API key created, store the secret now as it will not be shown again", created)
+}
+
+func GetAPIKeys(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	apiKeyServices := services.APIKeyServices{}
+	keys, err := apiKeyServices.List(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", keys)
+}
+
+func RevokeAPIKey(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	apiKeyServices := services.APIKeyServices{}
+	if err := apiKeyServices.Revoke(c.UserContext(), userID, c.Params("id")); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "API key revoked", nil)
+}
@@ -8,6 +8,7 @@ import (
 
 	"exdex/internal/src/services"
 	"exdex/server/constant"
+	"exdex/server/jwt"
 	response "exdex/server/responses"
 )
 
@@ -17,10 +18,86 @@ func CheckExdexUserInfo(c *fiber.Ctx) error {
 		return response.ErrorMessage(c, constant.BADREQUEST, errors.New("token is required"))
 	}
 	exdexServices := services.AuthServices{}
-	user, err := exdexServices.ExdexAuth(exdexToken)
+	tokens, err := exdexServices.ExdexAuth(c.UserContext(), exdexToken, c.Get("User-Agent"), c.IP())
 	if err != nil {
 		log.Println("Service error:", err)
+		if errors.Is(err, services.ErrInvalidIdentityToken) || errors.Is(err, services.ErrSessionUnavailable) {
+			return response.ErrorMessage(c, constant.UNAUTHORIZED, err)
+		}
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
 	}
-	return response.SuccessResponse(c, "Successfully fetched user in EXDEX", user)
+	return response.SuccessResponse(c, "Successfully fetched user in EXDEX", tokens)
+}
+
+func RefreshToken(c *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return response.ErrorMessage(c, constant.BADREQUEST, errors.New("refresh_token is required"))
+	}
+
+	tokenServices := services.TokenServices{}
+	tokens, err := tokenServices.Refresh(c.UserContext(), body.RefreshToken, c.Get("User-Agent"), c.IP())
+	if err != nil {
+		return response.ErrorMessage(c, constant.UNAUTHORIZED, err)
+	}
+	return response.SuccessResponse(c, "Token refreshed", tokens)
+}
+
+// Logout revokes the current access token and its session. An optional
+// refresh_token in the body selects the session to end instead.
+func Logout(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*jwt.Claims)
+	if !ok {
+		return response.ErrorMessage(c, constant.UNAUTHORIZED, errors.New("missing token claims"))
+	}
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	c.BodyParser(&body)
+
+	tokenServices := services.TokenServices{}
+	if err := tokenServices.Logout(c.UserContext(), claims, body.RefreshToken); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Logged out", nil)
+}
+
+func LogoutAll(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	tokenServices := services.TokenServices{}
+	if err := tokenServices.RevokeUserSessions(c.UserContext(), userID); err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "All sessions ended", nil)
+}
+
+func GetSessions(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	tokenServices := services.TokenServices{}
+	sessions, err := tokenServices.Sessions(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", sessions)
+}
+
+// AdminRevokeUserSessions ends every session of the given user immediately.
+func AdminRevokeUserSessions(c *fiber.Ctx) error {
+	tokenServices := services.TokenServices{}
+	if err := tokenServices.RevokeUserSessions(c.UserContext(), c.Params("id")); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "User sessions revoked", nil)
 }
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	response "exdex/server/responses"
+)
+
+func StartBacktest(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	var req models.BacktestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	backtestServices := services.BacktestServices{}
+	run, err := backtestServices.Start(c.UserContext(), userID, req)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Backtest started", run)
+}
+
+func GetBacktests(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	backtestServices := services.BacktestServices{}
+	runs, err := backtestServices.List(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", runs)
+}
+
+func GetBacktest(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	backtestServices := services.BacktestServices{}
+	run, err := backtestServices.Get(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", run)
+}
+
+func GetBacktestTrades(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	backtestServices := services.BacktestServices{}
+	trades, total, err := backtestServices.Trades(c.UserContext(), userID, c.Params("id"), page, limit)
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"data":  trades,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	response "exdex/server/responses"
+)
+
+func BecomeCopyLeader(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	var req models.CopyLeaderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	leader, err := copyServices.BecomeLeader(c.UserContext(), userID, req)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Leader profile saved", leader)
+}
+
+func GetMyCopyLeader(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	leader, err := copyServices.MyLeaderProfile(c.UserContext(), userID)
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", leader)
+}
+
+func StopCopyLeading(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	if err := copyServices.StopLeading(c.UserContext(), userID); err != nil {
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "Stopped publishing trades", nil)
+}
+
+func GetCopyLeaders(c *fiber.Ctx) error {
+	copyServices := services.CopyServices{}
+	leaders, err := copyServices.Leaders(c.UserContext())
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", leaders)
+}
+
+func FollowCopyLeader(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	var req models.CopySubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	sub, err := copyServices.Follow(c.UserContext(), userID, req)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Following leader", sub)
+}
+
+func GetCopySubscriptions(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	subs, err := copyServices.Subscriptions(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", subs)
+}
+
+func PauseCopySubscription(c *fiber.Ctx) error {
+	return setCopySubscriptionStatus(c, models.CopyStatusPaused)
+}
+
+func ResumeCopySubscription(c *fiber.Ctx) error {
+	return setCopySubscriptionStatus(c, models.CopyStatusActive)
+}
+
+func setCopySubscriptionStatus(c *fiber.Ctx, status string) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	sub, err := copyServices.SetStatus(c.UserContext(), userID, c.Params("id"), status)
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "Subscription "+status, sub)
+}
+
+func UnfollowCopyLeader(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	if err := copyServices.Unfollow(c.UserContext(), userID, c.Params("id")); err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "Unfollowed leader", nil)
+}
+
+func GetCopyOrders(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	copyServices := services.CopyServices{}
+	orders, total, err := copyServices.Orders(c.UserContext(), userID, c.Params("id"), page, limit)
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"data":  orders,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func GetCopyPerformance(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	copyServices := services.CopyServices{}
+	perf, err := copyServices.Performance(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", perf)
+}
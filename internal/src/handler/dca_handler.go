@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	response "exdex/server/responses"
+)
+
+func CreateDCAPlan(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	var req models.DCAPlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	dcaServices := services.DCAServices{}
+	plan, err := dcaServices.Create(c.UserContext(), userID, req)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Recurring buy created", plan)
+}
+
+func GetDCAPlans(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	dcaServices := services.DCAServices{}
+	plans, err := dcaServices.List(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", plans)
+}
+
+func GetDCAPlan(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	dcaServices := services.DCAServices{}
+	plan, err := dcaServices.Get(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", plan)
+}
+
+func GetDCARuns(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	dcaServices := services.DCAServices{}
+	runs, total, err := dcaServices.Runs(c.UserContext(), userID, c.Params("id"), page, limit)
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"data":  runs,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func PauseDCAPlan(c *fiber.Ctx) error {
+	return setDCAPlanStatus(c, models.DCAStatusPaused)
+}
+
+func ResumeDCAPlan(c *fiber.Ctx) error {
+	return setDCAPlanStatus(c, models.DCAStatusActive)
+}
+
+func setDCAPlanStatus(c *fiber.Ctx, status string) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	dcaServices := services.DCAServices{}
+	plan, err := dcaServices.SetStatus(c.UserContext(), userID, c.Params("id"), status)
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "Recurring buy "+status, plan)
+}
+
+func DeleteDCAPlan(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	dcaServices := services.DCAServices{}
+	if err := dcaServices.Delete(c.UserContext(), userID, c.Params("id")); err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "Recurring buy deleted", nil)
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/services"
+)
+
+var startedAt = time.Now()
+
+// Healthz is the liveness probe: it answers as long as the process can
+// serve requests and checks no dependencies, so a slow database never gets
+// the process restarted.
+func Healthz(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status":    models.HealthOK,
+		"uptime_ms": time.Since(startedAt).Milliseconds(),
+	})
+}
+
+// Readyz is the readiness probe. It reports every dependency and answers
+// 503 when any is down, so traffic is routed elsewhere.
+func Readyz(c *fiber.Ctx) error {
+	report := services.Readiness(c.UserContext())
+	status := fiber.StatusOK
+	if report.Status == models.HealthDown {
+		status = fiber.StatusServiceUnavailable
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(report)
+}
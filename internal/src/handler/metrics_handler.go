@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/server/metrics"
+)
+
+// Metrics serves every metric in the Prometheus text format.
+func Metrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return metrics.Write(c)
+}
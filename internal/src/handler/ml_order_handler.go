@@ -7,6 +7,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
+	models "exdex/internal/src/model"
 	"exdex/internal/src/services"
 	"exdex/server/constant"
 	response "exdex/server/responses"
@@ -29,7 +30,7 @@ func GetOrderAllHistory(c *fiber.Ctx) error {
 		limit = 10
 	}
 	// skip := (page - 1) * limit
-	data, total, err := services.GetMyOrders(userID, page, limit)
+	data, total, err := services.GetMyOrders(c.UserContext(), userID, page, limit)
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
@@ -63,12 +64,18 @@ func MarketOrder(c *fiber.Ctx) error {
 		Symbol   string `json:"symbol"`
 		Side     string `json:"side"`
 		Quantity string `json:"quantity"`
+		Paper    bool   `json:"paper"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return response.ErrorMessage(c, constant.BADREQUEST, err)
 	}
 
-	order, err := services.PlaceMarketOrder(body.Symbol, body.Side, body.Quantity, userID)
+	var order *models.OrderResponse
+	if body.Paper {
+		order, err = services.PaperMarketOrder(c.UserContext(), body.Symbol, body.Side, body.Quantity, userID)
+	} else {
+		order, err = services.PlaceMarketOrder(c.UserContext(), body.Symbol, body.Side, body.Quantity, userID)
+	}
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
@@ -89,12 +96,19 @@ func MarketOrderTPSL(c *fiber.Ctx) error {
 		Quantity        string `json:"quantity"`
 		TakeProfitPrice string `json:"takeProfitPrice"`
 		StopLossPrice   string `json:"stopLossPrice"`
+		Paper           bool   `json:"paper"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return response.ErrorMessage(c, constant.BADREQUEST, err)
 	}
 
-	order, oco, err := services.PlaceMarketOrderWithTPSL(body.Symbol, body.Side, body.Quantity, body.TakeProfitPrice, body.StopLossPrice, userID)
+	var order *models.OrderResponse
+	var oco *models.OCOOrderResponse
+	if body.Paper {
+		order, oco, err = services.PaperMarketOrderWithTPSL(c.UserContext(), body.Symbol, body.Side, body.Quantity, body.TakeProfitPrice, body.StopLossPrice, userID)
+	} else {
+		order, oco, err = services.PlaceMarketOrderWithTPSL(c.UserContext(), body.Symbol, body.Side, body.Quantity, body.TakeProfitPrice, body.StopLossPrice, userID)
+	}
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
@@ -117,12 +131,18 @@ func LimitOrder(c *fiber.Ctx) error {
 		Side     string `json:"side"`
 		Quantity string `json:"quantity"`
 		Price    string `json:"price"`
+		Paper    bool   `json:"paper"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return response.ErrorMessage(c, constant.BADREQUEST, err)
 	}
 
-	order, err := services.PlaceLimitOrder(body.Symbol, body.Side, body.Quantity, body.Price, userID)
+	var order *models.OrderResponse
+	if body.Paper {
+		order, err = services.PaperLimitOrder(c.UserContext(), body.Symbol, body.Side, body.Quantity, body.Price, userID)
+	} else {
+		order, err = services.PlaceLimitOrder(c.UserContext(), body.Symbol, body.Side, body.Quantity, body.Price, userID)
+	}
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
@@ -144,12 +164,18 @@ func LimitOrderTPSL(c *fiber.Ctx) error {
 		Price           string `json:"price"`
 		TakeProfitPrice string `json:"takeProfitPrice"`
 		StopLossPrice   string `json:"stopLossPrice"`
+		Paper           bool   `json:"paper"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return response.ErrorMessage(c, constant.BADREQUEST, err)
 	}
 
-	order, err := services.PlaceLimitOrderWithTPSL(body.Symbol, body.Side, body.Quantity, body.Price, body.TakeProfitPrice, body.StopLossPrice, userID)
+	var order *models.OrderResponse
+	if body.Paper {
+		order, err = services.PaperLimitOrderWithTPSL(c.UserContext(), body.Symbol, body.Side, body.Quantity, body.Price, body.TakeProfitPrice, body.StopLossPrice, userID)
+	} else {
+		order, err = services.PlaceLimitOrderWithTPSL(c.UserContext(), body.Symbol, body.Side, body.Quantity, body.Price, body.TakeProfitPrice, body.StopLossPrice, userID)
+	}
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
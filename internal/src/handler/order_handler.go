@@ -28,7 +28,7 @@ func OrderHandler(c *fiber.Ctx) error {
 	}
 
 	orderServices := services.OrderSerices{}
-	result, err := orderServices.PlaceOrder(req, idStr)
+	result, err := orderServices.PlaceOrder(c.UserContext(), req, idStr)
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
@@ -42,7 +42,7 @@ func GetOrderPriceHandler(c *fiber.Ctx) error {
 		return response.ErrorMessage(c, constant.BADREQUEST, errors.New("symbol is required"))
 	}
 	orderServices := services.OrderSerices{}
-	p1, err := orderServices.GetSymbolPrice(symbol)
+	p1, err := orderServices.GetSymbolPrice(c.UserContext(), symbol)
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
@@ -76,7 +76,7 @@ func GetOrderHistory(c *fiber.Ctx) error {
 	}
 
 	orderServices := services.OrderSerices{}
-	data, err := orderServices.GetHistory(idStr, limit, offset)
+	data, err := orderServices.GetHistory(c.UserContext(), idStr, limit, offset)
 	if err != nil {
 		log.Println("Service error:", err)
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
@@ -95,7 +95,7 @@ func CancelOCOOrderHandler(c *fiber.Ctx) error {
 	}
 
 	orderServices := services.OrderSerices{}
-	res, err := orderServices.CancelOCOOrder(orderListId, symbol)
+	res, err := orderServices.CancelOCOOrder(c.UserContext(), orderListId, symbol)
 	if err != nil {
 		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
 	}
@@ -105,6 +105,7 @@ func CancelOCOOrderHandler(c *fiber.Ctx) error {
 
 func WebSocketHandler(c *websocket.Conn) {
 	defer c.Close()
+	defer services.TrackWSClient(services.WSChannelEcho)()
 	for {
 		_, msg, err := c.ReadMessage()
 		if err != nil {
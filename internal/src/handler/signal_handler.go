@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	response "exdex/server/responses"
+)
+
+// ReceiveSignal is the public endpoint charting platforms post alerts to.
+// The secret token is taken from the URL or from the "token" body field.
+func ReceiveSignal(c *fiber.Ctx) error {
+	signalServices := services.SignalServices{}
+	entry, err := signalServices.Ingest(c.UserContext(), c.Params("token"), c.Body(), c.IP())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSignalToken) {
+			return response.ErrorMessage(c, constant.UNAUTHORIZED, err)
+		}
+		if errors.Is(err, services.ErrOwnerCannotTrade) {
+			return response.ErrorMessage(c, constant.FORBIDDEN, err)
+		}
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+
+	if !entry.Valid {
+		return response.ErrorMessage(c, constant.UNPROCESSABLEENTITY, errors.New(entry.ValidationError))
+	}
+	if entry.OrderError != "" {
+		return response.ErrorMessage(c, constant.BADGATEWAY, errors.New(entry.OrderError))
+	}
+	return response.SuccessResponse(c, "Signal executed", entry)
+}
+
+func CreateSignalToken(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	signalServices := services.SignalServices{}
+	token, err := signalServices.CreateToken(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+
+	return response.SuccessResponse(c, "Signal token created, it will not be shown again", fiber.Map{
+		"token": token,
+		"url":   c.BaseURL() + "/v1/api/signal/" + token,
+	})
+}
+
+func DisableSignalToken(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	signalServices := services.SignalServices{}
+	if err := signalServices.Disable(c.UserContext(), userID); err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "Signal token disabled", nil)
+}
+
+func GetSignalLogs(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	signalServices := services.SignalServices{}
+	data, total, err := signalServices.Logs(c.UserContext(), userID, page, limit)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"data":  data,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
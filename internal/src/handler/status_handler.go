@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/services"
+	"exdex/server/httpclient"
+	response "exdex/server/responses"
+)
+
+// GetUpstreamStatus shows the circuit breaker of every upstream and the
+// exchange rate limit usage.
+func GetUpstreamStatus(c *fiber.Ctx) error {
+	return response.SuccessResponse(c, "successfully", fiber.Map{
+		"upstreams":       httpclient.Status(),
+		"exchange_limits": services.ExchangeLimits(),
+	})
+}
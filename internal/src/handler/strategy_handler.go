@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	response "exdex/server/responses"
+)
+
+func CreateStrategy(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	var req models.StrategyInstanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	strategyServices := services.StrategyServices{}
+	inst, err := strategyServices.Create(c.UserContext(), userID, req)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Strategy created", inst)
+}
+
+func GetStrategies(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	strategyServices := services.StrategyServices{}
+	instances, err := strategyServices.List(c.UserContext(), userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+	return response.SuccessResponse(c, "successfully", instances)
+}
+
+func GetStrategyStatus(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	strategyServices := services.StrategyServices{}
+	inst, err := strategyServices.Get(c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "successfully", inst)
+}
+
+func StartStrategy(c *fiber.Ctx) error {
+	return changeStrategyState(c, "started", services.StrategyServices.Start)
+}
+
+func StopStrategy(c *fiber.Ctx) error {
+	return changeStrategyState(c, "stopped", services.StrategyServices.Stop)
+}
+
+func PauseStrategy(c *fiber.Ctx) error {
+	return changeStrategyState(c, "paused", services.StrategyServices.Pause)
+}
+
+func changeStrategyState(c *fiber.Ctx, verb string, fn func(services.StrategyServices, context.Context, string, string) (models.StrategyInstance, error)) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	inst, err := fn(services.StrategyServices{}, c.UserContext(), userID, c.Params("id"))
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "Strategy "+verb, inst)
+}
+
+func DeleteStrategy(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	strategyServices := services.StrategyServices{}
+	if err := strategyServices.Delete(c.UserContext(), userID, c.Params("id")); err != nil {
+		return response.ErrorMessage(c, constant.NOTFOUND, err)
+	}
+	return response.SuccessResponse(c, "Strategy deleted", nil)
+}
@@ -12,17 +12,19 @@ import (
 func RegisterWebSocket(app *fiber.App) {
 	app.Get("/ws/market/tickers", websocket.New(func(c *websocket.Conn) {
 		defer c.Close()
+		defer services.TrackWSClient(services.WSChannelMarket)()
 
-		tickerChan := make(chan services.StreamTicker)
-
-		// Start Binance stream in a goroutine
-		go services.StartBinanceTickerStream(tickerChan)
+		// All clients share the hub's single Binance stream.
+		tickerChan := services.MarketData.Subscribe()
+		defer services.MarketData.Unsubscribe(tickerChan)
 
 		for ticker := range tickerChan {
 			if err := c.WriteJSON(ticker); err != nil {
 				log.Println("WebSocket send error:", err)
-				break
+				return
 			}
 		}
+		// The hub has stopped.
+		c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
 	}))
 }
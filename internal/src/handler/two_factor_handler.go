@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	"exdex/server/jwt"
+	response "exdex/server/responses"
+)
+
+type twoFactorCodeBody struct {
+	Code string `json:"code"`
+}
+
+func parseTwoFactorCode(c *fiber.Ctx) (string, error) {
+	var body twoFactorCodeBody
+	if err := c.BodyParser(&body); err != nil || body.Code == "" {
+		return "", errors.New("code is required")
+	}
+	return body.Code, nil
+}
+
+func sessionID(c *fiber.Ctx) string {
+	if claims, ok := c.Locals("claims").(*jwt.Claims); ok {
+		return claims.SessionID
+	}
+	return ""
+}
+
+func twoFactorError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		return response.ErrorMessage(c, constant.UNAUTHORIZED, err)
+	case errors.Is(err, services.ErrTwoFactorLocked), errors.Is(err, services.ErrFreshLoginRequired):
+		return response.ErrorMessage(c, constant.FORBIDDEN, err)
+	default:
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+}
+
+func GetTwoFactorStatus(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	twoFactorServices := services.TwoFactorServices{}
+	status, err := twoFactorServices.Status(c.UserContext(), userID, sessionID(c))
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	return response.SuccessResponse(c, "successfully", status)
+}
+
+func EnrollTwoFactor(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	twoFactorServices := services.TwoFactorServices{}
+	enrollment, err := twoFactorServices.Enroll(c.UserContext(), userID, sessionID(c))
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+	return response.SuccessResponse(c, "Scan the QR code, then confirm with a code", enrollment)
+}
+
+func ConfirmTwoFactor(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	code, err := parseTwoFactorCode(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	twoFactorServices := services.TwoFactorServices{}
+	codes, err := twoFactorServices.Confirm(c.UserContext(), userID, sessionID(c), code)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+	return response.SuccessResponse(c, "Two-factor authentication enabled, store the recovery codes now", fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// VerifyTwoFactor confirms the second factor for the current session.
+func VerifyTwoFactor(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	code, err := parseTwoFactorCode(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	twoFactorServices := services.TwoFactorServices{}
+	if err := twoFactorServices.Verify(c.UserContext(), userID, sessionID(c), code); err != nil {
+		return twoFactorError(c, err)
+	}
+	return response.SuccessResponse(c, "Verified", nil)
+}
+
+func DisableTwoFactor(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	code, err := parseTwoFactorCode(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	twoFactorServices := services.TwoFactorServices{}
+	if err := twoFactorServices.Disable(c.UserContext(), userID, code); err != nil {
+		return twoFactorError(c, err)
+	}
+	return response.SuccessResponse(c, "Two-factor authentication disabled", nil)
+}
+
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+	code, err := parseTwoFactorCode(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	twoFactorServices := services.TwoFactorServices{}
+	codes, err := twoFactorServices.RegenerateRecoveryCodes(c.UserContext(), userID, code)
+	if err != nil {
+		return twoFactorError(c, err)
+	}
+	return response.SuccessResponse(c, "Recovery codes replaced", fiber.Map{"recovery_codes": codes})
+}
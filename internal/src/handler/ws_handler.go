@@ -1,7 +1,12 @@
 package handler
 
-import "exdex/internal/src/services"
+import (
+	"context"
 
-func WsInit() {
-	services.OrderWSHandler()
+	"exdex/internal/src/services"
+)
+
+// WsInit runs the exchange user-data stream until ctx is done.
+func WsInit(ctx context.Context) error {
+	return services.OrderWSHandler(ctx)
 }
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+	"exdex/server/jwt"
+	response "exdex/server/responses"
+)
+
+const wsPingInterval = 30 * time.Second
+
+// IssueWSTicket returns a one-time ticket for opening /ws/private.
+func IssueWSTicket(c *fiber.Ctx) error {
+	userID, err := getUserID(c)
+	if err != nil {
+		return response.ErrorMessage(c, constant.BADREQUEST, err)
+	}
+
+	ticket, expiresAt, err := services.IssueWSTicket(userID)
+	if err != nil {
+		log.Println("Service error:", err)
+		return response.ErrorMessage(c, constant.INTERNALSERVERERROR, err)
+	}
+
+	return response.SuccessResponse(c, "Ticket issued", fiber.Map{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
+// PrivateWSUpgrade authenticates the caller before the WebSocket upgrade,
+// either with a JWT (Authorization header or ?token=) or a one-time ?ticket=.
+func PrivateWSUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	if ticket := c.Query("ticket"); ticket != "" {
+		userID, ok := services.RedeemWSTicket(ticket)
+		if !ok {
+			return response.ErrorMessage(c, constant.UNAUTHORIZED, errors.New("invalid or expired ticket"))
+		}
+		c.Locals("userID", userID)
+		return c.Next()
+	}
+
+	tokenString := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+	if tokenString == "" {
+		tokenString = c.Query("token")
+	}
+	if tokenString == "" {
+		return response.ErrorMessage(c, constant.UNAUTHORIZED, errors.New("missing token or ticket"))
+	}
+
+	claims, err := jwt.ParseToken(tokenString)
+	if err != nil {
+		return response.ErrorMessage(c, constant.UNAUTHORIZED, errors.New("invalid or expired token"))
+	}
+	revoked, err := services.IsAccessTokenRevoked(c.UserContext(), claims)
+	if err != nil {
+		return response.ErrorMessage(c, constant.SERVICEUNAVAILABLE, errors.New("could not verify token"))
+	}
+	if revoked {
+		return response.ErrorMessage(c, constant.UNAUTHORIZED, errors.New("token has been revoked"))
+	}
+
+	c.Locals("userID", claims.UserID)
+	return c.Next()
+}
+
+// PrivateWebSocketHandler streams the caller's order, fill, balance and
+// alert events. Pass ?since=<seq> on reconnect to replay missed events.
+func PrivateWebSocketHandler(c *websocket.Conn) {
+	defer c.Close()
+
+	userID, _ := c.Locals("userID").(string)
+	if userID == "" {
+		return
+	}
+	since, _ := strconv.ParseInt(c.Query("since"), 10, 64)
+
+	sub, replay := services.UserEvents.Subscribe(userID, since)
+	defer services.UserEvents.Unsubscribe(sub)
+	defer services.TrackWSClient(services.WSChannelPrivate)()
+
+	for _, ev := range replay {
+		if err := c.WriteJSON(ev); err != nil {
+			return
+		}
+	}
+
+	// The reader only exists to notice the client going away.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+				return
+			}
+			if err := c.WriteJSON(ev); err != nil {
+				log.Println("WebSocket send error:", err)
+				return
+			}
+		case <-ping.C:
+			if err := c.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
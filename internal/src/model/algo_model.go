@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AlgoTWAP = "twap"
+	AlgoVWAP = "vwap"
+
+	AlgoStatusRunning   = "running"
+	AlgoStatusPaused    = "paused"
+	AlgoStatusCompleted = "completed"
+	AlgoStatusExpired   = "expired" // ran out of time before the full quantity filled
+	AlgoStatusCanceled  = "canceled"
+)
+
+type AlgoOrderRequest struct {
+	Symbol            string  `json:"symbol" validate:"required"`
+	Side              string  `json:"side" validate:"required"`
+	Quantity          string  `json:"quantity" validate:"required"`
+	Algo              string  `json:"algo" validate:"required"`     // twap | vwap
+	Duration          string  `json:"duration" validate:"required"` // e.g. "30m", "4h"
+	Slices            int     `json:"slices,omitempty"`             // default one per minute
+	LimitPrice        string  `json:"limit_price,omitempty"`        // worst acceptable price
+	ParticipationRate float64 `json:"participation_rate,omitempty"` // max share of market volume, 0-1
+}
+
+// AlgoOrder is a parent order worked over time through child orders that
+// reference it by ParentID in the orders collection.
+type AlgoOrder struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID            string             `bson:"user_id" json:"user_id"`
+	Symbol            string             `bson:"symbol" json:"symbol"`
+	Side              string             `bson:"side" json:"side"`
+	Algo              string             `bson:"algo" json:"algo"`
+	Quantity          float64            `bson:"quantity" json:"quantity"`
+	LimitPrice        float64            `bson:"limit_price,omitempty" json:"limit_price,omitempty"`
+	ParticipationRate float64            `bson:"participation_rate,omitempty" json:"participation_rate,omitempty"`
+	DurationSec       int64              `bson:"duration_sec" json:"duration_sec"`
+	SliceIntervalSec  int64              `bson:"slice_interval_sec" json:"slice_interval_sec"`
+	Weights           []float64          `bson:"weights" json:"weights"` // share of Quantity per slice, sums to 1
+	NextSlice         int                `bson:"next_slice" json:"next_slice"`
+	NextSliceAt       time.Time          `bson:"next_slice_at" json:"next_slice_at"`
+	ExecutedQty       float64            `bson:"executed_qty" json:"executed_qty"`
+	QuoteQty          float64            `bson:"quote_qty" json:"quote_qty"`
+	AvgPrice          float64            `bson:"avg_price" json:"avg_price"`
+	Progress          float64            `bson:"-" json:"progress"` // executed share, 0-1
+	ChildCount        int                `bson:"child_count" json:"child_count"`
+	Status            string             `bson:"status" json:"status"`
+	LastError         string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	PausedAt          *time.Time         `bson:"paused_at,omitempty" json:"paused_at,omitempty"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+	FinishedAt        *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKeyRequest struct {
+	Name       string     `json:"name" validate:"required"`
+	Scopes     []string   `json:"scopes" validate:"required"` // read | trade
+	AllowedIPs []string   `json:"allowed_ips,omitempty"`      // IPs or CIDRs, empty allows any
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKey lets a bot call the API without a browser session. The key is
+// stored hashed for lookup. The secret has to be recoverable to check HMAC
+// signatures, so it is kept encrypted instead and shown only once.
+type APIKey struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	Name            string             `bson:"name" json:"name"`
+	KeyPrefix       string             `bson:"key_prefix" json:"key_prefix"`
+	KeyHash         string             `bson:"key_hash" json:"-"`
+	SecretEncrypted string             `bson:"secret_encrypted" json:"-"`
+	Scopes          []string           `bson:"scopes" json:"scopes"`
+	AllowedIPs      []string           `bson:"allowed_ips,omitempty" json:"allowed_ips,omitempty"`
+	ExpiresAt       *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt      *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP      string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+	RevokedAt       *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// APIKeyCreated is the only response that carries the key and secret.
+type APIKeyCreated struct {
+	APIKey
+	Key    string `json:"api_key"`
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	BacktestStatusRunning   = "running"
+	BacktestStatusCompleted = "completed"
+	BacktestStatusFailed    = "failed"
+)
+
+// Candle is one stored OHLCV bar, unique per symbol, interval and open time.
+type Candle struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Symbol    string             `bson:"symbol" json:"symbol"`
+	Interval  string             `bson:"interval" json:"interval"`
+	OpenTime  time.Time          `bson:"open_time" json:"open_time"`
+	CloseTime time.Time          `bson:"close_time" json:"close_time"`
+	Open      float64            `bson:"open" json:"open"`
+	High      float64            `bson:"high" json:"high"`
+	Low       float64            `bson:"low" json:"low"`
+	Close     float64            `bson:"close" json:"close"`
+	Volume    float64            `bson:"volume" json:"volume"`
+}
+
+type BacktestRequest struct {
+	Symbol         string             `json:"symbol" validate:"required"`
+	Interval       string             `json:"interval" validate:"required"` // kline interval, e.g. "1h"
+	Start          time.Time          `json:"start" validate:"required"`
+	End            time.Time          `json:"end" validate:"required"`
+	Strategy       string             `json:"strategy" validate:"required"` // grid | dca | bracket
+	Params         map[string]float64 `json:"params"`
+	InitialBalance float64            `json:"initial_balance" validate:"required,gt=0"` // in quote asset
+	FeeRate        *float64           `json:"fee_rate,omitempty"`                       // default 0.001
+	SlippageBps    float64            `json:"slippage_bps,omitempty"`                   // applied to market and stop fills
+}
+
+// BacktestRun is one replay of a strategy over stored candles.
+type BacktestRun struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         string             `bson:"user_id" json:"user_id"`
+	Symbol         string             `bson:"symbol" json:"symbol"`
+	Interval       string             `bson:"interval" json:"interval"`
+	Start          time.Time          `bson:"start" json:"start"`
+	End            time.Time          `bson:"end" json:"end"`
+	Strategy       string             `bson:"strategy" json:"strategy"`
+	Params         map[string]float64 `bson:"params" json:"params"`
+	InitialBalance float64            `bson:"initial_balance" json:"initial_balance"`
+	FeeRate        float64            `bson:"fee_rate" json:"fee_rate"`
+	SlippageBps    float64            `bson:"slippage_bps" json:"slippage_bps"`
+	Status         string             `bson:"status" json:"status"`
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+	Bars           int                `bson:"bars" json:"bars"`
+	Stats          *BacktestStats     `bson:"stats,omitempty" json:"stats,omitempty"`
+	Equity         []EquityPoint      `bson:"equity,omitempty" json:"equity,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	FinishedAt     *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+type EquityPoint struct {
+	Time   time.Time `bson:"time" json:"time"`
+	Equity float64   `bson:"equity" json:"equity"`
+}
+
+type BacktestStats struct {
+	FinalEquity      float64 `bson:"final_equity" json:"final_equity"`
+	ReturnPct        float64 `bson:"return_pct" json:"return_pct"`
+	BuyAndHoldPct    float64 `bson:"buy_and_hold_pct" json:"buy_and_hold_pct"`
+	MaxDrawdownPct   float64 `bson:"max_drawdown_pct" json:"max_drawdown_pct"`
+	Trades           int     `bson:"trades" json:"trades"`
+	RoundTrips       int     `bson:"round_trips" json:"round_trips"`
+	WinRate          float64 `bson:"win_rate" json:"win_rate"`
+	ProfitFactor     float64 `bson:"profit_factor" json:"profit_factor"`
+	TotalFees        float64 `bson:"total_fees" json:"total_fees"`
+	FinalBase        float64 `bson:"final_base" json:"final_base"`
+	FinalQuote       float64 `bson:"final_quote" json:"final_quote"`
+	SharpeAnnualised float64 `bson:"sharpe_annualised" json:"sharpe_annualised"`
+}
+
+// BacktestTrade is one simulated fill. Order carries the simulated order in
+// the same shape as live orders.
+type BacktestTrade struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RunID       primitive.ObjectID `bson:"run_id" json:"run_id"`
+	Time        time.Time          `bson:"time" json:"time"`
+	Order       OrderRecord        `bson:"order" json:"order"`
+	Price       float64            `bson:"price" json:"price"`
+	Quantity    float64            `bson:"quantity" json:"quantity"`
+	Fee         float64            `bson:"fee" json:"fee"`
+	FeeAsset    string             `bson:"fee_asset" json:"fee_asset"`
+	RealizedPnL float64            `bson:"realized_pnl" json:"realized_pnl"` // sells only, against average cost
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CopyModeFixedRatio    = "fixed_ratio"
+	CopyModeFixedNotional = "fixed_notional"
+	CopyModeEquity        = "equity_proportional"
+
+	CopyStatusActive = "active"
+	CopyStatusPaused = "paused"
+
+	CopyOrderPending = "pending" // claimed, order not placed yet
+	CopyOrderPlaced  = "placed"
+	CopyOrderSkipped = "skipped"
+	CopyOrderFailed  = "failed"
+)
+
+type CopyLeaderRequest struct {
+	DisplayName string `json:"display_name" validate:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// CopyLeader is a user who publishes their fills for others to mirror.
+type CopyLeader struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	DisplayName string             `bson:"display_name" json:"display_name"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Active      bool               `bson:"active" json:"active"`
+	Followers   int64              `bson:"-" json:"followers"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CopySubscriptionRequest struct {
+	LeaderID         string   `json:"leader_id" validate:"required"`
+	Mode             string   `json:"mode" validate:"required"` // fixed_ratio | fixed_notional | equity_proportional
+	Ratio            float64  `json:"ratio,omitempty"`          // fixed_ratio: follower qty = leader qty * ratio
+	Notional         float64  `json:"notional,omitempty"`       // fixed_notional: quote amount per leader order
+	MaxOrderNotional float64  `json:"max_order_notional,omitempty"`
+	MaxDailyNotional float64  `json:"max_daily_notional,omitempty"`
+	Symbols          []string `json:"symbols,omitempty"` // allow-list, empty means all
+}
+
+// CopySubscription links a follower to a leader with a sizing rule and the
+// follower's own risk limits.
+type CopySubscription struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LeaderID         primitive.ObjectID `bson:"leader_id" json:"leader_id"`
+	LeaderUserID     string             `bson:"leader_user_id" json:"-"`
+	FollowerID       string             `bson:"follower_id" json:"follower_id"`
+	Mode             string             `bson:"mode" json:"mode"`
+	Ratio            float64            `bson:"ratio,omitempty" json:"ratio,omitempty"`
+	Notional         float64            `bson:"notional,omitempty" json:"notional,omitempty"`
+	MaxOrderNotional float64            `bson:"max_order_notional,omitempty" json:"max_order_notional,omitempty"`
+	MaxDailyNotional float64            `bson:"max_daily_notional,omitempty" json:"max_daily_notional,omitempty"`
+	Symbols          []string           `bson:"symbols,omitempty" json:"symbols,omitempty"`
+	Status           string             `bson:"status" json:"status"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CopyOrder attributes one mirrored order, or a skipped mirror, to the
+// leader fill it came from.
+type CopyOrder struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SubscriptionID primitive.ObjectID `bson:"subscription_id" json:"subscription_id"`
+	LeaderID       primitive.ObjectID `bson:"leader_id" json:"leader_id"`
+	FollowerID     string             `bson:"follower_id" json:"follower_id"`
+	Symbol         string             `bson:"symbol" json:"symbol"`
+	Side           string             `bson:"side" json:"side"`
+	SourceOrderID  int64              `bson:"source_order_id" json:"source_order_id"`
+	SourceTradeID  int64              `bson:"source_trade_id" json:"source_trade_id"`
+	SourceQty      float64            `bson:"source_qty" json:"source_qty"`
+	SourcePrice    float64            `bson:"source_price" json:"source_price"`
+	Quantity       string             `bson:"quantity,omitempty" json:"quantity,omitempty"`
+	OrderID        int64              `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	ExecutedQty    float64            `bson:"executed_qty" json:"executed_qty"`
+	QuoteQty       float64            `bson:"quote_qty" json:"quote_qty"`
+	AvgPrice       float64            `bson:"avg_price,omitempty" json:"avg_price,omitempty"`
+	Status         string             `bson:"status" json:"status"`
+	Reason         string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CopyPerformance compares a follower's mirrored fills with the leader's.
+type CopyPerformance struct {
+	Mirrored         int64   `json:"mirrored"`
+	Skipped          int64   `json:"skipped"`
+	Failed           int64   `json:"failed"`
+	LeaderNotional   float64 `json:"leader_notional"`
+	FollowerNotional float64 `json:"follower_notional"`
+	AvgSlippageBps   float64 `json:"avg_slippage_bps"` // positive means the follower got a worse price
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DCAScheduleDaily  = "daily"
+	DCAScheduleWeekly = "weekly"
+	DCAScheduleCron   = "cron"
+
+	DCAStatusActive = "active"
+	DCAStatusPaused = "paused"
+
+	DCARunFilled  = "filled"
+	DCARunSkipped = "skipped"
+	DCARunFailed  = "failed"
+)
+
+type DCAPlanRequest struct {
+	Symbol      string `json:"symbol" validate:"required"`
+	QuoteAmount string `json:"quote_amount" validate:"required"`
+	Schedule    string `json:"schedule" validate:"required"` // daily | weekly | cron
+	Time        string `json:"time,omitempty"`               // HH:MM UTC, for daily and weekly
+	Weekday     int    `json:"weekday,omitempty"`            // 0 = Sunday, for weekly
+	Cron        string `json:"cron,omitempty"`               // 5-field expression, for cron
+}
+
+// DCAPlan is a recurring market buy of a fixed quote amount.
+type DCAPlan struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	Symbol          string             `bson:"symbol" json:"symbol"`
+	QuoteAmount     string             `bson:"quote_amount" json:"quote_amount"`
+	Schedule        string             `bson:"schedule" json:"schedule"`
+	CronExpr        string             `bson:"cron_expr" json:"cron_expr"`
+	Status          string             `bson:"status" json:"status"`
+	NextRunAt       time.Time          `bson:"next_run_at" json:"next_run_at"`
+	LastRunAt       *time.Time         `bson:"last_run_at,omitempty" json:"last_run_at,omitempty"`
+	RunCount        int                `bson:"run_count" json:"run_count"`
+	SkippedCount    int                `bson:"skipped_count" json:"skipped_count"` // runs skipped for lack of balance
+	FailedCount     int                `bson:"failed_count" json:"failed_count"`   // runs that errored
+	TotalQuoteSpent float64            `bson:"total_quote_spent" json:"total_quote_spent"`
+	TotalBaseBought float64            `bson:"total_base_bought" json:"total_base_bought"`
+	AverageCost     float64            `bson:"-" json:"average_cost"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// DCARun is the outcome of one scheduled execution of a plan.
+type DCARun struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PlanID      primitive.ObjectID `bson:"plan_id" json:"plan_id"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	Symbol      string             `bson:"symbol" json:"symbol"`
+	ScheduledAt time.Time          `bson:"scheduled_at" json:"scheduled_at"`
+	ExecutedAt  time.Time          `bson:"executed_at" json:"executed_at"`
+	Status      string             `bson:"status" json:"status"`
+	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	OrderID     int64              `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	QuoteSpent  float64            `bson:"quote_spent" json:"quote_spent"`
+	BaseQty     float64            `bson:"base_qty" json:"base_qty"`
+	FillPrice   float64            `bson:"fill_price" json:"fill_price"`
+}
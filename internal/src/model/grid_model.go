@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	GridSpacingArithmetic = "arithmetic"
+	GridSpacingGeometric  = "geometric"
+
+	GridStatusRunning = "running"
+	GridStatusStopped = "stopped"
+	GridStatusError   = "error"
+)
+
+type GridBotRequest struct {
+	Symbol     string  `json:"symbol" validate:"required"`
+	LowerPrice float64 `json:"lower_price" validate:"required,gt=0"`
+	UpperPrice float64 `json:"upper_price" validate:"required,gt=0"`
+	GridCount  int     `json:"grid_count" validate:"required,min=2,max=200"`
+	Investment float64 `json:"investment" validate:"required,gt=0"` // in quote asset
+	Spacing    string  `json:"spacing"`                             // arithmetic (default) | geometric
+	StopLoss   float64 `json:"stop_loss,omitempty"`                 // stop the bot when price <= this
+	TakeProfit float64 `json:"take_profit,omitempty"`               // stop the bot when price >= this
+}
+
+// GridBot is a spot grid: one resting limit order per price level, with a
+// single empty level around the current price.
+type GridBot struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	Symbol          string             `bson:"symbol" json:"symbol"`
+	LowerPrice      float64            `bson:"lower_price" json:"lower_price"`
+	UpperPrice      float64            `bson:"upper_price" json:"upper_price"`
+	GridCount       int                `bson:"grid_count" json:"grid_count"`
+	Investment      float64            `bson:"investment" json:"investment"`
+	Spacing         string             `bson:"spacing" json:"spacing"`
+	StopLoss        float64            `bson:"stop_loss,omitempty" json:"stop_loss,omitempty"`
+	TakeProfit      float64            `bson:"take_profit,omitempty" json:"take_profit,omitempty"`
+	QtyPerGrid      string             `bson:"qty_per_grid" json:"qty_per_grid"`
+	Levels          []GridLevel        `bson:"levels" json:"levels"`
+	BaseHeld        float64            `bson:"base_held" json:"base_held"`
+	GridProfit      float64            `bson:"grid_profit" json:"grid_profit"`
+	CompletedCycles int                `bson:"completed_cycles" json:"completed_cycles"`
+	Status          string             `bson:"status" json:"status"`
+	StopReason      string             `bson:"stop_reason,omitempty" json:"stop_reason,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	StoppedAt       *time.Time         `bson:"stopped_at,omitempty" json:"stopped_at,omitempty"`
+}
+
+// GridLevel is one price of the ladder and the order resting on it, if any.
+type GridLevel struct {
+	Index   int    `bson:"index" json:"index"`
+	Price   string `bson:"price" json:"price"`
+	Side    string `bson:"side,omitempty" json:"side,omitempty"`
+	OrderID int64  `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	// EntryPrice is what the base sold by this level's SELL was bought at.
+	EntryPrice float64 `bson:"entry_price,omitempty" json:"entry_price,omitempty"`
+	// PendingSide is set while an order for this level could not be placed
+	// and is being retried.
+	PendingSide string `bson:"pending_side,omitempty" json:"pending_side,omitempty"`
+	Attempts    int    `bson:"attempts,omitempty" json:"attempts,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Health statuses, from best to worst.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // working, but something needs attention
+	HealthDown     = "down"     // not able to serve trading traffic
+)
+
+// HealthReport is the body of /readyz. Status is the worst status of any
+// component that readiness depends on.
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Time       time.Time                  `json:"time"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// ComponentHealth is one dependency's state. Only the measurements that
+// apply to the component are set; durations are in milliseconds.
+type ComponentHealth struct {
+	Status             string `json:"status"`
+	Error              string `json:"error,omitempty"`
+	LatencyMS          *int64 `json:"latency_ms,omitempty"`
+	ClockSkewMS        *int64 `json:"clock_skew_ms,omitempty"`
+	LastMessageAgeMS   *int64 `json:"last_message_age_ms,omitempty"`
+	ListenKeyAgeMS     *int64 `json:"listen_key_age_ms,omitempty"`
+	LastKeepAliveAgeMS *int64 `json:"last_keepalive_age_ms,omitempty"`
+}
@@ -5,10 +5,12 @@ import (
 )
 
 type OrderResponse struct {
-	Symbol        string `json:"symbol"`
-	OrderID       int64  `json:"orderId"`
-	ClientOrderID string `json:"clientOrderId"`
-	Status        string `json:"status"`
+	Symbol              string `json:"symbol"`
+	OrderID             int64  `json:"orderId"`
+	ClientOrderID       string `json:"clientOrderId"`
+	Status              string `json:"status"`
+	ExecutedQty         string `json:"executedQty,omitempty"`
+	CummulativeQuoteQty string `json:"cummulativeQuoteQty,omitempty"`
 }
 
 type OCOOrderResponse struct {
@@ -32,5 +34,15 @@ type OrderRecord struct {
 	OrderID         int64              `bson:"order_id"`
 	ClientOrderID   string             `bson:"client_order_id"`
 	Status          string             `bson:"status"`
+	ParentID        string             `bson:"parent_id,omitempty"` // algo parent, strategy instance or copy order that placed it
+	ExecutedQty     string             `bson:"executed_qty,omitempty"`
+	QuoteQty        string             `bson:"quote_qty,omitempty"`
 	CreatedAt       primitive.DateTime `bson:"created_at"`
+
+	// Paper trading only. Paper orders have negative order IDs.
+	Paper        bool    `bson:"paper,omitempty"`
+	OrderListID  int64   `bson:"order_list_id,omitempty"` // shared by both legs of an OCO
+	StopPrice    string  `bson:"stop_price,omitempty"`
+	LockedAsset  string  `bson:"locked_asset,omitempty"`
+	LockedAmount float64 `bson:"locked_amount,omitempty"`
 }
@@ -13,6 +13,7 @@ type OrderRequest struct {
 	SLPrice   float64 `json:"sl_price,omitempty"` // if absolute
 	TPMul     float64 `json:"tp_multiplier,omitempty"`
 	SLMul     float64 `json:"sl_multiplier,omitempty"`
+	Paper     bool    `json:"paper,omitempty"` // simulate against the paper account
 }
 
 type PlacedOrder struct {
@@ -20,6 +21,7 @@ type PlacedOrder struct {
 	Symbol        string `json:"symbol"`
 	UserID        string `bson:"user_id"`
 	OrderID       int64  `json:"orderId"`
+	OrderListID   int64  `bson:"order_list_id,omitempty" json:"orderListId,omitempty"` // set for OCO orders
 	ClientOrderID string `json:"clientOrderId"`
 	TransactTime  int64  `json:"transactTime"`
 	Price         string `json:"price"`
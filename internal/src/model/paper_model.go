@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PaperAccount holds a user's virtual balances for paper trading.
+type PaperAccount struct {
+	ID        primitive.ObjectID      `bson:"_id,omitempty" json:"id"`
+	UserID    string                  `bson:"user_id" json:"user_id"`
+	Balances  map[string]PaperBalance `bson:"balances" json:"balances"`
+	CreatedAt time.Time               `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time               `bson:"updated_at" json:"updated_at"`
+}
+
+type PaperBalance struct {
+	Free   float64 `bson:"free" json:"free"`
+	Locked float64 `bson:"locked" json:"locked"`
+}
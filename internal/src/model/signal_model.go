@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SignalWebhook is a user's inbound signal endpoint. Only the SHA-256 hash of
+// the secret token is stored; the token itself is shown once on creation.
+type SignalWebhook struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	Enabled   bool               `bson:"enabled" json:"enabled"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// SignalPayload is the JSON body accepted by POST /v1/api/signal.
+//
+//	{
+//	  "token": "<secret, optional when given in the URL>",
+//	  "symbol": "BTCUSDT",
+//	  "side": "buy",              // buy | sell
+//	  "order_type": "market",     // market (default) | limit
+//	  "size_mode": "base",        // base (default) | quote
+//	  "size": "0.001",            // base quantity or quote amount
+//	  "price": "65000",           // required for limit
+//	  "take_profit": "70000",     // optional
+//	  "stop_loss": "60000",       // optional
+//	  "comment": "free text"      // optional, stored with the log
+//	}
+type SignalPayload struct {
+	Token      string `json:"token,omitempty"`
+	Symbol     string `json:"symbol" validate:"required"`
+	Side       string `json:"side" validate:"required"`
+	OrderType  string `json:"order_type"`
+	SizeMode   string `json:"size_mode"`
+	Size       string `json:"size" validate:"required"`
+	Price      string `json:"price,omitempty"`
+	TakeProfit string `json:"take_profit,omitempty"`
+	StopLoss   string `json:"stop_loss,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+// SignalLog records every signal received, whether it passed validation and
+// what order (if any) it produced, so users can debug their alerts.
+type SignalLog struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	WebhookID       primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`
+	RawPayload      string             `bson:"raw_payload" json:"raw_payload"`
+	Payload         SignalPayload      `bson:"payload" json:"payload"`
+	SourceIP        string             `bson:"source_ip" json:"source_ip"`
+	Valid           bool               `bson:"valid" json:"valid"`
+	ValidationError string             `bson:"validation_error,omitempty" json:"validation_error,omitempty"`
+	OrderID         int64              `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	OrderStatus     string             `bson:"order_status,omitempty" json:"order_status,omitempty"`
+	OrderResult     interface{}        `bson:"order_result,omitempty" json:"order_result,omitempty"`
+	OrderError      string             `bson:"order_error,omitempty" json:"order_error,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+}
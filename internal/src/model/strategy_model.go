@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	StrategyStatusRunning = "running"
+	StrategyStatusPaused  = "paused"
+	StrategyStatusStopped = "stopped"
+	StrategyStatusError   = "error" // stopped after a panic or fatal error
+)
+
+type StrategyInstanceRequest struct {
+	Name     string                 `json:"name"`
+	Kind     string                 `json:"kind" validate:"required"` // price_alert | sma_cross
+	Symbol   string                 `json:"symbol" validate:"required"`
+	Interval string                 `json:"interval,omitempty"` // candle interval for OnCandle, e.g. "1m"
+	Config   map[string]interface{} `json:"config"`
+	Paused   bool                   `json:"paused,omitempty"` // create without starting
+}
+
+// StrategyInstance is one user strategy hosted by the runtime. Config is set
+// at creation; State is owned by the strategy and saved after each hook.
+type StrategyInstance struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	UserID     string                 `bson:"user_id" json:"user_id"`
+	Name       string                 `bson:"name" json:"name"`
+	Kind       string                 `bson:"kind" json:"kind"`
+	Symbol     string                 `bson:"symbol" json:"symbol"`
+	Interval   string                 `bson:"interval,omitempty" json:"interval,omitempty"`
+	Config     map[string]interface{} `bson:"config" json:"config"`
+	State      map[string]interface{} `bson:"state" json:"state"`
+	Status     string                 `bson:"status" json:"status"`
+	LastError  string                 `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	PanicCount int                    `bson:"panic_count" json:"panic_count"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time              `bson:"updated_at" json:"updated_at"`
+	StartedAt  *time.Time             `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	StoppedAt  *time.Time             `bson:"stopped_at,omitempty" json:"stopped_at,omitempty"`
+	Runtime    *StrategyRuntimeInfo   `bson:"-" json:"runtime,omitempty"`
+}
+
+// StrategyRuntimeInfo is the live view of a loaded instance.
+type StrategyRuntimeInfo struct {
+	Loaded      bool       `json:"loaded"`
+	QueueDepth  int        `json:"queue_depth"`
+	Events      int64      `json:"events"`
+	LastEventAt *time.Time `json:"last_event_at,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenPair is returned on login and refresh.
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// RefreshToken is stored hashed. Every refresh replaces the token with a new
+// one in the same family; presenting a used token revokes the whole family.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	FamilyID  string             `bson:"family_id" json:"family_id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	UserAgent string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IP        string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+const (
+	RevokedAccessToken = "jti"
+	RevokedSession     = "session"
+)
+
+// RevokedToken blocks an access token, or every access token of a session,
+// until ExpiresAt, after which the tokens would be rejected anyway.
+type RevokedToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Kind      string             `bson:"kind"`
+	Value     string             `bson:"value"`
+	UserID    string             `bson:"user_id"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
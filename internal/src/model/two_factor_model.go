@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TwoFactorEnrollment is returned once when TOTP enrollment starts. The URI
+// is meant to be rendered as a QR code.
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	Pending                bool       `json:"pending"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	StepUpValidUntil       *time.Time `json:"step_up_valid_until,omitempty"`
+}
+
+// StepUp records the last second-factor confirmation of a session.
+type StepUp struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	SessionID  string             `bson:"session_id"`
+	UserID     string             `bson:"user_id"`
+	VerifiedAt time.Time          `bson:"verified_at"`
+}
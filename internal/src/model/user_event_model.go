@@ -0,0 +1,69 @@
+package models
+
+// Event types pushed over the private user WebSocket.
+const (
+	EventOrderUpdate   = "order.update"
+	EventOrderFill     = "order.fill"
+	EventBalanceUpdate = "balance.update"
+	EventAlertTrigger  = "alert.triggered"
+	EventResync        = "resync.required"
+)
+
+// UserEvent is the envelope of every message sent on /ws/private.
+// Seq increases by one per event for a given user, so a client that sees
+// a jump in Seq knows it missed events and should resync over REST.
+type UserEvent struct {
+	Seq  int64       `json:"seq"`
+	Type string      `json:"type"`
+	Time int64       `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+type OrderUpdateEvent struct {
+	Symbol        string `json:"symbol"`
+	OrderID       int64  `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	Status        string `json:"status"`
+	ExecutionType string `json:"executionType"`
+	Price         string `json:"price"`
+	Quantity      string `json:"quantity"`
+	FilledQty     string `json:"filledQty"`
+	RejectReason  string `json:"rejectReason,omitempty"`
+}
+
+type FillEvent struct {
+	Symbol          string `json:"symbol"`
+	OrderID         int64  `json:"orderId"`
+	TradeID         int64  `json:"tradeId"`
+	Side            string `json:"side"`
+	Price           string `json:"price"`
+	Quantity        string `json:"quantity"`
+	QuoteQty        string `json:"quoteQty"`
+	FilledQty       string `json:"filledQty"`
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+}
+
+type BalanceEvent struct {
+	Balances []AssetBalance `json:"balances"`
+}
+
+type AssetBalance struct {
+	Asset  string `json:"asset"`
+	Free   string `json:"free"`
+	Locked string `json:"locked"`
+}
+
+type AlertEvent struct {
+	AlertID string `json:"alertId"`
+	Symbol  string `json:"symbol"`
+	Message string `json:"message"`
+	Price   string `json:"price,omitempty"`
+}
+
+type ResyncEvent struct {
+	Reason  string `json:"reason"`
+	LastSeq int64  `json:"lastSeq"`
+}
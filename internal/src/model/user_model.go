@@ -15,6 +15,20 @@ type User struct {
 	UpdatedAt     time.Time  `bson:"updated_at,omitempty" json:"updated_at"`
 	DeletedAt     *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 	Role          string     `bson:"role" json:"role"` // e.g., "user", "admin"
+	UpstreamRole  string     `bson:"upstream_role,omitempty" json:"upstream_role,omitempty"`
+	RoleOverride  bool       `bson:"role_override,omitempty" json:"role_override,omitempty"` // set by an admin, not synced on login
+	LastLoginAt   *time.Time `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
 	SuspendedAt   *time.Time `bson:"suspended_at,omitempty" json:"suspended_at,omitempty"`
-	Status        bool       `bson:"status" json:"status"` // e.g., "active", "inactive", "suspended"
+	Status        bool       `bson:"status" json:"status"`         // e.g., "active", "inactive", "suspended"
+	PaperMode     bool       `bson:"paper_mode" json:"paper_mode"` // route all orders to the paper account
+	// Two-factor authentication. Secrets are encrypted, recovery codes are
+	// bcrypt hashes that are removed once used.
+	TOTPEnabled       bool       `bson:"totp_enabled,omitempty" json:"totp_enabled"`
+	TOTPEnabledAt     *time.Time `bson:"totp_enabled_at,omitempty" json:"totp_enabled_at,omitempty"`
+	TOTPSecret        string     `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string     `bson:"totp_pending_secret,omitempty" json:"-"` // enrolled, not yet confirmed
+	TOTPLastStep      int64      `bson:"totp_last_step,omitempty" json:"-"`      // last accepted time step, against replay
+	RecoveryCodes     []string   `bson:"recovery_codes,omitempty" json:"-"`
+	// Access tokens issued before this are rejected; set to end all sessions.
+	TokensRevokedAfter *time.Time `bson:"tokens_revoked_after,omitempty" json:"-"`
 }
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	models "exdex/internal/src/model"
+)
+
+// Find returns every document in collection matching filter, decoded as T.
+// The result is never nil, so empty lists serialise as [].
+func Find[T any](ctx context.Context, collection string, filter bson.M, opts *options.FindOptions) ([]T, error) {
+	items := []T{}
+	if err := IRepo.FindByFilter(ctx, collection, &items, filter, opts); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FindPage is Find with the limit, offset and sort of a models.Filter.
+func FindPage[T any](ctx context.Context, collection string, query bson.M, filter models.Filter) ([]T, error) {
+	items := []T{}
+	if err := IRepo.GetAllByFiltter(ctx, collection, &items, query, filter); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FindOne returns the first document matching filter, or mongo.ErrNoDocuments.
+func FindOne[T any](ctx context.Context, collection string, filter bson.M) (T, error) {
+	var item T
+	err := IRepo.FindOneWhere(ctx, collection, filter, &item)
+	return item, err
+}
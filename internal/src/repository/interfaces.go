@@ -9,19 +9,29 @@ import (
 	models "exdex/internal/src/model"
 )
 
+// RepositoryInterfaces is the storage API. Every method takes the caller's
+// context first; prefer the typed helpers in generic.go for reads.
 type RepositoryInterfaces interface {
-	UpdateOne(collectionName string, filter, update bson.M, upsert bool) error
-	FindOneWhere(collectionName string, filter bson.M, result interface{}) error
-	FindByFilter(tableName string, obj interface{}, filter bson.M, opts *options.FindOptions) error
-	Insert(collection string, document interface{}) error
+	UpdateOne(ctx context.Context, collectionName string, filter, update bson.M, upsert bool) error
+	// UpdateOneMatched updates the first document matching filter and
+	// reports whether there was one, for writes conditional on state.
+	UpdateOneMatched(ctx context.Context, collectionName string, filter, update bson.M) (bool, error)
+	// FindOneAndUpdate updates (or with upsert, inserts) the first document
+	// matching filter and decodes it, as it is after the update, into result.
+	FindOneAndUpdate(ctx context.Context, collectionName string, filter, update bson.M, upsert bool, result interface{}) error
+	UpdateMany(ctx context.Context, collectionName string, filter, update bson.M) error
+	FindOneWhere(ctx context.Context, collectionName string, filter bson.M, result interface{}) error
+	FindByFilter(ctx context.Context, tableName string, obj interface{}, filter bson.M, opts *options.FindOptions) error
+	Insert(ctx context.Context, collection string, document interface{}) error
 	GetAll(ctx context.Context, collectionName string, filter bson.M, result interface{}) error
-	FindByKeyValue(tableName string, obj interface{}, key string, value interface{}) error
-	Create(tableName string, item interface{}) error
-	GetByID(collectionName string, id string, result interface{}) error
-	RemoveByFilter(collectionName string, filter bson.M) error
-	GetByEmail(collectionName string, email string, result interface{}) error
-	Count(collectionName string, filter bson.M) (int64, error) // New method
+	FindByKeyValue(ctx context.Context, tableName string, obj interface{}, key string, value interface{}) error
+	Create(ctx context.Context, tableName string, item interface{}) error
+	GetByID(ctx context.Context, collectionName string, id string, result interface{}) error
+	RemoveByFilter(ctx context.Context, collectionName string, filter bson.M) error
+	GetByEmail(ctx context.Context, collectionName string, email string, result interface{}) error
+	Count(ctx context.Context, collectionName string, filter bson.M) (int64, error)
 	GetAllByFiltter(
+		ctx context.Context,
 		collectionName string,
 		items interface{},
 		query bson.M, // 👈 custom filter
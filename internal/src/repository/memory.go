@@ -0,0 +1,896 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	models "exdex/internal/src/model"
+)
+
+// MemoryRepository is a thread-safe, in-memory RepositoryInterfaces so
+// services can be exercised without MongoDB:
+//
+//	repository.IRepo = repository.NewMemoryRepository()
+//
+// Documents are stored as BSON round-trips of what was written, so decoding
+// behaves like the driver. Filters support equality (dotted paths reach into
+// arrays of documents), $in, $nin, $ne, $gt, $gte, $lt, $lte, $exists,
+// $regex, $or and $and. Updates support $set, $unset, $setOnInsert, $inc,
+// $push and $pull. Anything else is reported as an error rather than
+// silently ignored.
+type MemoryRepository struct {
+	mu          sync.RWMutex
+	collections map[string][]bson.M
+	unique      map[string][][]string
+}
+
+var _ RepositoryInterfaces = (*MemoryRepository)(nil)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		collections: make(map[string][]bson.M),
+		unique:      make(map[string][][]string),
+	}
+}
+
+// Unique makes writes to collection fail with a duplicate key error when
+// another document has the same values for keys, like a unique index.
+func (m *MemoryRepository) Unique(collection string, keys ...string) *MemoryRepository {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unique[collection] = append(m.unique[collection], keys)
+	return m
+}
+
+func (m *MemoryRepository) Create(ctx context.Context, collectionName string, item interface{}) error {
+	now := time.Now()
+	if ts, ok := item.(models.TimestampSetter); ok {
+		ts.SetCreatedAt(now)
+		ts.SetUpdatedAt(now)
+	}
+	return m.Insert(ctx, collectionName, item)
+}
+
+func (m *MemoryRepository) Insert(ctx context.Context, collection string, document interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	doc, err := toDoc(document)
+	if err != nil {
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = primitive.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkUnique(collection, doc, -1); err != nil {
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+	m.collections[collection] = append(m.collections[collection], doc)
+	return nil
+}
+
+func (m *MemoryRepository) FindOneWhere(ctx context.Context, collectionName string, filter bson.M, result interface{}) error {
+	docs, err := m.find(ctx, collectionName, filter, options.Find().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return decode(docs[0], result)
+}
+
+func (m *MemoryRepository) FindByFilter(ctx context.Context, tableName string, obj interface{}, filter bson.M, opts *options.FindOptions) error {
+	docs, err := m.find(ctx, tableName, filter, opts)
+	if err != nil {
+		return err
+	}
+	return decodeAll(docs, obj)
+}
+
+func (m *MemoryRepository) GetAllByFiltter(ctx context.Context, collectionName string, items interface{}, query bson.M, filter models.Filter) error {
+	opts := options.Find().SetLimit(int64(filter.Limit)).SetSkip(int64(filter.Offset))
+	if filter.Sort != "" {
+		opts.SetSort(bson.M{filter.Sort: filter.SortOrder})
+	}
+	return m.FindByFilter(ctx, collectionName, items, query, opts)
+}
+
+func (m *MemoryRepository) GetAll(ctx context.Context, collectionName string, filter bson.M, result interface{}) error {
+	return m.FindByFilter(ctx, collectionName, result, filter, nil)
+}
+
+func (m *MemoryRepository) FindByKeyValue(ctx context.Context, tableName string, obj interface{}, key string, value interface{}) error {
+	return m.FindByFilter(ctx, tableName, obj, bson.M{key: value}, nil)
+}
+
+func (m *MemoryRepository) GetByID(ctx context.Context, collectionName string, id string, result interface{}) error {
+	err := m.FindOneWhere(ctx, collectionName, bson.M{"id": id}, result)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("no document found with ID: %s", id)
+	}
+	return err
+}
+
+func (m *MemoryRepository) GetByEmail(ctx context.Context, collectionName string, email string, result interface{}) error {
+	err := m.FindOneWhere(ctx, collectionName, bson.M{"email": email}, result)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("no document found with email: %s", email)
+	}
+	return err
+}
+
+func (m *MemoryRepository) RemoveByFilter(ctx context.Context, collectionName string, filter bson.M) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	docs := m.collections[collectionName]
+	for i, doc := range docs {
+		ok, err := matches(doc, f)
+		if err != nil {
+			return err
+		}
+		if ok {
+			m.collections[collectionName] = append(docs[:i:i], docs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching record")
+}
+
+func (m *MemoryRepository) Count(ctx context.Context, collectionName string, filter bson.M) (int64, error) {
+	docs, err := m.find(ctx, collectionName, filter, nil)
+	return int64(len(docs)), err
+}
+
+func (m *MemoryRepository) UpdateOne(ctx context.Context, collectionName string, filter, update bson.M, upsert bool) error {
+	_, _, err := m.update(ctx, collectionName, filter, update, upsert, false)
+	return err
+}
+
+func (m *MemoryRepository) UpdateOneMatched(ctx context.Context, collectionName string, filter, update bson.M) (bool, error) {
+	matched, _, err := m.update(ctx, collectionName, filter, update, false, false)
+	return matched, err
+}
+
+func (m *MemoryRepository) FindOneAndUpdate(ctx context.Context, collectionName string, filter, update bson.M, upsert bool, result interface{}) error {
+	_, doc, err := m.update(ctx, collectionName, filter, update, upsert, false)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return mongo.ErrNoDocuments
+	}
+	return decode(doc, result)
+}
+
+func (m *MemoryRepository) UpdateMany(ctx context.Context, collectionName string, filter, update bson.M) error {
+	_, _, err := m.update(ctx, collectionName, filter, update, false, true)
+	return err
+}
+
+func (m *MemoryRepository) find(ctx context.Context, collection string, filter bson.M, opts *options.FindOptions) ([]bson.M, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []bson.M
+	for _, doc := range m.collections[collection] {
+		ok, err := matches(doc, f)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, doc)
+		}
+	}
+	if opts == nil {
+		return out, nil
+	}
+
+	if opts.Sort != nil {
+		spec, err := toSortSpec(opts.Sort)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(out, func(i, j int) bool {
+			for _, s := range spec {
+				c := compareValues(first(lookup(out[i], s.key)), first(lookup(out[j], s.key)))
+				if c != 0 {
+					return c*s.dir < 0
+				}
+			}
+			return false
+		})
+	}
+	if opts.Skip != nil && *opts.Skip > 0 {
+		if int(*opts.Skip) >= len(out) {
+			out = nil
+		} else {
+			out = out[*opts.Skip:]
+		}
+	}
+	if opts.Limit != nil && *opts.Limit > 0 && int(*opts.Limit) < len(out) {
+		out = out[:*opts.Limit]
+	}
+	if opts.Projection != nil {
+		return project(out, opts.Projection)
+	}
+	return out, nil
+}
+
+func (m *MemoryRepository) update(ctx context.Context, collection string, filter, update bson.M, upsert, many bool) (matched bool, last bson.M, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, nil, err
+	}
+	f, err := toDoc(filter)
+	if err != nil {
+		return false, nil, err
+	}
+	u, err := toDoc(update)
+	if err != nil {
+		return false, nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs := m.collections[collection]
+	for i, doc := range docs {
+		ok, err := matches(doc, f)
+		if err != nil {
+			return false, nil, err
+		}
+		if !ok {
+			continue
+		}
+		matched = true
+		// Work on a copy so a failed update or unique check leaves the
+		// stored document untouched.
+		updated, err := toDoc(doc)
+		if err != nil {
+			return false, nil, err
+		}
+		if err := applyUpdate(updated, u, false); err != nil {
+			return false, nil, err
+		}
+		if err := m.checkUnique(collection, updated, i); err != nil {
+			return false, nil, err
+		}
+		docs[i] = updated
+		last = updated
+		if !many {
+			return true, updated, nil
+		}
+	}
+	if matched || !upsert {
+		return matched, last, nil
+	}
+
+	doc := bson.M{}
+	for k, v := range f {
+		if strings.HasPrefix(k, "$") || isOperatorDoc(v) {
+			continue
+		}
+		if err := setPath(doc, k, v); err != nil {
+			return false, nil, err
+		}
+	}
+	if err := applyUpdate(doc, u, true); err != nil {
+		return false, nil, err
+	}
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = primitive.NewObjectID()
+	}
+	if err := m.checkUnique(collection, doc, -1); err != nil {
+		return false, nil, err
+	}
+	m.collections[collection] = append(docs, doc)
+	return false, doc, nil
+}
+
+// checkUnique reports a duplicate key error if doc clashes with any stored
+// document other than the one at index self. Callers hold m.mu.
+func (m *MemoryRepository) checkUnique(collection string, doc bson.M, self int) error {
+	for _, keys := range m.unique[collection] {
+		for i, other := range m.collections[collection] {
+			if i == self {
+				continue
+			}
+			clash := true
+			for _, k := range keys {
+				if !valuesEqual(first(lookup(doc, k)), first(lookup(other, k))) {
+					clash = false
+					break
+				}
+			}
+			if clash {
+				return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+					Code:    11000,
+					Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: %s", collection, strings.Join(keys, "_")),
+				}}}
+			}
+		}
+	}
+	return nil
+}
+
+// toDoc round-trips v through BSON so stored documents, filters and updates
+// all use the driver's representation (DateTime for time.Time, primitive.A
+// for slices, and so on).
+func toDoc(v interface{}) (bson.M, error) {
+	if v == nil {
+		return bson.M{}, nil
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return normalize(doc).(bson.M), nil
+}
+
+// normalize turns nested bson.D values into bson.M so lookups only have to
+// deal with one document type.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		for k, e := range t {
+			t[k] = normalize(e)
+		}
+		return t
+	case bson.D:
+		m := make(bson.M, len(t))
+		for _, e := range t {
+			m[e.Key] = normalize(e.Value)
+		}
+		return m
+	case primitive.A:
+		for i, e := range t {
+			t[i] = normalize(e)
+		}
+		return t
+	}
+	return v
+}
+
+func decode(doc bson.M, result interface{}) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, result)
+}
+
+func decodeAll(docs []bson.M, obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("results argument must be a pointer to a slice")
+	}
+	slice := reflect.MakeSlice(rv.Elem().Type(), 0, len(docs))
+	for _, doc := range docs {
+		elem := reflect.New(slice.Type().Elem())
+		if err := decode(doc, elem.Interface()); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+// lookup returns the values at a dotted path. Arrays of documents fan out,
+// so "levels.order_id" yields the order_id of every level.
+func lookup(v interface{}, path string) []interface{} {
+	return lookupParts(v, strings.Split(path, "."))
+}
+
+func lookupParts(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+	switch t := v.(type) {
+	case bson.M:
+		child, ok := t[parts[0]]
+		if !ok {
+			return nil
+		}
+		return lookupParts(child, parts[1:])
+	case primitive.A:
+		if i, err := strconv.Atoi(parts[0]); err == nil {
+			if i >= 0 && i < len(t) {
+				return lookupParts(t[i], parts[1:])
+			}
+			return nil
+		}
+		var out []interface{}
+		for _, e := range t {
+			out = append(out, lookupParts(e, parts)...)
+		}
+		return out
+	}
+	return nil
+}
+
+func first(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+func isOperatorDoc(v interface{}) bool {
+	m, ok := v.(bson.M)
+	if !ok || len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(doc, filter bson.M) (bool, error) {
+	for key, cond := range filter {
+		switch key {
+		case "$or", "$and":
+			clauses, ok := cond.(primitive.A)
+			if !ok {
+				return false, fmt.Errorf("%s needs an array", key)
+			}
+			matched := false
+			for _, c := range clauses {
+				sub, ok := c.(bson.M)
+				if !ok {
+					return false, fmt.Errorf("%s clauses must be documents", key)
+				}
+				ok, err := matches(doc, sub)
+				if err != nil {
+					return false, err
+				}
+				if ok && key == "$or" {
+					matched = true
+					break
+				}
+				if !ok && key == "$and" {
+					return false, nil
+				}
+			}
+			if key == "$or" && !matched {
+				return false, nil
+			}
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unsupported query operator %s", key)
+			}
+			ok, err := matchField(lookup(doc, key), cond)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func matchField(values []interface{}, cond interface{}) (bool, error) {
+	if !isOperatorDoc(cond) {
+		return matchEq(values, cond), nil
+	}
+	ops := cond.(bson.M)
+	for op, arg := range ops {
+		var ok bool
+		switch op {
+		case "$exists":
+			ok = (len(values) > 0) == truthy(arg)
+		case "$ne":
+			ok = !matchEq(values, arg)
+		case "$in", "$nin":
+			list, isList := arg.(primitive.A)
+			if !isList {
+				return false, fmt.Errorf("%s needs an array", op)
+			}
+			for _, want := range list {
+				if matchEq(values, want) {
+					ok = true
+					break
+				}
+			}
+			if op == "$nin" {
+				ok = !ok
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			for _, v := range flatten(values) {
+				c, comparable := compare(v, arg)
+				if !comparable {
+					continue
+				}
+				if (op == "$gt" && c > 0) || (op == "$gte" && c >= 0) || (op == "$lt" && c < 0) || (op == "$lte" && c <= 0) {
+					ok = true
+					break
+				}
+			}
+		case "$regex":
+			pattern, _ := arg.(string)
+			if r, isRegex := arg.(primitive.Regex); isRegex {
+				pattern = r.Pattern
+				if r.Options != "" {
+					pattern = "(?" + r.Options + ")" + pattern
+				}
+			} else if opt, _ := ops["$options"].(string); opt != "" {
+				pattern = "(?" + opt + ")" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return false, err
+			}
+			ok = matchRegex(values, re)
+		case "$options":
+			ok = true
+		default:
+			return false, fmt.Errorf("unsupported query operator %s", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchEq follows Mongo equality: a field matches if it equals want or is an
+// array containing want, and a missing field matches null.
+func matchEq(values []interface{}, want interface{}) bool {
+	if r, ok := want.(primitive.Regex); ok {
+		pattern := r.Pattern
+		if r.Options != "" {
+			pattern = "(?" + r.Options + ")" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		return err == nil && matchRegex(values, re)
+	}
+	if want == nil && len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if valuesEqual(v, want) {
+			return true
+		}
+		if arr, ok := v.(primitive.A); ok {
+			for _, e := range arr {
+				if valuesEqual(e, want) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchRegex(values []interface{}, re *regexp.Regexp) bool {
+	for _, v := range flatten(values) {
+		if s, ok := v.(string); ok && re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func flatten(values []interface{}) []interface{} {
+	var out []interface{}
+	for _, v := range values {
+		if arr, ok := v.(primitive.A); ok {
+			out = append(out, arr...)
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case nil:
+		return false
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// compare orders two values of the same BSON kind. Values of different
+// kinds are not comparable, as with Mongo's range operators.
+func compare(a, b interface{}) (int, bool) {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		return cmpOrdered(fa, fb), true
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	case primitive.DateTime:
+		y, ok := b.(primitive.DateTime)
+		return cmpOrdered(x, y), ok
+	case primitive.ObjectID:
+		y, ok := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:]), ok
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if x == y {
+			return 0, true
+		}
+		if !x {
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+func cmpOrdered[T int64 | float64 | primitive.DateTime](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues is compare for sorting: missing values sort first and
+// mismatched kinds are treated as equal.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	c, _ := compare(a, b)
+	return c
+}
+
+type sortKey struct {
+	key string
+	dir int
+}
+
+func toSortSpec(spec interface{}) ([]sortKey, error) {
+	var out []sortKey
+	add := func(k string, v interface{}) {
+		dir := 1
+		if f, ok := toFloat(v); ok && f < 0 {
+			dir = -1
+		}
+		out = append(out, sortKey{key: k, dir: dir})
+	}
+	switch s := spec.(type) {
+	case bson.D:
+		for _, e := range s {
+			add(e.Key, e.Value)
+		}
+	case bson.M:
+		for k, v := range s {
+			add(k, v)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sort spec %T", spec)
+	}
+	return out, nil
+}
+
+// project applies a top-level inclusion or exclusion projection.
+func project(docs []bson.M, projection interface{}) ([]bson.M, error) {
+	p, err := toDoc(projection)
+	if err != nil {
+		return nil, err
+	}
+	include := false
+	for k, v := range p {
+		if k != "_id" && truthy(v) {
+			include = true
+		}
+	}
+	out := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		d := bson.M{}
+		for k, v := range doc {
+			flag, listed := p[k]
+			switch {
+			case include && k == "_id" && (!listed || truthy(flag)):
+				d[k] = v
+			case include && listed && truthy(flag):
+				d[k] = v
+			case !include && !(listed && !truthy(flag)):
+				d[k] = v
+			}
+		}
+		out[i] = d
+	}
+	return out, nil
+}
+
+func applyUpdate(doc, update bson.M, inserting bool) error {
+	for op, spec := range update {
+		fields, ok := spec.(bson.M)
+		if !ok {
+			return fmt.Errorf("%s needs a document", op)
+		}
+		for path, v := range fields {
+			var err error
+			switch op {
+			case "$set":
+				err = setPath(doc, path, v)
+			case "$setOnInsert":
+				if inserting {
+					err = setPath(doc, path, v)
+				}
+			case "$unset":
+				unsetPath(doc, path)
+			case "$inc":
+				err = incPath(doc, path, v)
+			case "$push":
+				arr, _ := first(lookup(doc, path)).(primitive.A)
+				err = setPath(doc, path, append(arr, v))
+			case "$pull":
+				arr, _ := first(lookup(doc, path)).(primitive.A)
+				kept := primitive.A{}
+				for _, e := range arr {
+					drop := valuesEqual(e, v)
+					if sub, isDoc := e.(bson.M); isDoc {
+						if cond, isCond := v.(bson.M); isCond {
+							drop, err = matches(sub, cond)
+							if err != nil {
+								return err
+							}
+						}
+					}
+					if !drop {
+						kept = append(kept, e)
+					}
+				}
+				if arr != nil {
+					err = setPath(doc, path, kept)
+				}
+			default:
+				return fmt.Errorf("unsupported update operator %s", op)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setPath(doc bson.M, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	var cur interface{} = doc
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch c := cur.(type) {
+		case bson.M:
+			if last {
+				c[part] = value
+				return nil
+			}
+			next, ok := c[part]
+			if !ok || next == nil {
+				next = bson.M{}
+				c[part] = next
+			}
+			cur = next
+		case primitive.A:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return fmt.Errorf("cannot set %s: bad array index %q", path, part)
+			}
+			if last {
+				c[idx] = value
+				return nil
+			}
+			cur = c[idx]
+		default:
+			return fmt.Errorf("cannot set %s: %s is not a document", path, strings.Join(parts[:i], "."))
+		}
+	}
+	return nil
+}
+
+func unsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	parent := lookupParts(doc, parts[:len(parts)-1])
+	for _, p := range parent {
+		if m, ok := p.(bson.M); ok {
+			delete(m, parts[len(parts)-1])
+		}
+	}
+}
+
+func incPath(doc bson.M, path string, by interface{}) error {
+	delta, ok := toFloat(by)
+	if !ok {
+		return fmt.Errorf("cannot $inc %s by non-number %v", path, by)
+	}
+	cur := first(lookup(doc, path))
+	if cur == nil {
+		return setPath(doc, path, by)
+	}
+	base, ok := toFloat(cur)
+	if !ok {
+		return fmt.Errorf("cannot $inc non-number field %s", path)
+	}
+	_, curFloat := cur.(float64)
+	_, byFloat := by.(float64)
+	if curFloat || byFloat {
+		return setPath(doc, path, base+delta)
+	}
+	return setPath(doc, path, int64(base)+int64(delta))
+}
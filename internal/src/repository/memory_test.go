@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type memLine struct {
+	Sku string `bson:"sku"`
+	Qty int    `bson:"qty"`
+}
+
+type memItem struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty"`
+	Name  string             `bson:"name"`
+	Qty   int                `bson:"qty"`
+	Price float64            `bson:"price"`
+	Tags  []string           `bson:"tags,omitempty"`
+	Note  string             `bson:"note,omitempty"`
+	Lines []memLine          `bson:"lines,omitempty"`
+}
+
+func seedMemory(t *testing.T) *MemoryRepository {
+	t.Helper()
+	m := NewMemoryRepository()
+	items := []memItem{
+		{Name: "apple", Qty: 5, Price: 1.5, Tags: []string{"fruit", "red"}, Note: "fresh"},
+		{Name: "banana", Qty: 12, Price: 0.5, Tags: []string{"fruit"}},
+		{Name: "carrot", Qty: 0, Price: 0.8, Tags: []string{"vegetable"}, Lines: []memLine{{Sku: "c-1", Qty: 2}}},
+		{Name: "durian", Qty: 1, Price: 9.0, Note: "smelly"},
+	}
+	for i := range items {
+		if err := m.Insert(context.Background(), "items", &items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func names(t *testing.T, m *MemoryRepository, filter bson.M, opts *options.FindOptions) []string {
+	t.Helper()
+	var items []memItem
+	if err := m.FindByFilter(context.Background(), "items", &items, filter, opts); err != nil {
+		t.Fatalf("FindByFilter(%v): %v", filter, err)
+	}
+	out := []string{}
+	for _, it := range items {
+		out = append(out, it.Name)
+	}
+	return out
+}
+
+func TestMemoryFilters(t *testing.T) {
+	m := seedMemory(t)
+	tests := []struct {
+		name   string
+		filter bson.M
+		want   []string
+	}{
+		{"equality", bson.M{"name": "banana"}, []string{"banana"}},
+		{"array contains", bson.M{"tags": "fruit"}, []string{"apple", "banana"}},
+		{"$in", bson.M{"name": bson.M{"$in": []string{"apple", "durian", "kiwi"}}}, []string{"apple", "durian"}},
+		{"$in on array field", bson.M{"tags": bson.M{"$in": []string{"red", "vegetable"}}}, []string{"apple", "carrot"}},
+		{"$nin", bson.M{"name": bson.M{"$nin": []string{"apple", "banana"}}}, []string{"carrot", "durian"}},
+		{"$gt", bson.M{"qty": bson.M{"$gt": 1}}, []string{"apple", "banana"}},
+		{"$lt", bson.M{"price": bson.M{"$lt": 1.0}}, []string{"banana", "carrot"}},
+		{"$gte and $lte", bson.M{"qty": bson.M{"$gte": 1, "$lte": 5}}, []string{"apple", "durian"}},
+		{"$ne", bson.M{"qty": bson.M{"$ne": 0}}, []string{"apple", "banana", "durian"}},
+		{"$exists true", bson.M{"note": bson.M{"$exists": true}}, []string{"apple", "durian"}},
+		{"$exists false", bson.M{"note": bson.M{"$exists": false}}, []string{"banana", "carrot"}},
+		{"dotted path into array", bson.M{"lines.sku": "c-1"}, []string{"carrot"}},
+		{"$or", bson.M{"$or": []bson.M{{"qty": 0}, {"price": bson.M{"$gt": 5}}}}, []string{"carrot", "durian"}},
+		{"no match", bson.M{"name": "kiwi"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(t, m, tt.filter, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryFindOptions(t *testing.T) {
+	m := seedMemory(t)
+	tests := []struct {
+		name string
+		opts *options.FindOptions
+		want []string
+	}{
+		{"sort ascending", options.Find().SetSort(bson.M{"price": 1}), []string{"banana", "carrot", "apple", "durian"}},
+		{"sort descending", options.Find().SetSort(bson.D{{Key: "qty", Value: -1}}), []string{"banana", "apple", "durian", "carrot"}},
+		{"limit", options.Find().SetSort(bson.M{"name": 1}).SetLimit(2), []string{"apple", "banana"}},
+		{"skip and limit", options.Find().SetSort(bson.M{"name": 1}).SetSkip(1).SetLimit(2), []string{"banana", "carrot"}},
+		{"skip past the end", options.Find().SetSkip(10), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(t, m, bson.M{}, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryUpdates(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.M
+		update bson.M
+		upsert bool
+		check  func(t *testing.T, it memItem)
+	}{
+		{
+			name:   "$set",
+			filter: bson.M{"name": "apple"},
+			update: bson.M{"$set": bson.M{"qty": 7, "note": "ripe"}},
+			check: func(t *testing.T, it memItem) {
+				if it.Qty != 7 || it.Note != "ripe" {
+					t.Errorf("got qty %d note %q", it.Qty, it.Note)
+				}
+			},
+		},
+		{
+			name:   "$setOnInsert is skipped on update",
+			filter: bson.M{"name": "apple"},
+			update: bson.M{"$set": bson.M{"qty": 6}, "$setOnInsert": bson.M{"price": 100.0}},
+			upsert: true,
+			check: func(t *testing.T, it memItem) {
+				if it.Qty != 6 || it.Price != 1.5 {
+					t.Errorf("got qty %d price %v, want 6 and the old 1.5", it.Qty, it.Price)
+				}
+			},
+		},
+		{
+			name:   "$unset",
+			filter: bson.M{"name": "apple"},
+			update: bson.M{"$unset": bson.M{"note": ""}},
+			check: func(t *testing.T, it memItem) {
+				if it.Note != "" {
+					t.Errorf("note = %q, want it removed", it.Note)
+				}
+			},
+		},
+		{
+			name:   "$pull a value",
+			filter: bson.M{"name": "apple"},
+			update: bson.M{"$pull": bson.M{"tags": "red"}},
+			check: func(t *testing.T, it memItem) {
+				if !reflect.DeepEqual(it.Tags, []string{"fruit"}) {
+					t.Errorf("tags = %v", it.Tags)
+				}
+			},
+		},
+		{
+			name:   "$pull by condition",
+			filter: bson.M{"name": "carrot"},
+			update: bson.M{"$pull": bson.M{"lines": bson.M{"sku": "c-1"}}},
+			check: func(t *testing.T, it memItem) {
+				if len(it.Lines) != 0 {
+					t.Errorf("lines = %v, want none", it.Lines)
+				}
+			},
+		},
+		{
+			name:   "upsert inserts with filter fields and $setOnInsert",
+			filter: bson.M{"name": "kiwi"},
+			update: bson.M{"$set": bson.M{"qty": 3}, "$setOnInsert": bson.M{"price": 2.0}},
+			upsert: true,
+			check: func(t *testing.T, it memItem) {
+				if it.ID.IsZero() || it.Qty != 3 || it.Price != 2 {
+					t.Errorf("got %+v", it)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := seedMemory(t)
+			if err := m.UpdateOne(ctx, "items", tt.filter, tt.update, tt.upsert); err != nil {
+				t.Fatalf("UpdateOne: %v", err)
+			}
+			var it memItem
+			if err := m.FindOneWhere(ctx, "items", tt.filter, &it); err != nil {
+				t.Fatalf("FindOneWhere: %v", err)
+			}
+			tt.check(t, it)
+		})
+	}
+}
+
+func TestMemoryUpdateWithoutUpsertLeavesCollection(t *testing.T) {
+	ctx := context.Background()
+	m := seedMemory(t)
+	if err := m.UpdateOne(ctx, "items", bson.M{"name": "kiwi"}, bson.M{"$set": bson.M{"qty": 1}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.Count(ctx, "items", bson.M{}); n != 4 {
+		t.Fatalf("count = %d, want 4", n)
+	}
+}
+
+func TestMemoryUpdateOneMatched(t *testing.T) {
+	ctx := context.Background()
+	m := seedMemory(t)
+	filter := bson.M{"name": "apple", "tags": "red"}
+	update := bson.M{"$pull": bson.M{"tags": "red"}}
+
+	for i, want := range []bool{true, false} {
+		matched, err := m.UpdateOneMatched(ctx, "items", filter, update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched != want {
+			t.Errorf("call %d: matched = %v, want %v", i+1, matched, want)
+		}
+	}
+}
+
+func TestMemoryFindOneAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryRepository()
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	for want := int64(1); want <= 3; want++ {
+		if err := m.FindOneAndUpdate(ctx, "counters", bson.M{"_id": "seq"}, bson.M{"$inc": bson.M{"seq": int64(1)}}, true, &counter); err != nil {
+			t.Fatal(err)
+		}
+		if counter.Seq != want {
+			t.Fatalf("seq = %d, want %d", counter.Seq, want)
+		}
+	}
+	if err := m.FindOneAndUpdate(ctx, "counters", bson.M{"_id": "other"}, bson.M{"$inc": bson.M{"seq": int64(1)}}, false, &counter); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("FindOneAndUpdate without upsert error = %v, want mongo.ErrNoDocuments", err)
+	}
+}
+
+func TestMemoryNoDocuments(t *testing.T) {
+	ctx := context.Background()
+	m := seedMemory(t)
+	var it memItem
+	if err := m.FindOneWhere(ctx, "items", bson.M{"name": "kiwi"}, &it); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("FindOneWhere error = %v, want mongo.ErrNoDocuments", err)
+	}
+	if err := m.FindOneWhere(ctx, "missing", bson.M{}, &it); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("FindOneWhere on an empty collection error = %v, want mongo.ErrNoDocuments", err)
+	}
+
+	prev := IRepo
+	IRepo = m
+	defer func() { IRepo = prev }()
+	if _, err := FindOne[memItem](ctx, "items", bson.M{"qty": bson.M{"$gt": 100}}); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("FindOne error = %v, want mongo.ErrNoDocuments", err)
+	}
+	items, err := Find[memItem](ctx, "items", bson.M{"name": "kiwi"}, nil)
+	if err != nil || items == nil || len(items) != 0 {
+		t.Errorf("Find = %v, %v, want an empty non-nil list", items, err)
+	}
+}
+
+func TestMemoryUnique(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryRepository().Unique("items", "name")
+	if err := m.Insert(ctx, "items", &memItem{Name: "apple"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Insert(ctx, "items", &memItem{Name: "apple"}); err == nil {
+		t.Error("second insert with the same name succeeded")
+	}
+	if err := m.Insert(ctx, "items", &memItem{Name: "pear"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateOne(ctx, "items", bson.M{"name": "pear"}, bson.M{"$set": bson.M{"name": "apple"}}, false); err == nil {
+		t.Error("update onto an existing name succeeded")
+	}
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -11,8 +10,10 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"exdex/config"
 	models "exdex/internal/src/model"
 	database "exdex/server/databases"
+	"exdex/server/metrics"
 )
 
 type MongoDBRepository struct {
@@ -22,11 +23,61 @@ type MongoDBRepository struct {
 
 var IRepo RepositoryInterfaces
 
+// Default per-operation timeouts. They only apply when the caller's context
+// carries no deadline of its own, so a request context bounds every query
+// while background jobs still cannot hang on a stuck server.
+var (
+	ReadTimeout  = 10 * time.Second
+	WriteTimeout = 10 * time.Second
+	CountTimeout = 5 * time.Second
+
+	// SlowQuery is the duration above which an operation is logged.
+	SlowQuery = 500 * time.Millisecond
+)
+
 func Init() {
+	if d := config.C.Mongo.Timeouts.Read; d > 0 {
+		ReadTimeout = d
+	}
+	if d := config.C.Mongo.Timeouts.Write; d > 0 {
+		WriteTimeout = d
+	}
+	if d := config.C.Mongo.Timeouts.Count; d > 0 {
+		CountTimeout = d
+	}
+	if d := config.C.Mongo.SlowQuery; d > 0 {
+		SlowQuery = d
+	}
 	IRepo = &MongoDBRepository{}
 }
 
-func (r *MongoDBRepository) Create(collectionName string, item interface{}) error {
+// withTimeout bounds ctx by d unless it already has a deadline.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+var opSeconds = metrics.NewHistogram("exdex_mongo_operation_duration_seconds",
+	"MongoDB operation latency by operation and collection.",
+	metrics.DefBuckets, "op", "collection")
+
+// observeOp records an operation's latency and logs it when slower than
+// SlowQuery. Only the operation and collection are logged, never filters or
+// results.
+func observeOp(op, collection string, start time.Time) {
+	elapsed := time.Since(start)
+	opSeconds.Observe(elapsed.Seconds(), op, collection)
+	if elapsed > SlowQuery {
+		log.Printf("[mongo] slow %s on %s took %s", op, collection, elapsed.Round(time.Millisecond))
+	}
+}
+
+func (r *MongoDBRepository) Create(ctx context.Context, collectionName string, item interface{}) error {
 	// Set the CreatedAt and UpdatedAt fields to the current time
 	now := time.Now()
 
@@ -35,16 +86,11 @@ func (r *MongoDBRepository) Create(collectionName string, item interface{}) erro
 		ts.SetUpdatedAt(now)
 	}
 
-	collection := database.DB.Collection(collectionName)
-
-	_, err := collection.InsertOne(context.Background(), item)
-	if err != nil {
-		return err
-	}
-	return nil
+	return r.Insert(ctx, collectionName, item)
 }
 
 func (r *MongoDBRepository) GetAllByFiltter(
+	ctx context.Context,
 	collectionName string,
 	items interface{},
 	query bson.M, // 👈 custom filter
@@ -58,205 +104,125 @@ func (r *MongoDBRepository) GetAllByFiltter(
 		findOptions.SetSort(bson.M{filter.Sort: filter.SortOrder})
 	}
 
-	collection := database.DB.Collection(collectionName)
-	cursor, err := collection.Find(context.Background(), query, findOptions)
-	if err != nil {
-		return err
-	}
-	defer cursor.Close(context.Background())
-
-	return cursor.All(context.Background(), items)
+	return r.FindByFilter(ctx, collectionName, items, query, findOptions)
 }
 
-func (r *MongoDBRepository) FindOneWhere(collectionName string, filter bson.M, result interface{}) error {
-	collection := database.DB.Collection(collectionName)
-	err := collection.FindOne(context.Background(), filter).Decode(result)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			log.Printf("No documents found in collection %s for filter: %v", collectionName, filter)
-			// return fmt.Errorf("no document found: %v", err)
-			return err
-		}
-		log.Printf("Error finding document in collection %s: %v", collectionName, err)
-		return err
-	}
-	log.Printf("Successfully found document in collection %s", collectionName)
-	return nil
+func (r *MongoDBRepository) FindOneWhere(ctx context.Context, collectionName string, filter bson.M, result interface{}) error {
+	ctx, cancel := withTimeout(ctx, ReadTimeout)
+	defer cancel()
+	defer observeOp("findOne", collectionName, time.Now())
+
+	return database.DB.Collection(collectionName).FindOne(ctx, filter).Decode(result)
 }
 
-func (r *MongoDBRepository) FindByFilter(tableName string, obj interface{}, filter bson.M, opts *options.FindOptions) error {
-	collection := database.DB.Collection(tableName)
-	// Log the query
-	queryJSON, _ := bson.Marshal(filter)
-	log.Printf("[FindByFilter] Preparing to execute MongoDB query on table '%s': %s\n", tableName, queryJSON)
+func (r *MongoDBRepository) FindByFilter(ctx context.Context, tableName string, obj interface{}, filter bson.M, opts *options.FindOptions) error {
+	ctx, cancel := withTimeout(ctx, ReadTimeout)
+	defer cancel()
+	defer observeOp("find", tableName, time.Now())
 
-	cursor, err := collection.Find(context.Background(), filter, opts)
-	if err != nil {
-		log.Printf("[FindByFilter] Error executing MongoDB query on table '%s': %s\n", tableName, err)
-		return err
-	}
-	defer func() {
-		if err := cursor.Close(context.Background()); err != nil {
-			log.Printf("[FindByFilter] Error closing cursor on table '%s': %s\n", tableName, err)
-		}
-	}()
-	err = cursor.All(context.Background(), obj)
+	cursor, err := database.DB.Collection(tableName).Find(ctx, filter, opts)
 	if err != nil {
-		log.Printf("[FindByFilter] Error fetching all results from cursor on table '%s': %s\n", tableName, err)
 		return err
 	}
-	log.Printf("[FindByFilter] Successfully fetched results from table '%s'\n", tableName)
+	defer cursor.Close(ctx)
 
-	return nil
+	return cursor.All(ctx, obj)
 }
 
-func (r *MongoDBRepository) Insert(collection string, document interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *MongoDBRepository) Insert(ctx context.Context, collection string, document interface{}) error {
+	ctx, cancel := withTimeout(ctx, WriteTimeout)
 	defer cancel()
+	defer observeOp("insert", collection, time.Now())
 
-	// Get collection from database
-	coll := database.DB.Collection(collection)
-
-	// Insert the document
-	_, err := coll.InsertOne(ctx, document)
-	if err != nil {
-		// Log the error with more details for debugging
-		log.Printf("Error inserting document into %s: %v", collection, err)
+	if _, err := database.DB.Collection(collection).InsertOne(ctx, document); err != nil {
 		return fmt.Errorf("failed to insert document: %w", err)
 	}
-
 	return nil
 }
 
 func (r *MongoDBRepository) GetAll(ctx context.Context, collectionName string, filter bson.M, result interface{}) error {
-	collection := database.DB.Collection(collectionName)
-
-	cursor, err := collection.Find(ctx, filter)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = cursor.Close(ctx) // closing cursor, ignoring close error
-	}()
-
-	if err := cursor.All(ctx, result); err != nil {
-		return err
-	}
-	return nil
+	return r.FindByFilter(ctx, collectionName, result, filter, nil)
 }
 
-func (r *MongoDBRepository) FindByKeyValue(tableName string, obj interface{}, key string, value interface{}) error {
-	collection := database.DB.Collection(tableName)
-	filter := bson.M{key: value}
-
-	// Log the query
-	queryJSON, _ := json.Marshal(filter)
-	log.Printf("Executing MongoDB query on table %s with filter: %s\n", tableName, queryJSON)
+func (r *MongoDBRepository) FindByKeyValue(ctx context.Context, tableName string, obj interface{}, key string, value interface{}) error {
+	return r.FindByFilter(ctx, tableName, obj, bson.M{key: value}, nil)
+}
 
-	cursor, err := collection.Find(context.Background(), filter)
-	if err != nil {
-		log.Printf("Error while executing Find operation on table %s: %v", tableName, err)
-		return err
-	}
-	defer func() {
-		if err := cursor.Close(context.Background()); err != nil {
-			log.Printf("Error closing cursor for table %s: %v", tableName, err)
-		}
-	}()
-
-	if cursor.RemainingBatchLength() == 0 {
-		log.Printf("No results found for table %s with filter: %s", tableName, queryJSON)
+func (r *MongoDBRepository) GetByID(ctx context.Context, collectionName string, id string, result interface{}) error {
+	err := r.FindOneWhere(ctx, collectionName, bson.M{"id": id}, result)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("no document found with ID: %s", id)
 	}
+	return err
+}
 
-	err = cursor.All(context.Background(), obj)
-	if err != nil {
-		log.Printf("Error decoding results for table %s: %v", tableName, err)
-		return err
+func (r *MongoDBRepository) GetByEmail(ctx context.Context, collectionName string, email string, result interface{}) error {
+	err := r.FindOneWhere(ctx, collectionName, bson.M{"email": email}, result)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("no document found with email: %s", email)
 	}
-
-	resultJSON, _ := json.Marshal(obj)
-	log.Printf("Query result for table %s: %s", tableName, resultJSON)
-
-	return nil
+	return err
 }
 
-func (r *MongoDBRepository) GetByID(collectionName string, id string, result interface{}) error {
-	collection := database.DB.Collection(collectionName)
-
-	filter := bson.M{"id": id}
-	err := collection.FindOne(context.Background(), filter).Decode(result)
+func (r *MongoDBRepository) RemoveByFilter(ctx context.Context, collectionName string, filter bson.M) error {
+	ctx, cancel := withTimeout(ctx, WriteTimeout)
+	defer cancel()
+	defer observeOp("delete", collectionName, time.Now())
 
+	res, err := database.DB.Collection(collectionName).DeleteOne(ctx, filter)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return fmt.Errorf("no document found with ID: %s", id)
-		}
-		log.Printf("Error finding document in %s: %v", collectionName, err)
 		return err
 	}
-
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("no matching record")
+	}
 	return nil
 }
 
-func (r *MongoDBRepository) GetByEmail(collectionName string, email string, result interface{}) error {
-	collection := database.DB.Collection(collectionName)
-
-	filter := bson.M{"email": email} // filter by email field
-	err := collection.FindOne(context.Background(), filter).Decode(result)
-
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return fmt.Errorf("no document found with email: %s", email)
-		}
-		log.Printf("Error finding document in %s: %v", collectionName, err)
-		return err
-	}
+func (r *MongoDBRepository) Count(ctx context.Context, collectionName string, filter bson.M) (int64, error) {
+	ctx, cancel := withTimeout(ctx, CountTimeout)
+	defer cancel()
+	defer observeOp("count", collectionName, time.Now())
 
-	return nil
+	return database.DB.Collection(collectionName).CountDocuments(ctx, filter)
 }
 
-func (r *MongoDBRepository) RemoveByFilter(collectionName string, filter bson.M) error {
-	collection := database.DB.Collection(collectionName)
+func (r *MongoDBRepository) UpdateOne(ctx context.Context, collectionName string, filter, update bson.M, upsert bool) error {
+	ctx, cancel := withTimeout(ctx, WriteTimeout)
+	defer cancel()
+	defer observeOp("updateOne", collectionName, time.Now())
 
-	queryJSON, _ := json.Marshal(filter)
-	log.Printf("Executing MongoDB deletion query: %s\n", queryJSON)
+	opts := options.Update().SetUpsert(upsert)
+	_, err := database.DB.Collection(collectionName).UpdateOne(ctx, filter, update, opts)
+	return err
+}
 
-	count, err := collection.CountDocuments(context.Background(), filter)
-	if err != nil {
-		return err
-	}
-	if count == 0 {
-		return fmt.Errorf("no matching record")
-	}
+func (r *MongoDBRepository) UpdateOneMatched(ctx context.Context, collectionName string, filter, update bson.M) (bool, error) {
+	ctx, cancel := withTimeout(ctx, WriteTimeout)
+	defer cancel()
+	defer observeOp("updateOne", collectionName, time.Now())
 
-	_, err = collection.DeleteOne(context.Background(), filter)
+	res, err := database.DB.Collection(collectionName).UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	return nil
+	return res.MatchedCount > 0, nil
 }
 
-func (r *MongoDBRepository) Count(collectionName string, filter bson.M) (int64, error) {
-	collection := database.DB.Collection(collectionName)
-	count, err := collection.CountDocuments(context.Background(), filter)
-	if err != nil {
-		log.Printf("[Count] Error counting documents in %s: %v", collectionName, err)
-		return 0, err
-	}
-	log.Printf("[Count] Successfully counted documents in %s: %d", collectionName, count)
-	return count, nil
-}
+func (r *MongoDBRepository) FindOneAndUpdate(ctx context.Context, collectionName string, filter, update bson.M, upsert bool, result interface{}) error {
+	ctx, cancel := withTimeout(ctx, WriteTimeout)
+	defer cancel()
+	defer observeOp("findOneAndUpdate", collectionName, time.Now())
 
-func (r *MongoDBRepository) UpdateOne(collectionName string, filter, update bson.M, upsert bool) error {
-	collection := database.DB.Collection(collectionName)
-	opts := options.Update().SetUpsert(upsert)
+	opts := options.FindOneAndUpdate().SetUpsert(upsert).SetReturnDocument(options.After)
+	return database.DB.Collection(collectionName).FindOneAndUpdate(ctx, filter, update, opts).Decode(result)
+}
 
-	_, err := collection.UpdateOne(context.Background(), filter, update, opts)
-	if err != nil {
-		log.Printf("[UpdateOne] Error updating document in %s: %v", collectionName, err)
-		return err
-	}
+func (r *MongoDBRepository) UpdateMany(ctx context.Context, collectionName string, filter, update bson.M) error {
+	ctx, cancel := withTimeout(ctx, WriteTimeout)
+	defer cancel()
+	defer observeOp("updateMany", collectionName, time.Now())
 
-	log.Printf("[UpdateOne] Successfully updated document in %s", collectionName)
-	return nil
+	_, err := database.DB.Collection(collectionName).UpdateMany(ctx, filter, update)
+	return err
 }
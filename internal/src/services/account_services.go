@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+type AccountBalance struct {
+	Asset  string `json:"asset"`
+	Free   string `json:"free"`
+	Locked string `json:"locked"`
+}
+
+// GetAccountBalances returns the non-zero balances of the exchange account.
+func GetAccountBalances(ctx context.Context) ([]AccountBalance, error) {
+	body, err := makeRequest(ctx, "GET", "/api/v3/account", "omitZeroBalances=true")
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Balances []AccountBalance `json:"balances"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return res.Balances, nil
+}
+
+// GetFreeBalance returns the free amount of a single asset.
+func GetFreeBalance(ctx context.Context, asset string) (float64, error) {
+	balances, err := GetAccountBalances(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, b := range balances {
+		if b.Asset == asset {
+			return strconv.ParseFloat(b.Free, 64)
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/rbac"
+)
+
+type AdminServices struct{}
+
+func (a AdminServices) ListUsers(ctx context.Context, search string, page, limit int) ([]models.User, int64, error) {
+	query := bson.M{}
+	if search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(search), Options: "i"}
+		query["$or"] = []bson.M{{"email": pattern}, {"full_name": pattern}, {"account_number": pattern}}
+	}
+	filter := models.Filter{
+		Sort:      "created_at",
+		SortOrder: -1,
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	}
+
+	users, err := repository.FindPage[models.User](ctx, "users", query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := repository.IRepo.Count(ctx, "users", query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (a AdminServices) GetUser(ctx context.Context, userID string) (models.User, error) {
+	var user models.User
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return user, errors.New("invalid user id")
+	}
+	if err := repository.IRepo.FindOneWhere(ctx, "users", bson.M{"_id": oid}, &user); err != nil {
+		return user, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// SetRole changes a user's role and ends their sessions, so the next token
+// they get carries the new role.
+func (a AdminServices) SetRole(ctx context.Context, userID, role string) (models.User, error) {
+	if !rbac.IsRole(role) {
+		return models.User{}, fmt.Errorf("unknown role %q, expected one of %s", role, strings.Join(rbac.Roles, ", "))
+	}
+	user, err := a.GetUser(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+	oid, _ := primitive.ObjectIDFromHex(userID)
+	update := bson.M{"$set": bson.M{"role": role, "role_override": true, "updated_at": time.Now()}}
+	if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, update, false); err != nil {
+		return user, err
+	}
+	if err := (TokenServices{}).RevokeUserSessions(ctx, userID); err != nil {
+		return user, err
+	}
+	user.Role = role
+	user.RoleOverride = true
+	return user, nil
+}
+
+// CreateAdmin makes the user with the given email an admin. When no such
+// user exists and exdexUserID is set, the user is created, so the first
+// admin can be set up before they have ever logged in. The role is marked as
+// overridden so logins do not reset it to the upstream role.
+func (a AdminServices) CreateAdmin(ctx context.Context, email string, exdexUserID uint) (models.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return models.User{}, errors.New("email is required")
+	}
+
+	user, err := repository.FindOne[models.User](ctx, "users", bson.M{"email": email})
+	if err == nil {
+		return a.SetRole(ctx, user.ID, rbac.RoleAdmin)
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return models.User{}, err
+	}
+	if exdexUserID == 0 {
+		return models.User{}, fmt.Errorf("no user with email %s; an EXDEX user id is needed to create one", email)
+	}
+
+	user = models.User{
+		ExdexUserID:  exdexUserID,
+		Email:        email,
+		Role:         rbac.RoleAdmin,
+		RoleOverride: true,
+		Status:       true,
+	}
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	if err := repository.IRepo.Insert(ctx, "users", user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.User{}, errors.New("another account already uses this EXDEX user id")
+		}
+		return models.User{}, err
+	}
+	return repository.FindOne[models.User](ctx, "users", bson.M{"email": email})
+}
+
+// SetSuspended suspends or reinstates a user. Suspending also ends their
+// sessions; refresh is refused while suspended.
+func (a AdminServices) SetSuspended(ctx context.Context, userID string, suspended bool) (models.User, error) {
+	user, err := a.GetUser(ctx, userID)
+	if err != nil {
+		return user, err
+	}
+	oid, _ := primitive.ObjectIDFromHex(userID)
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"updated_at": now}}
+	if suspended {
+		update["$set"].(bson.M)["suspended_at"] = now
+	} else {
+		update["$unset"] = bson.M{"suspended_at": ""}
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, update, false); err != nil {
+		return user, err
+	}
+	if suspended {
+		if err := (TokenServices{}).RevokeUserSessions(ctx, userID); err != nil {
+			return user, err
+		}
+		user.SuspendedAt = &now
+	} else {
+		user.SuspendedAt = nil
+	}
+	return user, nil
+}
+
+// ListOrders returns orders across all users, optionally narrowed to one
+// user or symbol.
+func (a AdminServices) ListOrders(ctx context.Context, userID, symbol string, page, limit int) ([]models.OrderRecord, int64, error) {
+	query := bson.M{}
+	if userID != "" {
+		query["user_id"] = userID
+	}
+	if symbol != "" {
+		query["symbol"] = strings.ToUpper(symbol)
+	}
+	filter := models.Filter{
+		Sort:      "created_at",
+		SortOrder: -1,
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	}
+
+	orders, err := repository.FindPage[models.OrderRecord](ctx, "orders", query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := repository.IRepo.Count(ctx, "orders", query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/validator"
+)
+
+const (
+	algoMaxDuration      = 24 * time.Hour
+	algoMinSliceInterval = 5 * time.Second
+	algoMaxSlices        = 1000
+	algoDefaultMaxSlices = 500
+)
+
+type AlgoServices struct{}
+
+type algoRunner struct {
+	cancel context.CancelFunc
+}
+
+// algoRunners holds the goroutine working each running parent order.
+var algoRunners sync.Map
+
+func (a AlgoServices) Create(ctx context.Context, userID string, req models.AlgoOrderRequest) (models.AlgoOrder, error) {
+	if err := validator.Validate(&req); err != nil {
+		return models.AlgoOrder{}, err
+	}
+
+	symbol := strings.ToUpper(req.Symbol)
+	side := strings.ToUpper(req.Side)
+	algo := strings.ToLower(req.Algo)
+	if side != "BUY" && side != "SELL" {
+		return models.AlgoOrder{}, fmt.Errorf("side must be BUY or SELL, got %q", req.Side)
+	}
+	if algo != models.AlgoTWAP && algo != models.AlgoVWAP {
+		return models.AlgoOrder{}, fmt.Errorf("algo must be twap or vwap, got %q", req.Algo)
+	}
+	qty, err := parsePositive("quantity", req.Quantity)
+	if err != nil {
+		return models.AlgoOrder{}, err
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 || duration > algoMaxDuration {
+		return models.AlgoOrder{}, fmt.Errorf("duration must be a positive Go duration up to %s", algoMaxDuration)
+	}
+	var limitPrice float64
+	if req.LimitPrice != "" {
+		if limitPrice, err = parsePositive("limit_price", req.LimitPrice); err != nil {
+			return models.AlgoOrder{}, err
+		}
+	}
+	if req.ParticipationRate < 0 || req.ParticipationRate > 1 {
+		return models.AlgoOrder{}, errors.New("participation_rate must be between 0 and 1")
+	}
+
+	slices := req.Slices
+	if slices <= 0 {
+		slices = int(math.Max(1, math.Min(duration.Minutes(), algoDefaultMaxSlices)))
+	}
+	if slices > algoMaxSlices || duration/time.Duration(slices) < algoMinSliceInterval {
+		return models.AlgoOrder{}, fmt.Errorf("too many slices: at most %d and no closer than %s apart", algoMaxSlices, algoMinSliceInterval)
+	}
+	interval := duration / time.Duration(slices)
+
+	if _, err := GetSymbolInfo(symbol); err != nil {
+		return models.AlgoOrder{}, err
+	}
+
+	now := time.Now()
+	weights := twapWeights(slices)
+	if algo == models.AlgoVWAP {
+		weights = vwapWeights(symbol, now, duration, slices)
+	}
+
+	order := models.AlgoOrder{
+		ID:                primitive.NewObjectID(),
+		UserID:            userID,
+		Symbol:            symbol,
+		Side:              side,
+		Algo:              algo,
+		Quantity:          qty,
+		LimitPrice:        limitPrice,
+		ParticipationRate: req.ParticipationRate,
+		DurationSec:       int64(duration / time.Second),
+		SliceIntervalSec:  int64(interval / time.Second),
+		Weights:           weights,
+		NextSliceAt:       now,
+		Status:            models.AlgoStatusRunning,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if order.SliceIntervalSec == 0 {
+		order.SliceIntervalSec = 1
+	}
+	if err := repository.IRepo.Insert(ctx, "algo_orders", &order); err != nil {
+		return models.AlgoOrder{}, err
+	}
+
+	startAlgoRunner(order.ID)
+	return order, nil
+}
+
+func twapWeights(slices int) []float64 {
+	w := make([]float64, slices)
+	for i := range w {
+		w[i] = 1 / float64(slices)
+	}
+	return w
+}
+
+// vwapWeights distributes the order like the traded volume in the same window
+// one day earlier. It falls back to TWAP when there is no history.
+func vwapWeights(symbol string, start time.Time, duration time.Duration, slices int) []float64 {
+	interval := "1m"
+	if duration > 1000*time.Minute {
+		interval = "5m"
+	}
+	if duration > 5000*time.Minute {
+		interval = "1h"
+	}
+
+	histStart := start.Add(-24 * time.Hour)
+	klines, err := GetKlines(context.Background(), symbol, interval, histStart, histStart.Add(duration), 1000)
+	if err != nil {
+		log.Printf("⚠️  VWAP profile for %s unavailable, using TWAP: %v", symbol, err)
+		return twapWeights(slices)
+	}
+
+	weights := make([]float64, slices)
+	sliceLen := duration / time.Duration(slices)
+	total := 0.0
+	for _, k := range klines {
+		idx := int(k.OpenTime.Sub(histStart) / sliceLen)
+		if idx < 0 || idx >= slices {
+			continue
+		}
+		weights[idx] += k.Volume
+		total += k.Volume
+	}
+	if total == 0 {
+		return twapWeights(slices)
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
+
+func (a AlgoServices) List(ctx context.Context, userID string) ([]models.AlgoOrder, error) {
+	orders, err := repository.Find[models.AlgoOrder](ctx, "algo_orders", bson.M{"user_id": userID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Progress = algoProgress(orders[i])
+	}
+	return orders, nil
+}
+
+func (a AlgoServices) Get(ctx context.Context, userID, id string) (models.AlgoOrder, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.AlgoOrder{}, errors.New("invalid algo order id")
+	}
+	order, err := repository.FindOne[models.AlgoOrder](ctx, "algo_orders", bson.M{"_id": oid, "user_id": userID})
+	if err != nil {
+		return order, err
+	}
+	order.Progress = algoProgress(order)
+	return order, nil
+}
+
+// Children returns the child orders placed for a parent order.
+func (a AlgoServices) Children(ctx context.Context, parent models.AlgoOrder) ([]models.OrderRecord, error) {
+	children, err := repository.Find[models.OrderRecord](ctx, "orders", bson.M{"parent_id": parent.ID.Hex()}, nil)
+	return children, err
+}
+
+func (a AlgoServices) Pause(ctx context.Context, userID, id string) (models.AlgoOrder, error) {
+	order, err := a.Get(ctx, userID, id)
+	if err != nil {
+		return order, err
+	}
+	if order.Status != models.AlgoStatusRunning {
+		return order, errors.New("algo order is not running")
+	}
+
+	stopAlgoRunner(order.ID)
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": models.AlgoStatusPaused, "paused_at": now, "updated_at": now}}
+	if err := repository.IRepo.UpdateOne(ctx, "algo_orders", bson.M{"_id": order.ID, "status": models.AlgoStatusRunning}, update, false); err != nil {
+		return order, err
+	}
+	return a.Get(ctx, userID, id)
+}
+
+// Resume continues a paused order. Remaining slices keep their spacing and
+// start from now, so the order finishes later by the time spent paused.
+func (a AlgoServices) Resume(ctx context.Context, userID, id string) (models.AlgoOrder, error) {
+	order, err := a.Get(ctx, userID, id)
+	if err != nil {
+		return order, err
+	}
+	if order.Status != models.AlgoStatusPaused {
+		return order, errors.New("algo order is not paused")
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set":   bson.M{"status": models.AlgoStatusRunning, "next_slice_at": now, "updated_at": now},
+		"$unset": bson.M{"paused_at": ""},
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "algo_orders", bson.M{"_id": order.ID, "status": models.AlgoStatusPaused}, update, false); err != nil {
+		return order, err
+	}
+	startAlgoRunner(order.ID)
+	return a.Get(ctx, userID, id)
+}
+
+func (a AlgoServices) Cancel(ctx context.Context, userID, id string) (models.AlgoOrder, error) {
+	order, err := a.Get(ctx, userID, id)
+	if err != nil {
+		return order, err
+	}
+	if order.Status != models.AlgoStatusRunning && order.Status != models.AlgoStatusPaused {
+		return order, errors.New("algo order has already finished")
+	}
+
+	stopAlgoRunner(order.ID)
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": models.AlgoStatusCanceled, "finished_at": now, "updated_at": now}}
+	filter := bson.M{"_id": order.ID, "status": bson.M{"$in": []string{models.AlgoStatusRunning, models.AlgoStatusPaused}}}
+	if err := repository.IRepo.UpdateOne(ctx, "algo_orders", filter, update, false); err != nil {
+		return order, err
+	}
+	return a.Get(ctx, userID, id)
+}
+
+func algoProgress(order models.AlgoOrder) float64 {
+	if order.Quantity == 0 {
+		return 0
+	}
+	return math.Min(1, order.ExecutedQty/order.Quantity)
+}
+
+// ResumeAlgoOrders restarts the workers of running parent orders after a restart.
+func ResumeAlgoOrders() {
+	ctx := context.Background()
+	orders, err := repository.Find[models.AlgoOrder](ctx, "algo_orders", bson.M{"status": models.AlgoStatusRunning}, nil)
+	if err != nil {
+		log.Printf("❌ Failed to load running algo orders: %v", err)
+		return
+	}
+	for _, o := range orders {
+		startAlgoRunner(o.ID)
+	}
+	if len(orders) > 0 {
+		log.Printf("▶️  Resumed %d algo orders", len(orders))
+	}
+}
+
+func startAlgoRunner(id primitive.ObjectID) {
+	ctx, cancel := context.WithCancel(workerCtx)
+	runner := &algoRunner{cancel: cancel}
+	if _, loaded := algoRunners.LoadOrStore(id, runner); loaded {
+		cancel()
+		return
+	}
+	goWorker(func(context.Context) {
+		defer algoRunners.CompareAndDelete(id, runner)
+		runAlgo(ctx, id)
+	})
+}
+
+func stopAlgoRunner(id primitive.ObjectID) {
+	if v, ok := algoRunners.LoadAndDelete(id); ok {
+		v.(*algoRunner).cancel()
+	}
+}
+
+func runAlgo(ctx context.Context, id primitive.ObjectID) {
+	for {
+		order, err := repository.FindOne[models.AlgoOrder](ctx, "algo_orders", bson.M{"_id": id})
+		if err != nil {
+			log.Printf("❌ Algo %s: failed to load: %v", id.Hex(), err)
+			return
+		}
+		if order.Status != models.AlgoStatusRunning {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(order.NextSliceAt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if done := executeAlgoSlice(ctx, &order); done {
+			return
+		}
+	}
+}
+
+// executeAlgoSlice places the child order for the next slice and saves the
+// parent's progress. It reports whether the parent has finished.
+func executeAlgoSlice(ctx context.Context, order *models.AlgoOrder) bool {
+	info, err := GetSymbolInfo(order.Symbol)
+	if err != nil {
+		order.LastError = err.Error()
+	} else {
+		target := 0.0
+		for _, w := range order.Weights[:order.NextSlice+1] {
+			target += w
+		}
+		if order.NextSlice == len(order.Weights)-1 {
+			target = 1
+		}
+		want := target*order.Quantity - order.ExecutedQty
+		want = capAlgoSlice(ctx, order, want)
+
+		qtyStr := info.FormatQuantity(want)
+		qty, _ := strconv.ParseFloat(qtyStr, 64)
+		if qty > 0 && qty >= info.MinQty && ctx.Err() == nil {
+			if err := placeAlgoChild(ctx, order, qtyStr); err != nil {
+				order.LastError = err.Error()
+				log.Printf("❌ Algo %s: child order failed: %v", order.ID.Hex(), err)
+			} else {
+				order.LastError = ""
+			}
+		}
+	}
+
+	order.NextSlice++
+	order.NextSliceAt = time.Now().Add(time.Duration(order.SliceIntervalSec) * time.Second)
+	if order.ExecutedQty > 0 {
+		order.AvgPrice = order.QuoteQty / order.ExecutedQty
+	}
+
+	set := bson.M{
+		"next_slice":    order.NextSlice,
+		"next_slice_at": order.NextSliceAt,
+		"executed_qty":  order.ExecutedQty,
+		"quote_qty":     order.QuoteQty,
+		"avg_price":     order.AvgPrice,
+		"child_count":   order.ChildCount,
+		"last_error":    order.LastError,
+		"updated_at":    time.Now(),
+	}
+
+	// Anything below one lot can't be traded, so it counts as done.
+	remaining := order.Quantity - order.ExecutedQty
+	minLot := math.Max(info.StepSize, info.MinQty)
+	finished := ""
+	if remaining <= 0 || remaining < minLot {
+		finished = models.AlgoStatusCompleted
+	} else if order.NextSlice >= len(order.Weights) {
+		finished = models.AlgoStatusExpired
+	}
+	if finished != "" {
+		set["status"] = finished
+		set["finished_at"] = time.Now()
+	}
+
+	// Only a still-running order is updated, so a concurrent pause or cancel wins.
+	err = repository.IRepo.UpdateOne(ctx, "algo_orders", bson.M{"_id": order.ID, "status": models.AlgoStatusRunning}, bson.M{"$set": set}, false)
+	if err != nil {
+		log.Printf("❌ Algo %s: failed to save progress: %v", order.ID.Hex(), err)
+	}
+	if finished != "" {
+		log.Printf("🏁 Algo %s %s: %.8f/%.8f %s at avg %.8f", order.ID.Hex(), finished, order.ExecutedQty, order.Quantity, order.Symbol, order.AvgPrice)
+		return true
+	}
+	return false
+}
+
+// capAlgoSlice applies the limit price and participation rate to a slice.
+func capAlgoSlice(ctx context.Context, order *models.AlgoOrder, want float64) float64 {
+	if want <= 0 {
+		return 0
+	}
+
+	if order.LimitPrice > 0 {
+		price := getPriceFloat(ctx, order.Symbol)
+		if price <= 0 ||
+			(order.Side == "BUY" && price > order.LimitPrice) ||
+			(order.Side == "SELL" && price < order.LimitPrice) {
+			return 0
+		}
+	}
+
+	if order.ParticipationRate > 0 {
+		klines, err := GetKlines(ctx, order.Symbol, "1m", time.Time{}, time.Time{}, 2)
+		if err == nil && len(klines) > 0 {
+			// The last bar is still forming; use the previous complete one.
+			perMinute := klines[0].Volume
+			expected := perMinute * float64(order.SliceIntervalSec) / 60
+			want = math.Min(want, expected*order.ParticipationRate)
+		}
+	}
+	return want
+}
+
+// placeAlgoChild places one slice like any other order, paper or live,
+// under a client ID derived from the parent and the slice number.
+func placeAlgoChild(ctx context.Context, order *models.AlgoOrder, qty string) error {
+	if err := authorizeOwnerOrder(ctx, order.UserID); err != nil {
+		return err
+	}
+	clientID := fmt.Sprintf("algo_%s_%d", order.ID.Hex(), order.ChildCount+1)
+
+	var res *models.OrderResponse
+	var err error
+	if order.LimitPrice > 0 {
+		info, _ := GetSymbolInfo(order.Symbol)
+		res, err = placeLimitOrder(ctx, order.Symbol, order.Side, qty, info.FormatPrice(order.LimitPrice), "IOC", clientID, order.UserID)
+	} else {
+		res, err = placeMarketOrder(ctx, order.Symbol, order.Side, qty, clientID, order.UserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	order.ChildCount++
+	executed, _ := strconv.ParseFloat(res.ExecutedQty, 64)
+	quote, _ := strconv.ParseFloat(res.CummulativeQuoteQty, 64)
+	order.ExecutedQty += executed
+	order.QuoteQty += quote
+
+	return repository.IRepo.UpdateOne(ctx, "orders", bson.M{"order_id": res.OrderID}, bson.M{"$set": bson.M{"parent_id": order.ID.Hex()}}, false)
+}
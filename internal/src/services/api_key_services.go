@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"exdex/config"
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/rbac"
+	"exdex/server/security"
+	"exdex/server/validator"
+)
+
+const (
+	maxAPIKeysPerUser     = 20
+	defaultAPIRecvWindow  = 5000 * time.Millisecond
+	maxAPIRecvWindow      = 60000 * time.Millisecond
+	apiKeyFutureTolerance = time.Second
+	apiKeyLastUsedEvery   = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid API key")
+	ErrAPIKeyExpired       = errors.New("API key has expired")
+	ErrInvalidSignature    = errors.New("invalid signature")
+	ErrTimestampOutOfRange = errors.New("timestamp is outside of the recvWindow")
+	ErrRequestReplayed     = errors.New("request was already processed")
+	ErrIPNotAllowed        = errors.New("IP address is not allowed for this API key")
+	ErrAPIAuthUnavailable  = errors.New("API key authentication is temporarily unavailable")
+)
+
+type APIKeyServices struct{}
+
+// SignedRequest is what the API key middleware extracts from a request.
+// Payload is the exact string the client signed.
+type SignedRequest struct {
+	Key        string
+	Timestamp  string
+	RecvWindow string
+	Signature  string
+	Payload    string
+	IP         string
+}
+
+func apiKeyEncryptionKey() string {
+	return config.C.APIKeys.EncryptionKey
+}
+
+func (a APIKeyServices) Create(ctx context.Context, userID string, req models.APIKeyRequest) (models.APIKeyCreated, error) {
+	if err := validator.Validate(&req); err != nil {
+		return models.APIKeyCreated{}, err
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return models.APIKeyCreated{}, err
+	}
+	for _, entry := range req.AllowedIPs {
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return models.APIKeyCreated{}, fmt.Errorf("invalid IP or CIDR %q", entry)
+			}
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return models.APIKeyCreated{}, errors.New("expires_at must be in the future")
+	}
+
+	active, err := repository.IRepo.Count(ctx, "api_keys", bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}})
+	if err != nil {
+		return models.APIKeyCreated{}, err
+	}
+	if active >= maxAPIKeysPerUser {
+		return models.APIKeyCreated{}, fmt.Errorf("at most %d active API keys are allowed", maxAPIKeysPerUser)
+	}
+
+	key, err := security.GenerateAPIKey()
+	if err != nil {
+		return models.APIKeyCreated{}, err
+	}
+	secret, err := security.GenerateAPIKey()
+	if err != nil {
+		return models.APIKeyCreated{}, err
+	}
+	encrypted, err := security.Encrypt(apiKeyEncryptionKey(), secret)
+	if err != nil {
+		return models.APIKeyCreated{}, err
+	}
+
+	apiKey := models.APIKey{
+		ID:              primitive.NewObjectID(),
+		UserID:          userID,
+		Name:            req.Name,
+		KeyPrefix:       key[:8],
+		KeyHash:         security.HashToken(key),
+		SecretEncrypted: encrypted,
+		Scopes:          scopes,
+		AllowedIPs:      req.AllowedIPs,
+		ExpiresAt:       req.ExpiresAt,
+		CreatedAt:       time.Now(),
+	}
+	if err := repository.IRepo.Insert(ctx, "api_keys", &apiKey); err != nil {
+		return models.APIKeyCreated{}, err
+	}
+	return models.APIKeyCreated{APIKey: apiKey, Key: key, Secret: secret}, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !rbac.IsScope(s) {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return out, nil
+}
+
+func (a APIKeyServices) List(ctx context.Context, userID string) ([]models.APIKey, error) {
+	keys, err := repository.Find[models.APIKey](ctx, "api_keys", bson.M{"user_id": userID}, nil)
+	return keys, err
+}
+
+func (a APIKeyServices) Revoke(ctx context.Context, userID, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid API key id")
+	}
+	key, err := repository.FindOne[models.APIKey](ctx, "api_keys", bson.M{"_id": oid, "user_id": userID})
+	if err != nil {
+		return errors.New("API key not found")
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return repository.IRepo.UpdateOne(ctx, "api_keys", bson.M{"_id": oid}, bson.M{"$set": bson.M{"revoked_at": time.Now()}}, false)
+}
+
+// Authenticate checks a signed request and returns the key and its owner.
+func (a APIKeyServices) Authenticate(ctx context.Context, req SignedRequest) (models.APIKey, models.User, error) {
+	var key models.APIKey
+	var user models.User
+
+	ts, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return key, user, ErrTimestampOutOfRange
+	}
+	window := defaultAPIRecvWindow
+	if req.RecvWindow != "" {
+		ms, err := strconv.ParseInt(req.RecvWindow, 10, 64)
+		if err != nil || ms <= 0 || time.Duration(ms)*time.Millisecond > maxAPIRecvWindow {
+			return key, user, fmt.Errorf("recvWindow must be between 1 and %d", maxAPIRecvWindow.Milliseconds())
+		}
+		window = time.Duration(ms) * time.Millisecond
+	}
+	sent := time.UnixMilli(ts)
+	now := time.Now()
+	if sent.After(now.Add(apiKeyFutureTolerance)) || now.Sub(sent) > window {
+		return key, user, ErrTimestampOutOfRange
+	}
+
+	if err := repository.IRepo.FindOneWhere(ctx, "api_keys", bson.M{"key_hash": security.HashToken(req.Key)}, &key); err != nil {
+		return key, user, ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil {
+		return key, user, ErrInvalidAPIKey
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return key, user, ErrAPIKeyExpired
+	}
+	if !ipAllowed(key.AllowedIPs, req.IP) {
+		return key, user, ErrIPNotAllowed
+	}
+
+	secret, err := security.Decrypt(apiKeyEncryptionKey(), key.SecretEncrypted)
+	if err != nil {
+		log.Printf("❌ Failed to decrypt secret of API key %s: %v", key.ID.Hex(), err)
+		return key, user, ErrInvalidAPIKey
+	}
+	// Hex is case-insensitive; one spelling per signature keeps a request
+	// from being replayed in a different case.
+	signature := strings.ToLower(req.Signature)
+	if !security.VerifySignature(secret, req.Payload, signature) {
+		return key, user, ErrInvalidSignature
+	}
+	// Only a correctly signed request may record its signature.
+	if err := rememberSignature(ctx, key.ID.Hex()+":"+signature, sent.Add(window+apiKeyFutureTolerance)); err != nil {
+		return key, user, err
+	}
+
+	oid, err := primitive.ObjectIDFromHex(key.UserID)
+	if err != nil {
+		return key, user, ErrInvalidAPIKey
+	}
+	if err := repository.IRepo.FindOneWhere(ctx, "users", bson.M{"_id": oid}, &user); err != nil {
+		return key, user, ErrInvalidAPIKey
+	}
+	if !user.Status || user.SuspendedAt != nil || user.DeletedAt != nil {
+		return key, user, ErrSessionUnavailable
+	}
+	user.Role = rbac.NormalizeRole(user.Role)
+
+	touchAPIKey(key, req.IP)
+	return key, user, nil
+}
+
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchAPIKey records usage at most once a minute per key.
+func touchAPIKey(key models.APIKey, ip string) {
+	if key.LastUsedAt != nil && time.Since(*key.LastUsedAt) < apiKeyLastUsedEvery && key.LastUsedIP == ip {
+		return
+	}
+	go func() {
+		update := bson.M{"$set": bson.M{"last_used_at": time.Now(), "last_used_ip": ip}}
+		if err := repository.IRepo.UpdateOne(context.Background(), "api_keys", bson.M{"_id": key.ID}, update, false); err != nil {
+			log.Printf("⚠️  Failed to record use of API key %s: %v", key.ID.Hex(), err)
+		}
+	}()
+}
+
+// apiNonce is a signature already accepted, kept until its timestamp
+// leaves the window, after which the timestamp check rejects it anyway.
+type apiNonce struct {
+	Nonce     string    `bson:"nonce"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// rememberSignature records a signature, or returns ErrRequestReplayed if it
+// was already recorded. The unique index on api_nonces.nonce catches replays
+// across instances; a TTL index removes expired entries.
+func rememberSignature(ctx context.Context, nonce string, until time.Time) error {
+	err := repository.IRepo.Insert(ctx, "api_nonces", &apiNonce{Nonce: nonce, ExpiresAt: until})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrRequestReplayed
+	}
+	if err != nil {
+		log.Printf("❌ Failed to record API request signature: %v", err)
+		return ErrAPIAuthUnavailable
+	}
+	return nil
+}
@@ -1,16 +1,19 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
 	models "exdex/internal/src/model"
 	"exdex/internal/src/repository"
-	"exdex/server/jwt"
+	"exdex/server/rbac"
 )
 
 type AuthServices struct{}
@@ -19,84 +22,90 @@ type ExdexTokenRequest struct {
 	Token string `json:"token"`
 }
 
-type ExdexUserResponse struct {
-	ID            int    `json:"id"`
-	Email         string `json:"email"`
-	AccountNumber string `json:"accountNumber"`
-	FullName      string `json:"fullName"`
-	Role          string `json:"role"`
-}
-
-type ExdexResponse struct {
-	Code   int               `json:"code"`
-	Status string            `json:"status"`
-	Data   ExdexUserResponse `json:"data"`
-	Error  string            `json:"error,omitempty"`
-}
-
-func (a AuthServices) ExdexAuth(exdexToken string) (string, error) {
-	url := fmt.Sprintf("https://api.exdex.com/api/exdex/check?token=%s", exdexToken)
-
-	req, err := http.NewRequest("POST", url, nil)
+// ExdexAuth verifies a login token with the identity provider, syncs the
+// user's profile into our users collection and starts a session for them.
+func (a AuthServices) ExdexAuth(ctx context.Context, exdexToken, userAgent, ip string) (models.TokenPair, error) {
+	id, err := Identities().Verify(ctx, exdexToken)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GET request: %v", err)
+		return models.TokenPair{}, err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	user, err := syncUser(ctx, id)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %v", err)
+		return models.TokenPair{}, err
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
+	if !user.Status || user.SuspendedAt != nil || user.DeletedAt != nil {
+		return models.TokenPair{}, ErrSessionUnavailable
 	}
 
-	// fmt.Println("🔍 Raw Response Body:", string(body))
+	tokenServices := TokenServices{}
+	return tokenServices.IssueSession(ctx, user, userAgent, ip)
+}
 
-	var apiResp ExdexResponse
-	err = json.Unmarshal(body, &apiResp)
-	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %v", err)
+// syncUser upserts the user by EXDEX id so profile changes upstream reach
+// us on the next login. The upstream role is applied unless an admin has
+// set the role here.
+func syncUser(ctx context.Context, id Identity) (models.User, error) {
+	filter := bson.M{"exdex_user_id": id.ExdexUserID}
+
+	var existing models.User
+	found := repository.IRepo.FindOneWhere(ctx, "users", filter, &existing) == nil
+
+	now := time.Now()
+	set := bson.M{
+		"email":          id.Email,
+		"full_name":      id.FullName,
+		"account_number": id.AccountNumber,
+		"upstream_role":  id.Role,
+		"updated_at":     now,
+		"last_login_at":  now,
 	}
-
-	if apiResp.Code != 200 {
-		return "", fmt.Errorf("unexpected status code: %d, error: %s", apiResp.Code, apiResp.Error)
+	if !found || !existing.RoleOverride {
+		set["role"] = rbac.NormalizeRole(id.Role)
 	}
-
-	var user models.User
-	user.ExdexUserID = uint(apiResp.Data.ID)
-	user.Email = apiResp.Data.Email
-	user.AccountNumber = apiResp.Data.AccountNumber
-	user.FullName = apiResp.Data.FullName
-	user.CreatedAt = time.Now()
-	user.Status = true
-	user.Role = apiResp.Data.Role
-
-	err = repository.IRepo.Create("users", &user)
-	if err != nil {
-		if strings.Contains(err.Error(), "E11000 duplicate key error") {
-			fmt.Errorf("duplicate: user with this email already exists")
-		} else {
-			return "", err
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"created_at": now, "status": true},
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "users", filter, update, true); err != nil {
+		if strings.Contains(err.Error(), "E11000") {
+			return models.User{}, errors.New("another account already uses this email")
 		}
+		return models.User{}, fmt.Errorf("failed to sync user: %v", err)
 	}
 
-	// filter := bson.M{"email": user.Email}
-
-	var u []models.User
-	err = repository.IRepo.FindByKeyValue("users", &u, "email", user.Email)
+	user, err := repository.FindOne[models.User](ctx, "users", filter)
 	if err != nil {
-		return "", err
+		return models.User{}, err
 	}
-	// fmt.Println(user.ID, user.Role)
+	user.Role = rbac.NormalizeRole(user.Role)
+	return user, nil
+}
+
+// ErrOwnerCannotTrade refuses an order placed on a user's behalf by a
+// signal, bot or copy subscription once that user may no longer trade.
+var ErrOwnerCannotTrade = errors.New("owner may not place orders")
 
-	token, err := jwt.GenerateJWT(u[0].ID, user.Email, user.Role)
+// authorizeOwnerOrder re-checks, before each order placed in the background,
+// that its owner is still active and still holds orders:write. The order is
+// refused when the user cannot be loaded.
+func authorizeOwnerOrder(ctx context.Context, userID string) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("%w: unknown user %q", ErrOwnerCannotTrade, userID)
 	}
-
-	return token, nil
+	var user models.User
+	if err := repository.IRepo.FindOneWhere(ctx, "users", bson.M{"_id": oid}, &user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: unknown user %s", ErrOwnerCannotTrade, userID)
+		}
+		return fmt.Errorf("check order owner: %w", err)
+	}
+	if !user.Status || user.SuspendedAt != nil || user.DeletedAt != nil {
+		return fmt.Errorf("%w: account is inactive or suspended", ErrOwnerCannotTrade)
+	}
+	if !rbac.HasPermission(user.Role, rbac.PermOrdersWrite) {
+		return fmt.Errorf("%w: role %s lacks %s", ErrOwnerCannotTrade, rbac.NormalizeRole(user.Role), rbac.PermOrdersWrite)
+	}
+	return nil
 }
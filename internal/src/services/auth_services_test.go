@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"exdex/config"
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/rbac"
+)
+
+// useMemoryRepo points the services at a fresh in-memory repository for the
+// rest of the test.
+func useMemoryRepo(t *testing.T) *repository.MemoryRepository {
+	t.Helper()
+	mem := repository.NewMemoryRepository().Unique("users", "email")
+	prev := repository.IRepo
+	repository.IRepo = mem
+	t.Cleanup(func() { repository.IRepo = prev })
+	return mem
+}
+
+func useIdentities(t *testing.T, users map[string]Identity) {
+	t.Helper()
+	SetIdentityProvider(&FakeIdentityProvider{Users: users})
+	t.Cleanup(func() { SetIdentityProvider(nil) })
+}
+
+func useJWTConfig(t *testing.T) {
+	t.Helper()
+	prev := config.C.JWT
+	config.C.JWT = config.JWTConfig{Secret: "test-secret", Expiration: time.Hour}
+	t.Cleanup(func() { config.C.JWT = prev })
+}
+
+func TestExdexAuthSyncsUser(t *testing.T) {
+	suspendedAt := time.Now().Add(-time.Hour)
+	alice := Identity{ExdexUserID: 7, Email: "alice@example.com", FullName: "Alice", AccountNumber: "A-7", Role: "Trader"}
+	renamed := alice
+	renamed.Email, renamed.FullName, renamed.Role = "alice@new.example.com", "Alice B", "user"
+
+	tests := []struct {
+		name     string
+		existing *models.User
+		login    Identity
+		wantRole string
+		wantErr  error
+	}{
+		{
+			name:     "first login inserts the user",
+			login:    alice,
+			wantRole: rbac.RoleTrader,
+		},
+		{
+			name: "later login syncs the profile and upstream role",
+			existing: &models.User{
+				ExdexUserID: 7, Email: alice.Email, FullName: alice.FullName, Role: rbac.RoleTrader, Status: true,
+			},
+			login:    renamed,
+			wantRole: rbac.RoleUser,
+		},
+		{
+			name: "an admin-set role survives login",
+			existing: &models.User{
+				ExdexUserID: 7, Email: alice.Email, Role: rbac.RoleAdmin, RoleOverride: true, Status: true,
+			},
+			login:    renamed,
+			wantRole: rbac.RoleAdmin,
+		},
+		{
+			name: "suspended users get no session",
+			existing: &models.User{
+				ExdexUserID: 7, Email: alice.Email, Role: rbac.RoleUser, Status: true, SuspendedAt: &suspendedAt,
+			},
+			login:   alice,
+			wantErr: ErrSessionUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			useMemoryRepo(t)
+			useJWTConfig(t)
+			useIdentities(t, map[string]Identity{"token": tt.login})
+
+			var existingID string
+			if tt.existing != nil {
+				if err := repository.IRepo.Insert(ctx, "users", tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				u, err := repository.FindOne[models.User](ctx, "users", bson.M{"exdex_user_id": tt.existing.ExdexUserID})
+				if err != nil {
+					t.Fatal(err)
+				}
+				existingID = u.ID
+			}
+
+			pair, err := AuthServices{}.ExdexAuth(ctx, "token", "test", "127.0.0.1")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ExdexAuth error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExdexAuth: %v", err)
+			}
+			if pair.AccessToken == "" || pair.RefreshToken == "" {
+				t.Fatalf("ExdexAuth returned an incomplete token pair: %+v", pair)
+			}
+
+			users, err := repository.Find[models.User](ctx, "users", bson.M{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 1 {
+				t.Fatalf("got %d users, want 1", len(users))
+			}
+			u := users[0]
+			if existingID != "" && u.ID != existingID {
+				t.Errorf("user id = %s, want the existing %s", u.ID, existingID)
+			}
+			if u.Email != tt.login.Email || u.FullName != tt.login.FullName || u.AccountNumber != tt.login.AccountNumber {
+				t.Errorf("profile = %q %q %q, want %q %q %q", u.Email, u.FullName, u.AccountNumber,
+					tt.login.Email, tt.login.FullName, tt.login.AccountNumber)
+			}
+			if u.Role != tt.wantRole {
+				t.Errorf("role = %q, want %q", u.Role, tt.wantRole)
+			}
+			if u.UpstreamRole != tt.login.Role {
+				t.Errorf("upstream role = %q, want %q", u.UpstreamRole, tt.login.Role)
+			}
+			if !u.Status || u.LastLoginAt == nil {
+				t.Errorf("status = %v, last login = %v, want an active user with a login time", u.Status, u.LastLoginAt)
+			}
+		})
+	}
+}
+
+func TestExdexAuthRejectsUnknownToken(t *testing.T) {
+	useMemoryRepo(t)
+	useIdentities(t, map[string]Identity{})
+
+	_, err := AuthServices{}.ExdexAuth(context.Background(), "nope", "test", "127.0.0.1")
+	if !errors.Is(err, ErrInvalidIdentityToken) {
+		t.Fatalf("ExdexAuth error = %v, want %v", err, ErrInvalidIdentityToken)
+	}
+	if n, _ := repository.IRepo.Count(context.Background(), "users", bson.M{}); n != 0 {
+		t.Fatalf("%d users stored for a rejected token", n)
+	}
+}
+
+func TestAuthorizeOwnerOrder(t *testing.T) {
+	suspendedAt := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		user    *models.User
+		wantErr bool
+	}{
+		{"active user", &models.User{Role: rbac.RoleUser, Status: true}, false},
+		{"inactive user", &models.User{Role: rbac.RoleUser, Status: false}, true},
+		{"suspended user", &models.User{Role: rbac.RoleUser, Status: true, SuspendedAt: &suspendedAt}, true},
+		{"role without orders:write", &models.User{Role: rbac.RoleSupport, Status: true}, true},
+		{"unknown user", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			useMemoryRepo(t)
+
+			userID := "000000000000000000000001"
+			if tt.user != nil {
+				tt.user.Email = "owner@example.com"
+				if err := repository.IRepo.Insert(ctx, "users", tt.user); err != nil {
+					t.Fatal(err)
+				}
+				u, err := repository.FindOne[models.User](ctx, "users", bson.M{"email": tt.user.Email})
+				if err != nil {
+					t.Fatal(err)
+				}
+				userID = u.ID
+			}
+
+			err := authorizeOwnerOrder(ctx, userID)
+			if tt.wantErr && !errors.Is(err, ErrOwnerCannotTrade) {
+				t.Fatalf("authorizeOwnerOrder error = %v, want %v", err, ErrOwnerCannotTrade)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("authorizeOwnerOrder: %v", err)
+			}
+		})
+	}
+}
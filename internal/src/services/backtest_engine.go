@@ -0,0 +1,494 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	models "exdex/internal/src/model"
+)
+
+// BacktestStrategy is driven bar by bar. OnBar runs at the close of each bar,
+// after resting orders have been matched against it; OnFill runs for every
+// fill of a resting order.
+type BacktestStrategy interface {
+	OnBar(bt *Backtester, bar models.Candle)
+	OnFill(bt *Backtester, order models.OrderRecord)
+}
+
+var backtestStrategies = map[string]func(params map[string]float64) (BacktestStrategy, error){
+	"grid":    newGridBacktest,
+	"dca":     newDCABacktest,
+	"bracket": newBracketBacktest,
+}
+
+type btOrder struct {
+	rec   models.OrderRecord
+	qty   float64
+	price float64
+	stop  float64
+}
+
+// Backtester is a single-symbol simulated account. Orders use the live
+// OrderRecord shape; limit orders fill at their price when the bar trades
+// through it, market and stop fills pay slippage.
+type Backtester struct {
+	Info     SymbolInfo
+	Base     float64
+	Quote    float64
+	feeRate  float64
+	slippage float64 // fraction, from basis points
+	bar      models.Candle
+	open     []*btOrder
+	trades   []models.BacktestTrade
+	nextID   int64
+	avgCost  float64
+	strategy BacktestStrategy
+}
+
+func newBacktester(info SymbolInfo, initialQuote, feeRate, slippageBps float64, strategy BacktestStrategy) *Backtester {
+	return &Backtester{
+		Info:     info,
+		Quote:    initialQuote,
+		feeRate:  feeRate,
+		slippage: slippageBps / 10000,
+		strategy: strategy,
+	}
+}
+
+// Price is the close of the current bar.
+func (bt *Backtester) Price() float64 {
+	return bt.bar.Close
+}
+
+func (bt *Backtester) newOrder(side, orderType string, qty, price float64) *btOrder {
+	bt.nextID++
+	return &btOrder{
+		rec: models.OrderRecord{
+			Symbol:        bt.Info.Symbol,
+			Side:          side,
+			Type:          orderType,
+			Quantity:      bt.Info.FormatQuantity(qty),
+			Price:         bt.Info.FormatPrice(price),
+			OrderID:       bt.nextID,
+			ClientOrderID: fmt.Sprintf("bt_%d", bt.nextID),
+			Status:        "NEW",
+			CreatedAt:     primitive.NewDateTimeFromTime(bt.bar.CloseTime),
+		},
+		qty:   qty,
+		price: price,
+	}
+}
+
+// Market fills qty at the bar close plus slippage.
+func (bt *Backtester) Market(side string, qty float64) error {
+	qty, _ = strconv.ParseFloat(bt.Info.FormatQuantity(qty), 64)
+	if qty <= 0 {
+		return fmt.Errorf("quantity too small")
+	}
+	o := bt.newOrder(side, "MARKET", qty, 0)
+	return bt.fill(o, bt.slipped(side, bt.bar.Close))
+}
+
+// MarketQuote spends quote at the bar close plus slippage.
+func (bt *Backtester) MarketQuote(quote float64) error {
+	return bt.Market("BUY", quote/bt.slipped("BUY", bt.bar.Close))
+}
+
+// Limit rests a GTC limit order and returns its ID.
+func (bt *Backtester) Limit(side string, qty, price float64) int64 {
+	o := bt.newOrder(side, "LIMIT", qty, price)
+	bt.open = append(bt.open, o)
+	return o.rec.OrderID
+}
+
+// Bracket rests a take-profit limit and a stop-loss leg that cancel each
+// other, like the live OCO.
+func (bt *Backtester) Bracket(side string, qty, tp, sl float64) {
+	tpLeg := bt.newOrder(side, "LIMIT_MAKER", qty, tp)
+	slLeg := bt.newOrder(side, "STOP_LOSS_LIMIT", qty, sl)
+	slLeg.stop = sl
+	slLeg.rec.StopPrice = bt.Info.FormatPrice(sl)
+	tpLeg.rec.OrderListID = tpLeg.rec.OrderID
+	slLeg.rec.OrderListID = tpLeg.rec.OrderID
+	bt.open = append(bt.open, tpLeg, slLeg)
+}
+
+func (bt *Backtester) Cancel(orderID int64) {
+	for i, o := range bt.open {
+		if o.rec.OrderID == orderID {
+			bt.open = append(bt.open[:i], bt.open[i+1:]...)
+			return
+		}
+	}
+}
+
+func (bt *Backtester) OpenOrders() int {
+	return len(bt.open)
+}
+
+func (bt *Backtester) Equity() float64 {
+	return bt.Quote + bt.Base*bt.bar.Close
+}
+
+func (bt *Backtester) slipped(side string, price float64) float64 {
+	if side == "BUY" {
+		return price * (1 + bt.slippage)
+	}
+	return price * (1 - bt.slippage)
+}
+
+func (bt *Backtester) fill(o *btOrder, price float64) error {
+	fee := 0.0
+	feeAsset := ""
+	pnl := 0.0
+	if o.rec.Side == "BUY" {
+		cost := o.qty * price
+		if cost > bt.Quote+1e-9 {
+			return fmt.Errorf("insufficient %s: have %.8f, need %.8f", bt.Info.QuoteAsset, bt.Quote, cost)
+		}
+		received := o.qty * (1 - bt.feeRate)
+		fee, feeAsset = o.qty*bt.feeRate, bt.Info.BaseAsset
+		if bt.Base+received > 0 {
+			bt.avgCost = (bt.avgCost*bt.Base + cost) / (bt.Base + received)
+		}
+		bt.Quote -= cost
+		bt.Base += received
+	} else {
+		if o.qty > bt.Base+1e-9 {
+			return fmt.Errorf("insufficient %s: have %.8f, need %.8f", bt.Info.BaseAsset, bt.Base, o.qty)
+		}
+		proceeds := o.qty * price * (1 - bt.feeRate)
+		fee, feeAsset = o.qty*price*bt.feeRate, bt.Info.QuoteAsset
+		pnl = proceeds - o.qty*bt.avgCost
+		bt.Base = math.Max(0, bt.Base-o.qty)
+		bt.Quote += proceeds
+		if bt.Base == 0 {
+			bt.avgCost = 0
+		}
+	}
+
+	o.rec.Status = "FILLED"
+	o.rec.ExecutedQty = o.rec.Quantity
+	o.rec.QuoteQty = strconv.FormatFloat(o.qty*price, 'f', -1, 64)
+	bt.trades = append(bt.trades, models.BacktestTrade{
+		Time:        bt.bar.CloseTime,
+		Order:       o.rec,
+		Price:       price,
+		Quantity:    o.qty,
+		Fee:         fee,
+		FeeAsset:    feeAsset,
+		RealizedPnL: pnl,
+	})
+	return nil
+}
+
+// matchBar fills resting orders the bar traded through. Stops are checked
+// first, so a bracket whose legs both trigger on one bar exits at the stop.
+func (bt *Backtester) matchBar() {
+	ordered := make([]*btOrder, 0, len(bt.open))
+	for _, o := range bt.open {
+		if o.stop > 0 {
+			ordered = append(ordered, o)
+		}
+	}
+	for _, o := range bt.open {
+		if o.stop == 0 {
+			ordered = append(ordered, o)
+		}
+	}
+
+	closedLists := make(map[int64]bool)
+	var filled []models.OrderRecord
+	for _, o := range ordered {
+		if o.rec.OrderListID != 0 && closedLists[o.rec.OrderListID] {
+			continue
+		}
+		price, ok := bt.triggerPrice(o)
+		if !ok {
+			continue
+		}
+		if o.rec.OrderListID != 0 {
+			closedLists[o.rec.OrderListID] = true
+		}
+		if err := bt.fill(o, price); err != nil {
+			o.rec.Status = "REJECTED"
+			continue
+		}
+		filled = append(filled, o.rec)
+	}
+
+	remaining := make([]*btOrder, 0, len(bt.open))
+	for _, o := range bt.open {
+		if o.rec.Status == "NEW" && !closedLists[o.rec.OrderListID] {
+			remaining = append(remaining, o)
+		}
+	}
+	bt.open = remaining
+
+	for _, rec := range filled {
+		bt.strategy.OnFill(bt, rec)
+	}
+}
+
+// triggerPrice reports whether the current bar reaches the order and at what
+// price it fills. A bar that gaps through the order fills at the open.
+func (bt *Backtester) triggerPrice(o *btOrder) (float64, bool) {
+	switch {
+	case o.stop > 0 && o.rec.Side == "SELL" && bt.bar.Low <= o.stop:
+		return bt.slipped("SELL", math.Min(o.stop, bt.bar.Open)), true
+	case o.stop > 0 && o.rec.Side == "BUY" && bt.bar.High >= o.stop:
+		return bt.slipped("BUY", math.Max(o.stop, bt.bar.Open)), true
+	case o.stop == 0 && o.rec.Side == "BUY" && bt.bar.Low <= o.price:
+		return math.Min(o.price, bt.bar.Open), true
+	case o.stop == 0 && o.rec.Side == "SELL" && bt.bar.High >= o.price:
+		return math.Max(o.price, bt.bar.Open), true
+	}
+	return 0, false
+}
+
+// Run replays candles and returns the equity curve, one point per bar.
+func (bt *Backtester) Run(candles []models.Candle) []models.EquityPoint {
+	curve := make([]models.EquityPoint, 0, len(candles))
+	for _, c := range candles {
+		bt.bar = c
+		bt.matchBar()
+		bt.strategy.OnBar(bt, c)
+		curve = append(curve, models.EquityPoint{Time: c.CloseTime, Equity: bt.Equity()})
+	}
+	return curve
+}
+
+func backtestStats(bt *Backtester, candles []models.Candle, curve []models.EquityPoint, initial float64, barsPerYear float64) models.BacktestStats {
+	stats := models.BacktestStats{
+		FinalEquity: bt.Equity(),
+		ReturnPct:   (bt.Equity() - initial) / initial * 100,
+		Trades:      len(bt.trades),
+		FinalBase:   bt.Base,
+		FinalQuote:  bt.Quote,
+	}
+	if len(candles) > 0 && candles[0].Open > 0 {
+		stats.BuyAndHoldPct = (candles[len(candles)-1].Close - candles[0].Open) / candles[0].Open * 100
+	}
+
+	wins, losses, grossWin, grossLoss := 0, 0, 0.0, 0.0
+	for _, t := range bt.trades {
+		if t.FeeAsset == bt.Info.BaseAsset {
+			stats.TotalFees += t.Fee * t.Price
+		} else {
+			stats.TotalFees += t.Fee
+		}
+		if t.Order.Side != "SELL" {
+			continue
+		}
+		stats.RoundTrips++
+		if t.RealizedPnL > 0 {
+			wins++
+			grossWin += t.RealizedPnL
+		} else {
+			losses++
+			grossLoss -= t.RealizedPnL
+		}
+	}
+	if stats.RoundTrips > 0 {
+		stats.WinRate = float64(wins) / float64(stats.RoundTrips)
+	}
+	if grossLoss > 0 {
+		stats.ProfitFactor = grossWin / grossLoss
+	}
+
+	peak, returns := 0.0, make([]float64, 0, len(curve))
+	for i, p := range curve {
+		peak = math.Max(peak, p.Equity)
+		if peak > 0 {
+			stats.MaxDrawdownPct = math.Max(stats.MaxDrawdownPct, (peak-p.Equity)/peak*100)
+		}
+		if i > 0 && curve[i-1].Equity > 0 {
+			returns = append(returns, p.Equity/curve[i-1].Equity-1)
+		}
+	}
+	if len(returns) > 1 {
+		mean, variance := 0.0, 0.0
+		for _, r := range returns {
+			mean += r
+		}
+		mean /= float64(len(returns))
+		for _, r := range returns {
+			variance += (r - mean) * (r - mean)
+		}
+		sd := math.Sqrt(variance / float64(len(returns)-1))
+		if sd > 0 {
+			stats.SharpeAnnualised = mean / sd * math.Sqrt(barsPerYear)
+		}
+	}
+	return stats
+}
+
+// downsampleEquity keeps at most max points, always including the last.
+func downsampleEquity(curve []models.EquityPoint, max int) []models.EquityPoint {
+	if len(curve) <= max {
+		return curve
+	}
+	stride := float64(len(curve)-1) / float64(max-1)
+	out := make([]models.EquityPoint, 0, max)
+	for i := 0; i < max; i++ {
+		out = append(out, curve[int(math.Round(float64(i)*stride))])
+	}
+	return out
+}
+
+func param(params map[string]float64, key string, def float64) float64 {
+	if v, ok := params[key]; ok {
+		return v
+	}
+	return def
+}
+
+// gridBacktest mirrors the live grid bot: a ladder of limits, each fill
+// re-placing the opposite order one level away.
+type gridBacktest struct {
+	lower, upper float64
+	count        int
+	geometric    bool
+	prices       []float64
+	qty          float64
+	levelOf      map[int64]int
+}
+
+func newGridBacktest(params map[string]float64) (BacktestStrategy, error) {
+	g := &gridBacktest{
+		lower:     param(params, "lower_price", 0),
+		upper:     param(params, "upper_price", 0),
+		count:     int(param(params, "grid_count", 10)),
+		geometric: param(params, "geometric", 0) == 1,
+		levelOf:   make(map[int64]int),
+	}
+	if g.lower <= 0 || g.upper <= g.lower {
+		return nil, fmt.Errorf("grid needs 0 < lower_price < upper_price")
+	}
+	if g.count < 2 || g.count > 200 {
+		return nil, fmt.Errorf("grid_count must be between 2 and 200")
+	}
+	return g, nil
+}
+
+func (g *gridBacktest) OnBar(bt *Backtester, bar models.Candle) {
+	if g.prices != nil {
+		return
+	}
+	spacing := models.GridSpacingArithmetic
+	if g.geometric {
+		spacing = models.GridSpacingGeometric
+	}
+	g.prices = gridLevelPrices(g.lower, g.upper, g.count, spacing)
+
+	current := bar.Close
+	g.qty = bt.Quote / float64(g.count) / current
+	empty := 0
+	for i, p := range g.prices {
+		if math.Abs(p-current) < math.Abs(g.prices[empty]-current) {
+			empty = i
+		}
+	}
+	if sells := len(g.prices) - 1 - empty; sells > 0 {
+		if err := bt.Market("BUY", g.qty*float64(sells)/(1-bt.feeRate)); err != nil {
+			return
+		}
+	}
+	for i, p := range g.prices {
+		if i < empty {
+			g.levelOf[bt.Limit("BUY", g.qty, p)] = i
+		} else if i > empty {
+			g.levelOf[bt.Limit("SELL", g.qty, p)] = i
+		}
+	}
+}
+
+func (g *gridBacktest) OnFill(bt *Backtester, order models.OrderRecord) {
+	level, ok := g.levelOf[order.OrderID]
+	if !ok {
+		return
+	}
+	delete(g.levelOf, order.OrderID)
+	if order.Side == "BUY" && level+1 < len(g.prices) {
+		g.levelOf[bt.Limit("SELL", g.qty, g.prices[level+1])] = level + 1
+	} else if order.Side == "SELL" && level > 0 {
+		g.levelOf[bt.Limit("BUY", g.qty, g.prices[level-1])] = level - 1
+	}
+}
+
+// dcaBacktest buys a fixed quote amount every N bars.
+type dcaBacktest struct {
+	quote float64
+	every int
+	bars  int
+}
+
+func newDCABacktest(params map[string]float64) (BacktestStrategy, error) {
+	d := &dcaBacktest{
+		quote: param(params, "quote_amount", 0),
+		every: int(param(params, "every_bars", 1)),
+	}
+	if d.quote <= 0 {
+		return nil, fmt.Errorf("dca needs a positive quote_amount")
+	}
+	if d.every < 1 {
+		return nil, fmt.Errorf("every_bars must be at least 1")
+	}
+	return d, nil
+}
+
+func (d *dcaBacktest) OnBar(bt *Backtester, bar models.Candle) {
+	if d.bars%d.every == 0 && bt.Quote >= d.quote {
+		bt.MarketQuote(d.quote)
+	}
+	d.bars++
+}
+
+func (d *dcaBacktest) OnFill(bt *Backtester, order models.OrderRecord) {}
+
+// bracketBacktest enters at market whenever flat and exits through a
+// take-profit / stop-loss bracket.
+type bracketBacktest struct {
+	tpPct   float64
+	slPct   float64
+	sizePct float64
+}
+
+func newBracketBacktest(params map[string]float64) (BacktestStrategy, error) {
+	b := &bracketBacktest{
+		tpPct:   param(params, "take_profit_pct", 0),
+		slPct:   param(params, "stop_loss_pct", 0),
+		sizePct: param(params, "size_pct", 100),
+	}
+	if b.tpPct <= 0 || b.slPct <= 0 || b.slPct >= 100 {
+		return nil, fmt.Errorf("bracket needs take_profit_pct and stop_loss_pct between 0 and 100")
+	}
+	if b.sizePct <= 0 || b.sizePct > 100 {
+		return nil, fmt.Errorf("size_pct must be in (0, 100]")
+	}
+	return b, nil
+}
+
+func (b *bracketBacktest) OnBar(bt *Backtester, bar models.Candle) {
+	if bt.OpenOrders() > 0 {
+		return
+	}
+	before := bt.Base
+	if err := bt.MarketQuote(bt.Quote * b.sizePct / 100); err != nil {
+		return
+	}
+	entry := bt.slipped("BUY", bar.Close)
+	qty, _ := strconv.ParseFloat(bt.Info.FormatQuantity(bt.Base-before), 64)
+	bt.Bracket("SELL", qty, entry*(1+b.tpPct/100), entry*(1-b.slPct/100))
+}
+
+func (b *bracketBacktest) OnFill(bt *Backtester, order models.OrderRecord) {}
+
+// barsPerYear is used to annualise the Sharpe ratio.
+func barsPerYear(step time.Duration) float64 {
+	return float64(365*24*time.Hour) / float64(step)
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/validator"
+)
+
+const (
+	backtestMaxBars         = 100000
+	backtestMaxEquityPoints = 1000
+)
+
+type BacktestServices struct{}
+
+// Start validates the request, stores a running BacktestRun and replays it in
+// the background.
+func (b BacktestServices) Start(ctx context.Context, userID string, req models.BacktestRequest) (models.BacktestRun, error) {
+	if err := validator.Validate(&req); err != nil {
+		return models.BacktestRun{}, err
+	}
+	req.Symbol = strings.ToUpper(req.Symbol)
+	req.Strategy = strings.ToLower(req.Strategy)
+
+	step, err := intervalDuration(req.Interval)
+	if err != nil {
+		return models.BacktestRun{}, err
+	}
+	if !req.End.After(req.Start) {
+		return models.BacktestRun{}, errors.New("end must be after start")
+	}
+	if bars := req.End.Sub(req.Start) / step; bars > backtestMaxBars {
+		return models.BacktestRun{}, fmt.Errorf("range covers %d bars, the limit is %d", bars, backtestMaxBars)
+	}
+	newStrategy, ok := backtestStrategies[req.Strategy]
+	if !ok {
+		return models.BacktestRun{}, fmt.Errorf("unknown strategy %q", req.Strategy)
+	}
+	if _, err := newStrategy(req.Params); err != nil {
+		return models.BacktestRun{}, err
+	}
+	feeRate := paperDefaultFeeRate
+	if req.FeeRate != nil {
+		feeRate = *req.FeeRate
+	}
+	if feeRate < 0 || feeRate >= 1 || req.SlippageBps < 0 {
+		return models.BacktestRun{}, errors.New("fee_rate must be in [0, 1) and slippage_bps non-negative")
+	}
+
+	run := models.BacktestRun{
+		ID:             primitive.NewObjectID(),
+		UserID:         userID,
+		Symbol:         req.Symbol,
+		Interval:       req.Interval,
+		Start:          req.Start,
+		End:            req.End,
+		Strategy:       req.Strategy,
+		Params:         req.Params,
+		InitialBalance: req.InitialBalance,
+		FeeRate:        feeRate,
+		SlippageBps:    req.SlippageBps,
+		Status:         models.BacktestStatusRunning,
+		CreatedAt:      time.Now(),
+	}
+	if err := repository.IRepo.Insert(ctx, "backtest_runs", &run); err != nil {
+		return models.BacktestRun{}, err
+	}
+
+	go executeBacktest(run)
+	return run, nil
+}
+
+func executeBacktest(run models.BacktestRun) {
+	ctx := context.Background()
+	set := bson.M{}
+	defer func() {
+		if r := recover(); r != nil {
+			set = bson.M{"status": models.BacktestStatusFailed, "error": fmt.Sprintf("strategy panicked: %v", r)}
+		}
+		now := time.Now()
+		set["finished_at"] = now
+		if err := repository.IRepo.UpdateOne(ctx, "backtest_runs", bson.M{"_id": run.ID}, bson.M{"$set": set}, false); err != nil {
+			log.Printf("❌ Backtest %s: failed to save result: %v", run.ID.Hex(), err)
+		}
+	}()
+
+	fail := func(err error) {
+		log.Printf("❌ Backtest %s failed: %v", run.ID.Hex(), err)
+		set = bson.M{"status": models.BacktestStatusFailed, "error": err.Error()}
+	}
+
+	info, err := GetSymbolInfo(run.Symbol)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if err := SyncCandles(ctx, run.Symbol, run.Interval, run.Start, run.End); err != nil {
+		fail(fmt.Errorf("candle sync failed: %v", err))
+		return
+	}
+	candles, err := LoadCandles(ctx, run.Symbol, run.Interval, run.Start, run.End)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if len(candles) == 0 {
+		fail(errors.New("no candles in range"))
+		return
+	}
+
+	strategy, err := backtestStrategies[run.Strategy](run.Params)
+	if err != nil {
+		fail(err)
+		return
+	}
+	bt := newBacktester(info, run.InitialBalance, run.FeeRate, run.SlippageBps, strategy)
+	curve := bt.Run(candles)
+
+	step, _ := intervalDuration(run.Interval)
+	stats := backtestStats(bt, candles, curve, run.InitialBalance, barsPerYear(step))
+
+	for i := range bt.trades {
+		bt.trades[i].RunID = run.ID
+		if err := repository.IRepo.Insert(ctx, "backtest_trades", &bt.trades[i]); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	set = bson.M{
+		"status": models.BacktestStatusCompleted,
+		"bars":   len(candles),
+		"stats":  stats,
+		"equity": downsampleEquity(curve, backtestMaxEquityPoints),
+	}
+	log.Printf("✅ Backtest %s: %s on %s %s, %d bars, return %.2f%%", run.ID.Hex(), run.Strategy, run.Symbol, run.Interval, len(candles), stats.ReturnPct)
+}
+
+// List returns the user's runs, newest first, without equity curves.
+func (b BacktestServices) List(ctx context.Context, userID string) ([]models.BacktestRun, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"equity": 0})
+	runs, err := repository.Find[models.BacktestRun](ctx, "backtest_runs", bson.M{"user_id": userID}, opts)
+	return runs, err
+}
+
+func (b BacktestServices) Get(ctx context.Context, userID, runID string) (models.BacktestRun, error) {
+	var run models.BacktestRun
+	oid, err := primitive.ObjectIDFromHex(runID)
+	if err != nil {
+		return run, errors.New("invalid backtest id")
+	}
+	if err := repository.IRepo.FindOneWhere(ctx, "backtest_runs", bson.M{"_id": oid, "user_id": userID}, &run); err != nil {
+		return run, errors.New("backtest not found")
+	}
+	return run, nil
+}
+
+func (b BacktestServices) Trades(ctx context.Context, userID, runID string, page, limit int) ([]models.BacktestTrade, int64, error) {
+	run, err := b.Get(ctx, userID, runID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := bson.M{"run_id": run.ID}
+	filter := models.Filter{
+		Sort:      "time",
+		SortOrder: 1,
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	}
+
+	trades, err := repository.FindPage[models.BacktestTrade](ctx, "backtest_trades", query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := repository.IRepo.Count(ctx, "backtest_trades", query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return trades, total, nil
+}
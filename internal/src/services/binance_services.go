@@ -1,22 +1,15 @@
 package services
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"time"
-)
 
-const (
-	apiKey    = "your_api_key"
-	apiSecret = "your_api_secret"
-	baseURL   = "https://testnet.binance.vision"
+	"exdex/config"
 )
 
 type Order struct {
@@ -37,43 +30,30 @@ type Order struct {
 	IsWorking     bool   `json:"isWorking"`
 }
 
-func GetOpenOrders() ([]Order, error) {
+func GetOpenOrders(ctx context.Context) ([]Order, error) {
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 
 	params := url.Values{}
+	params.Set("recvWindow", binanceRecvWindow())
 	params.Set("timestamp", timestamp)
 
-	signature := createSignature(params.Encode())
-	requestURL := fmt.Sprintf("%s/api/v3/openOrders?%s&signature=%s", baseURL, params.Encode(), signature)
+	signature := signQuery(params.Encode())
+	requestURL := fmt.Sprintf("%s/api/v3/openOrders?%s&signature=%s", config.C.Binance.BaseURL, params.Encode(), signature)
 
 	req, err := http.NewRequest("GET", requestURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-MBX-APIKEY", apiKey)
+	req.Header.Set("X-MBX-APIKEY", config.C.Binance.APIKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	status, body, err := doExchangeRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		var apiErr struct {
-			Code int    `json:"code"`
-			Msg  string `json:"msg"`
-		}
-		if err := json.Unmarshal(body, &apiErr); err != nil {
-			return nil, fmt.Errorf("API error: %s", string(body))
-		}
-		return nil, fmt.Errorf("API error %d: %s", apiErr.Code, apiErr.Msg)
+	if status != http.StatusOK {
+		return nil, parseExchangeError(status, body)
 	}
 
 	var orders []Order
@@ -83,9 +63,3 @@ func GetOpenOrders() ([]Order, error) {
 
 	return orders, nil
 }
-
-func createSignature(data string) string {
-	h := hmac.New(sha256.New, []byte(apiSecret))
-	h.Write([]byte(data))
-	return hex.EncodeToString(h.Sum(nil))
-}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+)
+
+const klinePageLimit = 1000
+
+var klineIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  72 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+func intervalDuration(interval string) (time.Duration, error) {
+	d, ok := klineIntervals[interval]
+	if !ok {
+		return 0, fmt.Errorf("unsupported interval %q", interval)
+	}
+	return d, nil
+}
+
+// SyncCandles makes sure the candles collection covers [start, end), pulling
+// missing bars from the exchange.
+func SyncCandles(ctx context.Context, symbol, interval string, start, end time.Time) error {
+	step, err := intervalDuration(interval)
+	if err != nil {
+		return err
+	}
+	symbol = strings.ToUpper(symbol)
+
+	filter := bson.M{"symbol": symbol, "interval": interval, "open_time": bson.M{"$gte": start, "$lt": end}}
+	stored, err := repository.IRepo.Count(ctx, "candles", filter)
+	if err != nil {
+		return err
+	}
+	if stored >= int64(end.Sub(start)/step) {
+		return nil
+	}
+
+	for from := start; from.Before(end); {
+		klines, err := GetKlines(ctx, symbol, interval, from, end.Add(-time.Millisecond), klinePageLimit)
+		if err != nil {
+			return err
+		}
+		if len(klines) == 0 {
+			break
+		}
+		for _, k := range klines {
+			candle := bson.M{
+				"symbol":     symbol,
+				"interval":   interval,
+				"open_time":  k.OpenTime,
+				"close_time": k.CloseTime,
+				"open":       k.Open,
+				"high":       k.High,
+				"low":        k.Low,
+				"close":      k.Close,
+				"volume":     k.Volume,
+			}
+			key := bson.M{"symbol": symbol, "interval": interval, "open_time": k.OpenTime}
+			if err := repository.IRepo.UpdateOne(ctx, "candles", key, bson.M{"$set": candle}, true); err != nil {
+				return err
+			}
+		}
+		from = klines[len(klines)-1].OpenTime.Add(step)
+		if len(klines) < klinePageLimit {
+			break
+		}
+	}
+	log.Printf("🕯️  Synced %s %s candles from %s to %s", symbol, interval, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	return nil
+}
+
+// LoadCandles returns stored candles in [start, end) in time order.
+func LoadCandles(ctx context.Context, symbol, interval string, start, end time.Time) ([]models.Candle, error) {
+	filter := bson.M{"symbol": strings.ToUpper(symbol), "interval": interval, "open_time": bson.M{"$gte": start, "$lt": end}}
+	opts := options.Find().SetSort(bson.D{{Key: "open_time", Value: 1}})
+	candles, err := repository.Find[models.Candle](ctx, "candles", filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return candles, nil
+}
@@ -0,0 +1,543 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/validator"
+)
+
+const (
+	copyQueueSize     = 1024
+	copyEquityTTL     = time.Minute
+	copyOwnerAttempts = 5
+	copyOwnerWait     = 200 * time.Millisecond
+)
+
+type CopyServices struct{}
+
+func (s CopyServices) BecomeLeader(ctx context.Context, userID string, req models.CopyLeaderRequest) (models.CopyLeader, error) {
+	if err := validator.Validate(&req); err != nil {
+		return models.CopyLeader{}, err
+	}
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"display_name": req.DisplayName,
+			"description":  req.Description,
+			"active":       true,
+			"updated_at":   now,
+		},
+		"$setOnInsert": bson.M{"user_id": userID, "created_at": now},
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "copy_leaders", bson.M{"user_id": userID}, update, true); err != nil {
+		return models.CopyLeader{}, err
+	}
+	return s.MyLeaderProfile(ctx, userID)
+}
+
+// StopLeading stops publishing; existing subscriptions stay but receive
+// nothing until the leader is active again.
+func (s CopyServices) StopLeading(ctx context.Context, userID string) error {
+	update := bson.M{"$set": bson.M{"active": false, "updated_at": time.Now()}}
+	return repository.IRepo.UpdateOne(ctx, "copy_leaders", bson.M{"user_id": userID}, update, false)
+}
+
+func (s CopyServices) MyLeaderProfile(ctx context.Context, userID string) (models.CopyLeader, error) {
+	leader, err := repository.FindOne[models.CopyLeader](ctx, "copy_leaders", bson.M{"user_id": userID})
+	if err != nil {
+		return leader, errors.New("you are not a leader")
+	}
+	leader.Followers, _ = repository.IRepo.Count(ctx, "copy_subscriptions", bson.M{"leader_id": leader.ID, "status": models.CopyStatusActive})
+	return leader, nil
+}
+
+func (s CopyServices) Leaders(ctx context.Context) ([]models.CopyLeader, error) {
+	leaders, err := repository.Find[models.CopyLeader](ctx, "copy_leaders", bson.M{"active": true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range leaders {
+		leaders[i].Followers, _ = repository.IRepo.Count(ctx, "copy_subscriptions", bson.M{"leader_id": leaders[i].ID, "status": models.CopyStatusActive})
+	}
+	return leaders, nil
+}
+
+func (s CopyServices) Follow(ctx context.Context, userID string, req models.CopySubscriptionRequest) (models.CopySubscription, error) {
+	if err := validator.Validate(&req); err != nil {
+		return models.CopySubscription{}, err
+	}
+	leaderID, err := primitive.ObjectIDFromHex(req.LeaderID)
+	if err != nil {
+		return models.CopySubscription{}, errors.New("invalid leader id")
+	}
+	leader, err := repository.FindOne[models.CopyLeader](ctx, "copy_leaders", bson.M{"_id": leaderID, "active": true})
+	if err != nil {
+		return models.CopySubscription{}, errors.New("leader not found")
+	}
+	if leader.UserID == userID {
+		return models.CopySubscription{}, errors.New("you cannot follow yourself")
+	}
+
+	switch req.Mode {
+	case models.CopyModeFixedRatio:
+		if req.Ratio <= 0 {
+			return models.CopySubscription{}, errors.New("fixed_ratio needs a positive ratio")
+		}
+	case models.CopyModeFixedNotional:
+		if req.Notional <= 0 {
+			return models.CopySubscription{}, errors.New("fixed_notional needs a positive notional")
+		}
+	case models.CopyModeEquity:
+		// Only paper accounts are valued per user; live users share the
+		// exchange account.
+		for _, id := range []string{userID, leader.UserID} {
+			paper, err := IsPaperUser(ctx, id)
+			if err != nil {
+				return models.CopySubscription{}, err
+			}
+			if !paper {
+				return models.CopySubscription{}, errors.New("equity_proportional is only available when both leader and follower paper trade")
+			}
+		}
+	default:
+		return models.CopySubscription{}, fmt.Errorf("unknown mode %q", req.Mode)
+	}
+	if req.MaxOrderNotional < 0 || req.MaxDailyNotional < 0 {
+		return models.CopySubscription{}, errors.New("risk limits cannot be negative")
+	}
+	for i := range req.Symbols {
+		req.Symbols[i] = strings.ToUpper(req.Symbols[i])
+	}
+
+	err = repository.IRepo.FindOneWhere(ctx, "copy_subscriptions", bson.M{"leader_id": leaderID, "follower_id": userID}, &models.CopySubscription{})
+	if err == nil {
+		return models.CopySubscription{}, errors.New("already following this leader")
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return models.CopySubscription{}, err
+	}
+
+	now := time.Now()
+	sub := models.CopySubscription{
+		ID:               primitive.NewObjectID(),
+		LeaderID:         leaderID,
+		LeaderUserID:     leader.UserID,
+		FollowerID:       userID,
+		Mode:             req.Mode,
+		Ratio:            req.Ratio,
+		Notional:         req.Notional,
+		MaxOrderNotional: req.MaxOrderNotional,
+		MaxDailyNotional: req.MaxDailyNotional,
+		Symbols:          req.Symbols,
+		Status:           models.CopyStatusActive,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	return sub, repository.IRepo.Insert(ctx, "copy_subscriptions", &sub)
+}
+
+func (s CopyServices) Subscriptions(ctx context.Context, userID string) ([]models.CopySubscription, error) {
+	subs, err := repository.Find[models.CopySubscription](ctx, "copy_subscriptions", bson.M{"follower_id": userID}, nil)
+	return subs, err
+}
+
+func (s CopyServices) Subscription(ctx context.Context, userID, id string) (models.CopySubscription, error) {
+	var sub models.CopySubscription
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return sub, errors.New("invalid subscription id")
+	}
+	if err := repository.IRepo.FindOneWhere(ctx, "copy_subscriptions", bson.M{"_id": oid, "follower_id": userID}, &sub); err != nil {
+		return sub, errors.New("subscription not found")
+	}
+	return sub, nil
+}
+
+func (s CopyServices) SetStatus(ctx context.Context, userID, id, status string) (models.CopySubscription, error) {
+	sub, err := s.Subscription(ctx, userID, id)
+	if err != nil {
+		return sub, err
+	}
+	update := bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}}
+	if err := repository.IRepo.UpdateOne(ctx, "copy_subscriptions", bson.M{"_id": sub.ID}, update, false); err != nil {
+		return sub, err
+	}
+	sub.Status = status
+	return sub, nil
+}
+
+func (s CopyServices) Unfollow(ctx context.Context, userID, id string) error {
+	sub, err := s.Subscription(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	return repository.IRepo.RemoveByFilter(ctx, "copy_subscriptions", bson.M{"_id": sub.ID})
+}
+
+func (s CopyServices) Orders(ctx context.Context, userID, id string, page, limit int) ([]models.CopyOrder, int64, error) {
+	sub, err := s.Subscription(ctx, userID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := bson.M{"subscription_id": sub.ID}
+	filter := models.Filter{
+		Sort:      "created_at",
+		SortOrder: -1,
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	}
+
+	orders, err := repository.FindPage[models.CopyOrder](ctx, "copy_orders", query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := repository.IRepo.Count(ctx, "copy_orders", query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+// Performance compares the follower's mirrored fills with the leader fills
+// they came from.
+func (s CopyServices) Performance(ctx context.Context, userID, id string) (models.CopyPerformance, error) {
+	var perf models.CopyPerformance
+	sub, err := s.Subscription(ctx, userID, id)
+	if err != nil {
+		return perf, err
+	}
+	orders, err := repository.Find[models.CopyOrder](ctx, "copy_orders", bson.M{"subscription_id": sub.ID}, nil)
+	if err != nil {
+		return perf, err
+	}
+
+	weighted := 0.0
+	for _, o := range orders {
+		switch o.Status {
+		case models.CopyOrderSkipped:
+			perf.Skipped++
+			continue
+		case models.CopyOrderFailed:
+			perf.Failed++
+			continue
+		}
+		perf.Mirrored++
+		perf.LeaderNotional += o.SourceQty * o.SourcePrice
+		perf.FollowerNotional += o.QuoteQty
+		if o.AvgPrice > 0 && o.SourcePrice > 0 {
+			bps := (o.AvgPrice - o.SourcePrice) / o.SourcePrice * 10000
+			if o.Side == "SELL" {
+				bps = -bps
+			}
+			weighted += bps * o.QuoteQty
+		}
+	}
+	if perf.FollowerNotional > 0 {
+		perf.AvgSlippageBps = weighted / perf.FollowerNotional
+	}
+	return perf, nil
+}
+
+var (
+	copyQueue     = make(chan ExecutionReport, copyQueueSize)
+	copyStartOnce sync.Once
+	copyEquity    sync.Map // user ID -> cachedPrice holding equity in USDT
+)
+
+// copyClientPrefix starts the client order ID of every mirrored order, so
+// their own fills are never mirrored again.
+const copyClientPrefix = "copy_"
+
+// StartCopyTrading mirrors leader fills. A single worker handles them in
+// order. It only runs on the instance with cron.is_runner set; the claims in
+// copy_orders keep a fill from being mirrored twice regardless.
+func StartCopyTrading() {
+	copyStartOnce.Do(func() {
+		RegisterExecutionListener(func(r ExecutionReport) {
+			if r.CurrentExecutionType != "TRADE" {
+				return
+			}
+			select {
+			case copyQueue <- r:
+			default:
+				log.Printf("❌ Copy queue full, dropping fill for order %d", r.OrderID)
+			}
+		})
+		goWorker(func(ctx context.Context) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case r := <-copyQueue:
+					mirrorLeaderFill(ctx, r)
+				}
+			}
+		})
+	})
+}
+
+func mirrorLeaderFill(ctx context.Context, r ExecutionReport) {
+	if strings.HasPrefix(r.ClientOrderID, copyClientPrefix) {
+		return
+	}
+	lastQty, _ := strconv.ParseFloat(r.LastExecutedQuantity, 64)
+	lastPrice, _ := strconv.ParseFloat(r.LastExecutedPrice, 64)
+	if lastQty <= 0 || lastPrice <= 0 {
+		return
+	}
+
+	// The order may not be stored yet when the fill arrives straight after
+	// placement.
+	var record models.OrderRecord
+	var err error
+	for i := 0; i < copyOwnerAttempts; i++ {
+		if err = repository.IRepo.FindOneWhere(ctx, "orders", bson.M{"order_id": r.OrderID}, &record); err == nil {
+			break
+		}
+		time.Sleep(copyOwnerWait)
+	}
+	if err != nil || record.UserID == "" {
+		return
+	}
+	if n, _ := repository.IRepo.Count(ctx, "copy_orders", bson.M{"order_id": r.OrderID}); n > 0 {
+		return
+	}
+
+	leader, err := repository.FindOne[models.CopyLeader](ctx, "copy_leaders", bson.M{"user_id": record.UserID, "active": true})
+	if err != nil {
+		return
+	}
+	subs, err := repository.Find[models.CopySubscription](ctx, "copy_subscriptions", bson.M{"leader_id": leader.ID, "status": models.CopyStatusActive}, nil)
+	if err != nil {
+		log.Printf("❌ Copy: failed to load followers of %s: %v", leader.ID.Hex(), err)
+		return
+	}
+
+	origQty, _ := strconv.ParseFloat(r.OrderQuantity, 64)
+	for _, sub := range subs {
+		// Claim the fill for this follower first. The unique index on
+		// (subscription_id, source_order_id, source_trade_id) lets only one
+		// claim through, even when the fill is delivered again after a
+		// reconnect or replayed, so the order is placed at most once.
+		co := models.CopyOrder{
+			ID:             primitive.NewObjectID(),
+			SubscriptionID: sub.ID,
+			LeaderID:       leader.ID,
+			FollowerID:     sub.FollowerID,
+			Symbol:         r.Symbol,
+			Side:           r.Side,
+			SourceOrderID:  r.OrderID,
+			SourceTradeID:  r.TradeID,
+			SourceQty:      lastQty,
+			SourcePrice:    lastPrice,
+			Status:         models.CopyOrderPending,
+			CreatedAt:      time.Now(),
+		}
+		if err := repository.IRepo.Insert(ctx, "copy_orders", &co); err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				log.Printf("❌ Copy: failed to claim mirror for %s: %v", sub.ID.Hex(), err)
+			}
+			continue
+		}
+
+		co = mirrorForFollower(ctx, co, sub, leader, record.Paper, r, origQty)
+		update := bson.M{"$set": bson.M{
+			"quantity":     co.Quantity,
+			"order_id":     co.OrderID,
+			"executed_qty": co.ExecutedQty,
+			"quote_qty":    co.QuoteQty,
+			"avg_price":    co.AvgPrice,
+			"status":       co.Status,
+			"reason":       co.Reason,
+		}}
+		if err := repository.IRepo.UpdateOne(ctx, "copy_orders", bson.M{"_id": co.ID}, update, false); err != nil {
+			log.Printf("❌ Copy: failed to record mirror for %s: %v", sub.ID.Hex(), err)
+		}
+		if co.OrderID != 0 {
+			update := bson.M{"$set": bson.M{"parent_id": co.ID.Hex()}}
+			repository.IRepo.UpdateOne(ctx, "orders", bson.M{"order_id": co.OrderID}, update, false)
+		}
+	}
+}
+
+// mirrorForFollower places the follower's copy of a leader fill, claimed as
+// co. Fills of simulated (paper) leader orders are only mirrored onto paper
+// followers.
+func mirrorForFollower(ctx context.Context, co models.CopyOrder, sub models.CopySubscription, leader models.CopyLeader, leaderPaper bool, r ExecutionReport, origQty float64) models.CopyOrder {
+	lastQty, lastPrice := co.SourceQty, co.SourcePrice
+	skip := func(reason string) models.CopyOrder {
+		co.Status, co.Reason = models.CopyOrderSkipped, reason
+		return co
+	}
+
+	if len(sub.Symbols) > 0 && !contains(sub.Symbols, r.Symbol) {
+		return skip("symbol not in follower allow-list")
+	}
+	if leaderPaper {
+		paper, err := IsPaperUser(ctx, sub.FollowerID)
+		if err != nil {
+			co.Status, co.Reason = models.CopyOrderFailed, err.Error()
+			return co
+		}
+		if !paper {
+			return skip("leader order is paper trading")
+		}
+	}
+	info, err := GetSymbolInfo(r.Symbol)
+	if err != nil {
+		co.Status, co.Reason = models.CopyOrderFailed, err.Error()
+		return co
+	}
+
+	var qty float64
+	switch sub.Mode {
+	case models.CopyModeFixedRatio:
+		qty = lastQty * sub.Ratio
+	case models.CopyModeFixedNotional:
+		share := 1.0
+		if origQty > 0 {
+			share = lastQty / origQty
+		}
+		qty = sub.Notional * share / lastPrice
+	case models.CopyModeEquity:
+		followerEq, ferr := userEquity(ctx, sub.FollowerID)
+		leaderEq, lerr := userEquity(ctx, leader.UserID)
+		if errors.Is(ferr, ErrBalanceNotTracked) || errors.Is(lerr, ErrBalanceNotTracked) {
+			co.Status, co.Reason = models.CopyOrderFailed, "equity sizing needs both accounts to paper trade"
+			return co
+		}
+		if ferr != nil || lerr != nil || leaderEq <= 0 {
+			co.Status, co.Reason = models.CopyOrderFailed, "could not value accounts for equity sizing"
+			return co
+		}
+		qty = lastQty * followerEq / leaderEq
+	}
+
+	if sub.MaxOrderNotional > 0 && qty*lastPrice > sub.MaxOrderNotional {
+		qty = sub.MaxOrderNotional / lastPrice
+	}
+	if sub.MaxDailyNotional > 0 {
+		used := copyNotionalToday(ctx, sub.ID)
+		if used+qty*lastPrice > sub.MaxDailyNotional {
+			return skip(fmt.Sprintf("daily limit reached: %.2f of %.2f used", used, sub.MaxDailyNotional))
+		}
+	}
+
+	if r.Side == "BUY" {
+		free, err := GetUserFreeBalance(ctx, sub.FollowerID, info.QuoteAsset)
+		if err != nil && !errors.Is(err, ErrBalanceNotTracked) {
+			co.Status, co.Reason = models.CopyOrderFailed, err.Error()
+			return co
+		}
+		if err == nil && free < qty*lastPrice {
+			return skip(fmt.Sprintf("insufficient %s: have %.8f, need %.8f", info.QuoteAsset, free, qty*lastPrice))
+		}
+	} else {
+		// Followers can only sell what they hold.
+		free, err := GetUserFreeBalance(ctx, sub.FollowerID, info.BaseAsset)
+		if err != nil && !errors.Is(err, ErrBalanceNotTracked) {
+			co.Status, co.Reason = models.CopyOrderFailed, err.Error()
+			return co
+		}
+		if err == nil {
+			qty = math.Min(qty, free)
+		}
+	}
+
+	co.Quantity = info.FormatQuantity(qty)
+	q, _ := strconv.ParseFloat(co.Quantity, 64)
+	if q <= 0 || q < info.MinQty || (info.MinNotional > 0 && q*lastPrice < info.MinNotional) {
+		return skip("mirrored size is below the exchange minimum")
+	}
+
+	if err := authorizeOwnerOrder(ctx, sub.FollowerID); err != nil {
+		co.Status, co.Reason = models.CopyOrderFailed, err.Error()
+		return co
+	}
+	order, err := placeMarketOrder(ctx, r.Symbol, r.Side, co.Quantity, copyClientPrefix+co.ID.Hex(), sub.FollowerID)
+	if err != nil {
+		co.Status, co.Reason = models.CopyOrderFailed, err.Error()
+		return co
+	}
+
+	co.Status = models.CopyOrderPlaced
+	co.OrderID = order.OrderID
+	co.ExecutedQty, _ = strconv.ParseFloat(order.ExecutedQty, 64)
+	co.QuoteQty, _ = strconv.ParseFloat(order.CummulativeQuoteQty, 64)
+	if co.ExecutedQty > 0 {
+		co.AvgPrice = co.QuoteQty / co.ExecutedQty
+	}
+	return co
+}
+
+func copyNotionalToday(ctx context.Context, subID primitive.ObjectID) float64 {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	filter := bson.M{"subscription_id": subID, "status": models.CopyOrderPlaced, "created_at": bson.M{"$gte": day}}
+	orders, err := repository.Find[models.CopyOrder](ctx, "copy_orders", filter, nil)
+	if err != nil {
+		return 0
+	}
+	total := 0.0
+	for _, o := range orders {
+		total += o.QuoteQty
+	}
+	return total
+}
+
+// userEquity values a paper user's balances in USDT. Live users have no
+// balances of their own, so it returns ErrBalanceNotTracked for them.
+// Results are cached for a minute.
+func userEquity(ctx context.Context, userID string) (float64, error) {
+	if v, ok := copyEquity.Load(userID); ok {
+		c := v.(cachedPrice)
+		if time.Since(c.updatedAt) < copyEquityTTL {
+			return c.price, nil
+		}
+	}
+
+	paper, err := IsPaperUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !paper {
+		return 0, ErrBalanceNotTracked
+	}
+	acct, err := PaperServices{}.Account(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	holdings := make(map[string]float64)
+	for asset, b := range acct.Balances {
+		holdings[asset] = b.Free + b.Locked
+	}
+
+	total := 0.0
+	for asset, amount := range holdings {
+		if amount <= 0 {
+			continue
+		}
+		if asset == "USDT" {
+			total += amount
+			continue
+		}
+		if price, err := MarketData.Price(asset + "USDT"); err == nil {
+			total += amount * price
+		}
+	}
+	copyEquity.Store(userID, cachedPrice{price: total, updatedAt: time.Now()})
+	return total, nil
+}
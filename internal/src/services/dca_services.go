@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/validator"
+)
+
+const dcaPollInterval = 30 * time.Second
+
+type DCAServices struct{}
+
+// dcaCronExpr turns a plan request into a standard 5-field cron expression in UTC.
+func dcaCronExpr(req models.DCAPlanRequest) (string, error) {
+	switch strings.ToLower(req.Schedule) {
+	case models.DCAScheduleDaily, models.DCAScheduleWeekly:
+		hh, mm := 0, 0
+		if req.Time != "" {
+			t, err := time.Parse("15:04", req.Time)
+			if err != nil {
+				return "", errors.New("time must be HH:MM")
+			}
+			hh, mm = t.Hour(), t.Minute()
+		}
+		if strings.ToLower(req.Schedule) == models.DCAScheduleDaily {
+			return fmt.Sprintf("%d %d * * *", mm, hh), nil
+		}
+		if req.Weekday < 0 || req.Weekday > 6 {
+			return "", errors.New("weekday must be between 0 (Sunday) and 6")
+		}
+		return fmt.Sprintf("%d %d * * %d", mm, hh, req.Weekday), nil
+	case models.DCAScheduleCron:
+		if req.Cron == "" {
+			return "", errors.New("cron expression is required")
+		}
+		return req.Cron, nil
+	default:
+		return "", fmt.Errorf("schedule must be daily, weekly or cron, got %q", req.Schedule)
+	}
+}
+
+func dcaNextRun(expr string, after time.Time) (time.Time, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression: %v", err)
+	}
+	return sched.Next(after.UTC()), nil
+}
+
+func (d DCAServices) Create(ctx context.Context, userID string, req models.DCAPlanRequest) (models.DCAPlan, error) {
+	if err := validator.Validate(&req); err != nil {
+		return models.DCAPlan{}, err
+	}
+	if _, err := parsePositive("quote_amount", req.QuoteAmount); err != nil {
+		return models.DCAPlan{}, err
+	}
+	expr, err := dcaCronExpr(req)
+	if err != nil {
+		return models.DCAPlan{}, err
+	}
+	next, err := dcaNextRun(expr, time.Now())
+	if err != nil {
+		return models.DCAPlan{}, err
+	}
+
+	symbol := strings.ToUpper(req.Symbol)
+	if _, err := GetSymbolInfo(symbol); err != nil {
+		return models.DCAPlan{}, err
+	}
+
+	now := time.Now()
+	plan := models.DCAPlan{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Symbol:      symbol,
+		QuoteAmount: req.QuoteAmount,
+		Schedule:    strings.ToLower(req.Schedule),
+		CronExpr:    expr,
+		Status:      models.DCAStatusActive,
+		NextRunAt:   next,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := repository.IRepo.Insert(ctx, "dca_plans", &plan); err != nil {
+		return models.DCAPlan{}, err
+	}
+	return plan, nil
+}
+
+func (d DCAServices) List(ctx context.Context, userID string) ([]models.DCAPlan, error) {
+	plans, err := repository.Find[models.DCAPlan](ctx, "dca_plans", bson.M{"user_id": userID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		plans[i].AverageCost = dcaAverageCost(plans[i])
+	}
+	return plans, nil
+}
+
+func (d DCAServices) Get(ctx context.Context, userID, planID string) (models.DCAPlan, error) {
+	id, err := primitive.ObjectIDFromHex(planID)
+	if err != nil {
+		return models.DCAPlan{}, errors.New("invalid plan id")
+	}
+	plan, err := repository.FindOne[models.DCAPlan](ctx, "dca_plans", bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return models.DCAPlan{}, err
+	}
+	plan.AverageCost = dcaAverageCost(plan)
+	return plan, nil
+}
+
+func (d DCAServices) Runs(ctx context.Context, userID, planID string, page, limit int) ([]models.DCARun, int64, error) {
+	plan, err := d.Get(ctx, userID, planID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := bson.M{"plan_id": plan.ID}
+	filter := models.Filter{
+		Sort:      "executed_at",
+		SortOrder: -1,
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	}
+
+	runs, err := repository.FindPage[models.DCARun](ctx, "dca_runs", query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := repository.IRepo.Count(ctx, "dca_runs", query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+// SetStatus pauses or resumes a plan. Resuming schedules the next run from now
+// so that runs missed while paused are not executed in a burst.
+func (d DCAServices) SetStatus(ctx context.Context, userID, planID, status string) (models.DCAPlan, error) {
+	plan, err := d.Get(ctx, userID, planID)
+	if err != nil {
+		return plan, err
+	}
+
+	set := bson.M{"status": status, "updated_at": time.Now()}
+	if status == models.DCAStatusActive {
+		next, err := dcaNextRun(plan.CronExpr, time.Now())
+		if err != nil {
+			return plan, err
+		}
+		set["next_run_at"] = next
+		plan.NextRunAt = next
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "dca_plans", bson.M{"_id": plan.ID}, bson.M{"$set": set}, false); err != nil {
+		return plan, err
+	}
+	plan.Status = status
+	return plan, nil
+}
+
+func (d DCAServices) Delete(ctx context.Context, userID, planID string) error {
+	id, err := primitive.ObjectIDFromHex(planID)
+	if err != nil {
+		return errors.New("invalid plan id")
+	}
+	return repository.IRepo.RemoveByFilter(ctx, "dca_plans", bson.M{"_id": id, "user_id": userID})
+}
+
+func dcaAverageCost(plan models.DCAPlan) float64 {
+	if plan.TotalBaseBought == 0 {
+		return 0
+	}
+	return plan.TotalQuoteSpent / plan.TotalBaseBought
+}
+
+// StartDCAScheduler executes due plans until the workers are stopped. It
+// only runs on the instance with cron.is_runner set so that plans are not
+// executed twice.
+func StartDCAScheduler() {
+	goWorker(func(ctx context.Context) {
+		log.Println("⏰ DCA scheduler started")
+		ticker := time.NewTicker(dcaPollInterval)
+		defer ticker.Stop()
+
+		for {
+			runDueDCAPlans(ctx, time.Now())
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func runDueDCAPlans(ctx context.Context, now time.Time) {
+	filter := bson.M{
+		"status":      models.DCAStatusActive,
+		"next_run_at": bson.M{"$lte": now},
+	}
+	plans, err := repository.Find[models.DCAPlan](ctx, "dca_plans", filter, nil)
+	if err != nil {
+		log.Printf("❌ DCA scheduler: failed to load due plans: %v", err)
+		return
+	}
+
+	for _, plan := range plans {
+		run := executeDCAPlan(ctx, plan)
+		if err := repository.IRepo.Insert(ctx, "dca_runs", &run); err != nil {
+			log.Printf("❌ DCA scheduler: failed to record run for plan %s: %v", plan.ID.Hex(), err)
+		}
+
+		// Missed slots (e.g. during downtime) are collapsed into this run.
+		next, err := dcaNextRun(plan.CronExpr, now)
+		if err != nil {
+			log.Printf("❌ DCA scheduler: plan %s has invalid schedule, pausing: %v", plan.ID.Hex(), err)
+			repository.IRepo.UpdateOne(ctx, "dca_plans", bson.M{"_id": plan.ID}, bson.M{"$set": bson.M{"status": models.DCAStatusPaused}}, false)
+			continue
+		}
+
+		executedAt := run.ExecutedAt
+		set := bson.M{"next_run_at": next, "last_run_at": executedAt, "updated_at": time.Now()}
+		inc := bson.M{"run_count": 1}
+		switch run.Status {
+		case models.DCARunFilled:
+			inc["total_quote_spent"] = run.QuoteSpent
+			inc["total_base_bought"] = run.BaseQty
+		case models.DCARunSkipped:
+			inc["skipped_count"] = 1
+		default:
+			inc["failed_count"] = 1
+		}
+		if err := repository.IRepo.UpdateOne(ctx, "dca_plans", bson.M{"_id": plan.ID}, bson.M{"$set": set, "$inc": inc}, false); err != nil {
+			log.Printf("❌ DCA scheduler: failed to update plan %s: %v", plan.ID.Hex(), err)
+		}
+	}
+}
+
+// executeDCAPlan places the market buy for one run, skipping it when the
+// quote balance cannot cover the amount.
+func executeDCAPlan(ctx context.Context, plan models.DCAPlan) models.DCARun {
+	run := models.DCARun{
+		PlanID:      plan.ID,
+		UserID:      plan.UserID,
+		Symbol:      plan.Symbol,
+		ScheduledAt: plan.NextRunAt,
+		ExecutedAt:  time.Now(),
+	}
+
+	amount, _ := strconv.ParseFloat(plan.QuoteAmount, 64)
+
+	info, err := GetSymbolInfo(plan.Symbol)
+	if err != nil {
+		run.Status = models.DCARunFailed
+		run.Reason = err.Error()
+		return run
+	}
+
+	if err := authorizeOwnerOrder(ctx, plan.UserID); err != nil {
+		run.Status = models.DCARunFailed
+		run.Reason = err.Error()
+		return run
+	}
+
+	// Only paper accounts have a balance of their own to check; live
+	// orders are refused by the exchange when funds are short, and that
+	// refusal skips the run the same way.
+	free, err := GetUserFreeBalance(ctx, plan.UserID, info.QuoteAsset)
+	if err != nil && !errors.Is(err, ErrBalanceNotTracked) {
+		run.Status = models.DCARunFailed
+		run.Reason = fmt.Sprintf("balance check failed: %v", err)
+		return run
+	}
+	if err == nil && free < amount {
+		run.Status = models.DCARunSkipped
+		run.Reason = fmt.Sprintf("insufficient %s balance: have %.8f, need %s", info.QuoteAsset, free, plan.QuoteAmount)
+		log.Printf("⏭️  DCA plan %s skipped: %s", plan.ID.Hex(), run.Reason)
+		return run
+	}
+
+	order, err := PlaceMarketQuoteOrder(ctx, plan.Symbol, "BUY", plan.QuoteAmount, plan.UserID)
+	if errors.Is(err, ErrInsufficientBalance) || errors.Is(err, ErrPaperInsufficientBalance) {
+		run.Status = models.DCARunSkipped
+		run.Reason = err.Error()
+		log.Printf("⏭️  DCA plan %s skipped: %s", plan.ID.Hex(), run.Reason)
+		return run
+	}
+	if err != nil {
+		run.Status = models.DCARunFailed
+		run.Reason = err.Error()
+		return run
+	}
+
+	run.OrderID = order.OrderID
+	run.QuoteSpent, _ = strconv.ParseFloat(order.CummulativeQuoteQty, 64)
+	run.BaseQty, _ = strconv.ParseFloat(order.ExecutedQty, 64)
+	if run.BaseQty == 0 {
+		run.Status = models.DCARunFailed
+		run.Reason = fmt.Sprintf("order %d not filled, status %s", order.OrderID, order.Status)
+		return run
+	}
+	run.Status = models.DCARunFilled
+	run.FillPrice = run.QuoteSpent / run.BaseQty
+	log.Printf("✅ DCA plan %s bought %.8f %s at %.8f", plan.ID.Hex(), run.BaseQty, plan.Symbol, run.FillPrice)
+	return run
+}
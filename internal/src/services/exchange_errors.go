@@ -0,0 +1,89 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of exchange failure callers can test for with errors.Is.
+var (
+	ErrInsufficientBalance    = errors.New("insufficient balance")
+	ErrFilterFailure          = errors.New("order violates a symbol filter")
+	ErrUnknownOrder           = errors.New("unknown order")
+	ErrTimestampOutsideWindow = errors.New("request timestamp outside of the exchange recvWindow")
+	ErrInvalidExchangeRequest = errors.New("invalid request to exchange")
+	ErrExchangeRejected       = errors.New("exchange rejected the request")
+	ErrExchangeUnavailable    = errors.New("exchange unavailable")
+)
+
+// ExchangeError is a failed exchange call with the exchange's own code and
+// message. It unwraps to one of the kinds above.
+type ExchangeError struct {
+	Kind       error
+	Status     int    // HTTP status from the exchange
+	ExchCode   int    // Binance error code, e.g. -2010
+	ExchMsg    string // Binance message
+	statusCode int
+	errorCode  string
+}
+
+func (e *ExchangeError) Error() string {
+	if e.ExchMsg == "" {
+		return e.Kind.Error()
+	}
+	return fmt.Sprintf("%v: %s (%d)", e.Kind, e.ExchMsg, e.ExchCode)
+}
+
+func (e *ExchangeError) Unwrap() error { return e.Kind }
+
+// HTTPStatus is the status our API answers with.
+func (e *ExchangeError) HTTPStatus() int { return e.statusCode }
+
+// ErrorCode is the stable code clients can switch on.
+func (e *ExchangeError) ErrorCode() string { return e.errorCode }
+
+func (e *RateLimitError) HTTPStatus() int { return http.StatusTooManyRequests }
+
+func (e *RateLimitError) ErrorCode() string { return "RATE_LIMITED" }
+
+// parseExchangeError turns a non-2xx exchange response into an error.
+func parseExchangeError(status int, body []byte) error {
+	var apiErr struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	json.Unmarshal(body, &apiErr)
+	if apiErr.Msg == "" && apiErr.Code == 0 {
+		apiErr.Msg = strings.TrimSpace(string(body))
+	}
+
+	e := &ExchangeError{Status: status, ExchCode: apiErr.Code, ExchMsg: apiErr.Msg}
+	msg := strings.ToLower(apiErr.Msg)
+
+	switch {
+	case status == http.StatusTooManyRequests || status == http.StatusTeapot ||
+		apiErr.Code == -1003 || apiErr.Code == -1015:
+		e.Kind, e.statusCode, e.errorCode = ErrExchangeRateLimited, http.StatusTooManyRequests, "RATE_LIMITED"
+	case apiErr.Code == -1021:
+		e.Kind, e.statusCode, e.errorCode = ErrTimestampOutsideWindow, http.StatusServiceUnavailable, "TIMESTAMP_OUT_OF_WINDOW"
+	case strings.Contains(msg, "insufficient balance"):
+		e.Kind, e.statusCode, e.errorCode = ErrInsufficientBalance, http.StatusUnprocessableEntity, "INSUFFICIENT_BALANCE"
+	case strings.HasPrefix(msg, "filter failure"):
+		e.Kind, e.statusCode, e.errorCode = ErrFilterFailure, http.StatusUnprocessableEntity, "FILTER_FAILURE"
+	case apiErr.Code == -2013 || (apiErr.Code == -2011 && strings.Contains(msg, "unknown order")):
+		e.Kind, e.statusCode, e.errorCode = ErrUnknownOrder, http.StatusNotFound, "UNKNOWN_ORDER"
+	case status >= 500:
+		e.Kind, e.statusCode, e.errorCode = ErrExchangeUnavailable, http.StatusBadGateway, "EXCHANGE_UNAVAILABLE"
+	case apiErr.Code <= -1100 && apiErr.Code >= -1199:
+		e.Kind, e.statusCode, e.errorCode = ErrInvalidExchangeRequest, http.StatusBadRequest, "INVALID_REQUEST"
+	case apiErr.Code == -2014 || apiErr.Code == -2015 || status == http.StatusUnauthorized:
+		// Our exchange credentials were refused, nothing the caller can fix.
+		e.Kind, e.statusCode, e.errorCode = ErrExchangeUnavailable, http.StatusBadGateway, "EXCHANGE_UNAVAILABLE"
+	default:
+		e.Kind, e.statusCode, e.errorCode = ErrExchangeRejected, http.StatusUnprocessableEntity, "EXCHANGE_REJECTED"
+	}
+	return e
+}
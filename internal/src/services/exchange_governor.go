@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"exdex/config"
+	"exdex/server/httpclient"
+)
+
+// Binance spot defaults, overridable under binance.limits.
+const (
+	defaultWeightPerMinute = 6000
+	defaultOrdersPer10s    = 100
+	defaultOrdersPerDay    = 200000
+	defaultGovernorWait    = 2 * time.Second
+	default429Backoff      = time.Minute
+	default418Backoff      = 2 * time.Minute
+)
+
+var ErrExchangeRateLimited = errors.New("exchange rate limit reached, try again shortly")
+
+// RateLimitError is returned when the governor refuses a call. RetryAfter is
+// how long until the call could go through.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Reason     string
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: %s, retry in %s", ErrExchangeRateLimited, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error { return ErrExchangeRateLimited }
+
+// exchangeGovernor keeps calls to one exchange host under its limits. It
+// counts weight and orders locally as calls are made and corrects the counts
+// from X-MBX-USED-WEIGHT-1M and X-MBX-ORDER-COUNT-* on every response. Market
+// data may only use part of the weight so it can never starve order flow.
+type exchangeGovernor struct {
+	mu sync.Mutex
+
+	weightMinute int64 // unix minute the weight count belongs to
+	usedWeight   int
+	orders10s    int64 // 10 second window the count belongs to
+	usedOrders   int
+	ordersDay    int64
+	usedDaily    int
+	bannedUntil  time.Time
+	banReason    string
+}
+
+var (
+	governorsMu sync.Mutex
+	governors   = make(map[string]*exchangeGovernor)
+)
+
+func governorFor(host string) *exchangeGovernor {
+	governorsMu.Lock()
+	defer governorsMu.Unlock()
+	g, ok := governors[host]
+	if !ok {
+		g = &exchangeGovernor{}
+		governors[host] = g
+	}
+	return g
+}
+
+func governorLimit(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// endpointWeight is the request weight Binance charges for an endpoint.
+func endpointWeight(method, path string, query string) int {
+	hasSymbol := strings.Contains(query, "symbol=")
+	switch path {
+	case "/api/v3/account", "/api/v3/myTrades", "/api/v3/allOrders", "/api/v3/exchangeInfo":
+		return 20
+	case "/api/v3/openOrders":
+		if hasSymbol {
+			return 6
+		}
+		return 80
+	case "/api/v3/ticker/price", "/api/v3/ticker/bookTicker":
+		if hasSymbol {
+			return 2
+		}
+		return 4
+	case "/api/v3/klines", "/api/v3/userDataStream":
+		return 2
+	case "/api/v3/depth":
+		return 5
+	case "/api/v3/order":
+		if method == http.MethodGet {
+			return 4
+		}
+	}
+	return 1
+}
+
+func isOrderEndpoint(method, path string) bool {
+	return method == http.MethodPost && strings.HasPrefix(path, "/api/v3/order")
+}
+
+// acquire reserves weight for a call, waiting up to binance.limits.max_wait
+// for the window to roll over before refusing.
+func (g *exchangeGovernor) acquire(ctx context.Context, weight int, order bool) error {
+	maxWait := config.C.Binance.Limits.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultGovernorWait
+	}
+	deadline := time.Now().Add(maxWait)
+
+	for {
+		wait, reason := g.tryAcquire(weight, order)
+		if wait == 0 {
+			return nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return &RateLimitError{RetryAfter: wait, Reason: reason}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (g *exchangeGovernor) tryAcquire(weight int, order bool) (time.Duration, string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(g.bannedUntil) {
+		return g.bannedUntil.Sub(now), g.banReason
+	}
+
+	minute := now.Unix() / 60
+	if minute != g.weightMinute {
+		g.weightMinute, g.usedWeight = minute, 0
+	}
+	window := now.Unix() / 10
+	if window != g.orders10s {
+		g.orders10s, g.usedOrders = window, 0
+	}
+	day := now.UTC().Unix() / 86400
+	if day != g.ordersDay {
+		g.ordersDay, g.usedDaily = day, 0
+	}
+
+	// Orders may use up to 95% of the weight, everything else 80%.
+	share := 80
+	if order {
+		share = 95
+	}
+	budget := governorLimit(config.C.Binance.Limits.WeightPerMinute, defaultWeightPerMinute) * share / 100
+	if g.usedWeight+weight > budget {
+		return time.Unix((minute+1)*60, 0).Sub(now), "request weight budget used"
+	}
+	if order {
+		if g.usedOrders+1 > governorLimit(config.C.Binance.Limits.OrdersPer10s, defaultOrdersPer10s)*95/100 {
+			return time.Unix((window+1)*10, 0).Sub(now), "order rate budget used"
+		}
+		if g.usedDaily+1 > governorLimit(config.C.Binance.Limits.OrdersPerDay, defaultOrdersPerDay)*95/100 {
+			return time.Unix((day+1)*86400, 0).Sub(now), "daily order budget used"
+		}
+		g.usedOrders++
+		g.usedDaily++
+	}
+	g.usedWeight += weight
+	return 0, ""
+}
+
+// observe corrects the counters from the exchange's view and backs off on
+// 429 (rate limited) and 418 (IP banned) as long as Retry-After says.
+func (g *exchangeGovernor) observe(host string, status int, header http.Header) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if v, err := strconv.Atoi(header.Get("X-MBX-USED-WEIGHT-1M")); err == nil {
+		if minute := now.Unix() / 60; minute == g.weightMinute && v > g.usedWeight {
+			g.usedWeight = v
+		}
+	}
+	if v, err := strconv.Atoi(header.Get("X-MBX-ORDER-COUNT-10S")); err == nil && v > g.usedOrders {
+		g.usedOrders = v
+	}
+	if v, err := strconv.Atoi(header.Get("X-MBX-ORDER-COUNT-1D")); err == nil && v > g.usedDaily {
+		g.usedDaily = v
+	}
+
+	if status != http.StatusTooManyRequests && status != http.StatusTeapot {
+		return
+	}
+	backoff := default429Backoff
+	reason := "exchange returned 429"
+	if status == http.StatusTeapot {
+		backoff = default418Backoff
+		reason = "exchange banned this IP (418)"
+	}
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs > 0 {
+		backoff = time.Duration(secs) * time.Second
+	}
+	if until := now.Add(backoff); until.After(g.bannedUntil) {
+		g.bannedUntil, g.banReason = until, reason
+	}
+	log.Printf("🚫 %s for %s, pausing calls for %s", reason, host, backoff)
+}
+
+func (g *exchangeGovernor) backoffRemaining() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return time.Until(g.bannedUntil)
+}
+
+// ExchangeLimitStatus is the governor's view of one exchange host.
+type ExchangeLimitStatus struct {
+	Host          string     `json:"host"`
+	UsedWeight1m  int        `json:"used_weight_1m"`
+	WeightLimit1m int        `json:"weight_limit_1m"`
+	OrderCount10s int        `json:"order_count_10s"`
+	OrderCount1d  int        `json:"order_count_1d"`
+	BackoffUntil  *time.Time `json:"backoff_until,omitempty"`
+	BackoffReason string     `json:"backoff_reason,omitempty"`
+}
+
+func ExchangeLimits() []ExchangeLimitStatus {
+	governorsMu.Lock()
+	defer governorsMu.Unlock()
+
+	out := make([]ExchangeLimitStatus, 0, len(governors))
+	now := time.Now()
+	for host, g := range governors {
+		g.mu.Lock()
+		s := ExchangeLimitStatus{Host: host, WeightLimit1m: governorLimit(config.C.Binance.Limits.WeightPerMinute, defaultWeightPerMinute)}
+		if g.weightMinute == now.Unix()/60 {
+			s.UsedWeight1m = g.usedWeight
+		}
+		if g.orders10s == now.Unix()/10 {
+			s.OrderCount10s = g.usedOrders
+		}
+		s.OrderCount1d = g.usedDaily
+		if now.Before(g.bannedUntil) {
+			until := g.bannedUntil
+			s.BackoffUntil, s.BackoffReason = &until, g.banReason
+		}
+		g.mu.Unlock()
+		out = append(out, s)
+	}
+	return out
+}
+
+// doExchangeRequest sends a request to the exchange through the governor
+// and the shared client, and returns the response status and body.
+func doExchangeRequest(ctx context.Context, req *http.Request) (status int, body []byte, err error) {
+	start := time.Now()
+	defer func() { observeExchangeRequest(req.URL.Path, start, status, body, err) }()
+
+	host := req.URL.Host
+	g := governorFor(host)
+	weight := endpointWeight(req.Method, req.URL.Path, req.URL.RawQuery)
+	if err := g.acquire(ctx, weight, isOrderEndpoint(req.Method, req.URL.Path)); err != nil {
+		return 0, nil, err
+	}
+
+	resp, err := httpclient.Do(ctx, "binance "+host, req)
+	if err != nil {
+		return 0, nil, err
+	}
+	g.observe(host, resp.StatusCode, resp.Header)
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot {
+		return resp.StatusCode, resp.Body, &RateLimitError{RetryAfter: g.backoffRemaining(), Reason: string(resp.Body)}
+	}
+	return resp.StatusCode, resp.Body, nil
+}
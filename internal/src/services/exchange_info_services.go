@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"exdex/config"
+)
+
+const symbolInfoTTL = time.Hour
+
+// SymbolInfo holds the parts of /api/v3/exchangeInfo we need to build valid orders.
+type SymbolInfo struct {
+	Symbol      string
+	BaseAsset   string
+	QuoteAsset  string
+	TickSize    float64
+	StepSize    float64
+	MinQty      float64
+	MinNotional float64
+	fetchedAt   time.Time
+}
+
+var (
+	symbolInfoMu    sync.Mutex
+	symbolInfoCache = make(map[string]SymbolInfo)
+)
+
+// GetSymbolInfo returns the trading rules of a symbol, cached for an hour.
+func GetSymbolInfo(symbol string) (SymbolInfo, error) {
+	symbol = strings.ToUpper(symbol)
+
+	symbolInfoMu.Lock()
+	info, ok := symbolInfoCache[symbol]
+	symbolInfoMu.Unlock()
+	if ok && time.Since(info.fetchedAt) < symbolInfoTTL {
+		return info, nil
+	}
+
+	url := fmt.Sprintf("%s/api/v3/exchangeInfo?symbol=%s", config.C.Binance.BaseURL, symbol)
+	body, err := sendRequest(context.Background(), "GET", url)
+	if err != nil {
+		return SymbolInfo{}, err
+	}
+
+	var res struct {
+		Symbols []struct {
+			Symbol     string `json:"symbol"`
+			BaseAsset  string `json:"baseAsset"`
+			QuoteAsset string `json:"quoteAsset"`
+			Filters    []struct {
+				FilterType  string `json:"filterType"`
+				TickSize    string `json:"tickSize"`
+				StepSize    string `json:"stepSize"`
+				MinQty      string `json:"minQty"`
+				MinNotional string `json:"minNotional"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return SymbolInfo{}, err
+	}
+	if len(res.Symbols) == 0 {
+		return SymbolInfo{}, fmt.Errorf("unknown symbol %s", symbol)
+	}
+
+	s := res.Symbols[0]
+	info = SymbolInfo{
+		Symbol:     s.Symbol,
+		BaseAsset:  s.BaseAsset,
+		QuoteAsset: s.QuoteAsset,
+		fetchedAt:  time.Now(),
+	}
+	for _, f := range s.Filters {
+		switch f.FilterType {
+		case "PRICE_FILTER":
+			info.TickSize, _ = strconv.ParseFloat(f.TickSize, 64)
+		case "LOT_SIZE":
+			info.StepSize, _ = strconv.ParseFloat(f.StepSize, 64)
+			info.MinQty, _ = strconv.ParseFloat(f.MinQty, 64)
+		case "NOTIONAL", "MIN_NOTIONAL":
+			info.MinNotional, _ = strconv.ParseFloat(f.MinNotional, 64)
+		}
+	}
+
+	symbolInfoMu.Lock()
+	symbolInfoCache[symbol] = info
+	symbolInfoMu.Unlock()
+	return info, nil
+}
+
+// FormatQuantity rounds qty down to the symbol's step size.
+func (s SymbolInfo) FormatQuantity(qty float64) string {
+	return formatToIncrement(qty, s.StepSize, math.Floor)
+}
+
+// FormatPrice rounds price to the nearest tick.
+func (s SymbolInfo) FormatPrice(price float64) string {
+	return formatToIncrement(price, s.TickSize, math.Round)
+}
+
+func formatToIncrement(v, inc float64, round func(float64) float64) string {
+	if inc <= 0 {
+		return strconv.FormatFloat(v, 'f', 8, 64)
+	}
+	decimals := 0
+	for x := inc; x < 1 && decimals < 8; x *= 10 {
+		decimals++
+	}
+	// Small epsilon so values like 0.3/0.1 don't floor to 2.
+	v = round(v/inc+1e-9) * inc
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
@@ -0,0 +1,539 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/validator"
+)
+
+const (
+	gridMonitorInterval = 10 * time.Second
+	// A level whose order still cannot be placed after this many attempts
+	// puts the bot in error.
+	gridPlaceAttempts = 5
+)
+
+type GridServices struct{}
+
+// gridLocks serialises fills, stop-loss checks and manual stops per bot.
+var gridLocks sync.Map
+
+func gridLock(id primitive.ObjectID) *sync.Mutex {
+	m, _ := gridLocks.LoadOrStore(id, &sync.Mutex{})
+	return m.(*sync.Mutex)
+}
+
+func gridLevelPrices(lower, upper float64, count int, spacing string) []float64 {
+	prices := make([]float64, count+1)
+	for i := 0; i <= count; i++ {
+		if spacing == models.GridSpacingGeometric {
+			prices[i] = lower * math.Pow(upper/lower, float64(i)/float64(count))
+		} else {
+			prices[i] = lower + float64(i)*(upper-lower)/float64(count)
+		}
+	}
+	return prices
+}
+
+func validateGridRequest(req *models.GridBotRequest) error {
+	if err := validator.Validate(req); err != nil {
+		return err
+	}
+	req.Symbol = strings.ToUpper(req.Symbol)
+	req.Spacing = strings.ToLower(req.Spacing)
+	if req.Spacing == "" {
+		req.Spacing = models.GridSpacingArithmetic
+	}
+	if req.Spacing != models.GridSpacingArithmetic && req.Spacing != models.GridSpacingGeometric {
+		return fmt.Errorf("spacing must be arithmetic or geometric, got %q", req.Spacing)
+	}
+	if req.LowerPrice >= req.UpperPrice {
+		return errors.New("lower_price must be below upper_price")
+	}
+	if req.StopLoss > 0 && req.StopLoss >= req.LowerPrice {
+		return errors.New("stop_loss must be below lower_price")
+	}
+	if req.TakeProfit > 0 && req.TakeProfit <= req.UpperPrice {
+		return errors.New("take_profit must be above upper_price")
+	}
+	return nil
+}
+
+// Create validates the configuration, buys the base asset needed for the sell
+// side of the ladder and places one limit order per level.
+func (g GridServices) Create(ctx context.Context, userID string, req models.GridBotRequest) (models.GridBot, error) {
+	if err := validateGridRequest(&req); err != nil {
+		return models.GridBot{}, err
+	}
+
+	info, err := GetSymbolInfo(req.Symbol)
+	if err != nil {
+		return models.GridBot{}, err
+	}
+	current := getPriceFloat(ctx, req.Symbol)
+	if current <= 0 {
+		return models.GridBot{}, fmt.Errorf("could not get price for %s", req.Symbol)
+	}
+	if current <= req.LowerPrice || current >= req.UpperPrice {
+		return models.GridBot{}, fmt.Errorf("current price %.8f is outside the grid range", current)
+	}
+
+	prices := gridLevelPrices(req.LowerPrice, req.UpperPrice, req.GridCount, req.Spacing)
+	qtyStr := info.FormatQuantity(req.Investment / float64(req.GridCount) / current)
+	qty, _ := strconv.ParseFloat(qtyStr, 64)
+	if qty <= 0 || qty < info.MinQty {
+		return models.GridBot{}, errors.New("investment is too small for this many grids")
+	}
+	if info.MinNotional > 0 && qty*req.LowerPrice < info.MinNotional {
+		return models.GridBot{}, fmt.Errorf("each grid order must be worth at least %.8f %s", info.MinNotional, info.QuoteAsset)
+	}
+
+	// The level nearest the current price stays empty; buys go below it and
+	// sells above it.
+	empty := 0
+	for i, p := range prices {
+		if math.Abs(p-current) < math.Abs(prices[empty]-current) {
+			empty = i
+		}
+	}
+
+	now := time.Now()
+	bot := models.GridBot{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		Symbol:     req.Symbol,
+		LowerPrice: req.LowerPrice,
+		UpperPrice: req.UpperPrice,
+		GridCount:  req.GridCount,
+		Investment: req.Investment,
+		Spacing:    req.Spacing,
+		StopLoss:   req.StopLoss,
+		TakeProfit: req.TakeProfit,
+		QtyPerGrid: qtyStr,
+		Status:     models.GridStatusRunning,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	for i, p := range prices {
+		bot.Levels = append(bot.Levels, models.GridLevel{Index: i, Price: info.FormatPrice(p)})
+	}
+
+	sellLevels := len(prices) - 1 - empty
+	if sellLevels > 0 {
+		baseQty := info.FormatQuantity(qty * float64(sellLevels))
+		order, err := PlaceMarketOrder(ctx, req.Symbol, "BUY", baseQty, userID)
+		if err != nil {
+			return models.GridBot{}, fmt.Errorf("initial base purchase failed: %v", err)
+		}
+		bought, _ := strconv.ParseFloat(order.ExecutedQty, 64)
+		if bought == 0 {
+			bought, _ = strconv.ParseFloat(baseQty, 64)
+		}
+		bot.BaseHeld = bought
+
+		entry := current
+		if quote, _ := strconv.ParseFloat(order.CummulativeQuoteQty, 64); quote > 0 && bought > 0 {
+			entry = quote / bought
+		}
+		for i := empty + 1; i < len(bot.Levels); i++ {
+			bot.Levels[i].EntryPrice = entry
+		}
+	}
+
+	if err := repository.IRepo.Insert(ctx, "grid_bots", &bot); err != nil {
+		return models.GridBot{}, err
+	}
+
+	mu := gridLock(bot.ID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range bot.Levels {
+		side := ""
+		if i < empty {
+			side = "BUY"
+		} else if i > empty {
+			side = "SELL"
+		}
+		if side == "" {
+			continue
+		}
+		if err := placeGridOrder(ctx, &bot, i, side); err != nil {
+			log.Printf("❌ Grid %s: failed to place %s at level %d: %v", bot.ID.Hex(), side, i, err)
+			stopGridLocked(ctx, &bot, fmt.Sprintf("failed to place ladder: %v", err), true)
+			return bot, err
+		}
+		// Save as we go so a level that fills immediately can be found by
+		// handleGridExecution once we release the lock.
+		if err := saveGridState(ctx, &bot); err != nil {
+			return bot, err
+		}
+	}
+	return bot, nil
+}
+
+func placeGridOrder(ctx context.Context, bot *models.GridBot, level int, side string) error {
+	if err := authorizeOwnerOrder(ctx, bot.UserID); err != nil {
+		return err
+	}
+	order, err := PlaceLimitOrder(ctx, bot.Symbol, side, bot.QtyPerGrid, bot.Levels[level].Price, bot.UserID)
+	if err != nil {
+		return err
+	}
+	if order.OrderID == 0 {
+		return fmt.Errorf("exchange did not accept %s order at %s", side, bot.Levels[level].Price)
+	}
+	bot.Levels[level].Side = side
+	bot.Levels[level].OrderID = order.OrderID
+	bot.Levels[level].PendingSide = ""
+	bot.Levels[level].Attempts = 0
+	return nil
+}
+
+// placeGridLevel places the order for a level, leaving it pending for the
+// monitor to retry when that fails. After gridPlaceAttempts failures the
+// bot is put in error, since a missing level breaks the ladder.
+func placeGridLevel(ctx context.Context, bot *models.GridBot, level int, side string) {
+	err := placeGridOrder(ctx, bot, level, side)
+	if err == nil {
+		return
+	}
+	lvl := &bot.Levels[level]
+	lvl.PendingSide = side
+	lvl.Attempts++
+	log.Printf("❌ Grid %s: failed to place %s at level %d (attempt %d): %v", bot.ID.Hex(), side, level, lvl.Attempts, err)
+	if lvl.Attempts >= gridPlaceAttempts {
+		reason := fmt.Sprintf("could not place %s at level %d after %d attempts: %v", side, level, lvl.Attempts, err)
+		haltGridLocked(ctx, bot, models.GridStatusError, reason, false)
+	}
+}
+
+func saveGridState(ctx context.Context, bot *models.GridBot) error {
+	_, err := saveGridStateWhere(ctx, bot, nil)
+	return err
+}
+
+// saveGridStateWhere saves the bot only if the stored one also matches
+// filter, and reports whether it did.
+func saveGridStateWhere(ctx context.Context, bot *models.GridBot, filter bson.M) (bool, error) {
+	bot.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"levels":           bot.Levels,
+		"base_held":        bot.BaseHeld,
+		"grid_profit":      bot.GridProfit,
+		"completed_cycles": bot.CompletedCycles,
+		"status":           bot.Status,
+		"stop_reason":      bot.StopReason,
+		"stopped_at":       bot.StoppedAt,
+		"updated_at":       bot.UpdatedAt,
+	}}
+	where := bson.M{"_id": bot.ID}
+	for k, v := range filter {
+		where[k] = v
+	}
+	return repository.IRepo.UpdateOneMatched(ctx, "grid_bots", where, update)
+}
+
+func (g GridServices) List(ctx context.Context, userID string) ([]models.GridBot, error) {
+	bots, err := repository.Find[models.GridBot](ctx, "grid_bots", bson.M{"user_id": userID}, nil)
+	return bots, err
+}
+
+func (g GridServices) Get(ctx context.Context, userID, botID string) (models.GridBot, error) {
+	id, err := primitive.ObjectIDFromHex(botID)
+	if err != nil {
+		return models.GridBot{}, errors.New("invalid bot id")
+	}
+	bot, err := repository.FindOne[models.GridBot](ctx, "grid_bots", bson.M{"_id": id, "user_id": userID})
+	return bot, err
+}
+
+// Stop cancels every order of the bot. With sellBase the base asset still
+// held by the bot is sold at market.
+func (g GridServices) Stop(ctx context.Context, userID, botID string, sellBase bool) (models.GridBot, error) {
+	bot, err := g.Get(ctx, userID, botID)
+	if err != nil {
+		return bot, err
+	}
+
+	mu := gridLock(bot.ID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Re-read under the lock, a fill may have changed the ladder.
+	if err := repository.IRepo.FindOneWhere(ctx, "grid_bots", bson.M{"_id": bot.ID}, &bot); err != nil {
+		return bot, err
+	}
+	if bot.Status != models.GridStatusRunning {
+		return bot, errors.New("bot is not running")
+	}
+	return bot, stopGridLocked(ctx, &bot, "stopped by user", sellBase)
+}
+
+func stopGridLocked(ctx context.Context, bot *models.GridBot, reason string, sellBase bool) error {
+	return haltGridLocked(ctx, bot, models.GridStatusStopped, reason, sellBase)
+}
+
+// haltGridLocked cancels the bot's orders and leaves it in status.
+func haltGridLocked(ctx context.Context, bot *models.GridBot, status, reason string, sellBase bool) error {
+	for i := range bot.Levels {
+		lvl := &bot.Levels[i]
+		lvl.PendingSide = ""
+		lvl.Attempts = 0
+		if lvl.OrderID == 0 {
+			continue
+		}
+		if err := CancelOrder(ctx, bot.Symbol, lvl.OrderID); err != nil {
+			log.Printf("⚠️  Grid %s: cancel of order %d failed: %v", bot.ID.Hex(), lvl.OrderID, err)
+		}
+		lvl.OrderID = 0
+		lvl.Side = ""
+	}
+
+	if sellBase && bot.BaseHeld > 0 {
+		if info, err := GetSymbolInfo(bot.Symbol); err == nil {
+			qty := info.FormatQuantity(bot.BaseHeld)
+			if _, err := PlaceMarketOrder(ctx, bot.Symbol, "SELL", qty, bot.UserID); err != nil {
+				log.Printf("❌ Grid %s: failed to sell remaining base: %v", bot.ID.Hex(), err)
+			} else {
+				bot.BaseHeld = 0
+			}
+		}
+	}
+
+	now := time.Now()
+	bot.Status = status
+	bot.StopReason = reason
+	bot.StoppedAt = &now
+	log.Printf("🛑 Grid %s stopped: %s", bot.ID.Hex(), reason)
+	return saveGridState(ctx, bot)
+}
+
+// handleGridExecution flips a filled level to the opposite order one level away.
+func handleGridExecution(report ExecutionReport) {
+	gridExecution(workerCtx, report)
+}
+
+func gridExecution(ctx context.Context, report ExecutionReport) {
+	if report.CurrentOrderStatus != "FILLED" {
+		return
+	}
+
+	filter := bson.M{"levels.order_id": report.OrderID, "status": models.GridStatusRunning}
+	bot, err := repository.FindOne[models.GridBot](ctx, "grid_bots", filter)
+	if err != nil {
+		return
+	}
+
+	mu := gridLock(bot.ID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := repository.IRepo.FindOneWhere(ctx, "grid_bots", bson.M{"_id": bot.ID}, &bot); err != nil || bot.Status != models.GridStatusRunning {
+		return
+	}
+
+	level := -1
+	for i, lvl := range bot.Levels {
+		if lvl.OrderID == report.OrderID {
+			level = i
+			break
+		}
+	}
+	if level < 0 {
+		return
+	}
+
+	filled := bot.Levels[level]
+	qty, _ := strconv.ParseFloat(report.CumulativeFilledQuantity, 64)
+	price, _ := strconv.ParseFloat(filled.Price, 64)
+	if quote, _ := strconv.ParseFloat(report.CumulativeQuoteQty, 64); quote > 0 && qty > 0 {
+		price = quote / qty
+	}
+	bot.Levels[level].OrderID = 0
+	bot.Levels[level].Side = ""
+	bot.Levels[level].EntryPrice = 0
+
+	var next int
+	var side string
+	var entry float64
+	if filled.Side == "BUY" {
+		bot.BaseHeld += qty
+		next, side, entry = level+1, "SELL", price
+	} else {
+		bot.BaseHeld -= qty
+		next, side = level-1, "BUY"
+		buyPrice := filled.EntryPrice
+		if buyPrice == 0 && next >= 0 {
+			// Bots created before entry prices were tracked.
+			buyPrice, _ = strconv.ParseFloat(bot.Levels[next].Price, 64)
+		}
+		bot.GridProfit += (price - buyPrice) * qty
+		bot.CompletedCycles++
+	}
+
+	flip := next >= 0 && next < len(bot.Levels) && bot.Levels[next].OrderID == 0
+	if flip {
+		bot.Levels[next].EntryPrice = entry
+		bot.Levels[next].PendingSide = side
+	}
+
+	// Claim the fill by saving its outcome only while the level still holds
+	// the order, so it is counted once even when the report and the
+	// monitor's catch-up both see it. The flipped order is saved as pending
+	// first, so the monitor places it if we stop before it is placed.
+	claim := bson.M{fmt.Sprintf("levels.%d.order_id", level): report.OrderID, "status": models.GridStatusRunning}
+	claimed, err := saveGridStateWhere(ctx, &bot, claim)
+	if err != nil {
+		log.Printf("❌ Grid %s: failed to claim the fill of order %d: %v", bot.ID.Hex(), report.OrderID, err)
+		return
+	}
+	if !claimed || !flip {
+		return
+	}
+	placeGridLevel(ctx, &bot, next, side)
+	if err := saveGridState(ctx, &bot); err != nil {
+		log.Printf("❌ Grid %s: failed to save state: %v", bot.ID.Hex(), err)
+	}
+}
+
+// StartGridMonitor wires grid bots to the user-data stream and checks their
+// stop-loss and take-profit prices. It only runs on the instance with
+// cron.is_runner set so that fills are not flipped twice.
+func StartGridMonitor() {
+	RegisterExecutionListener(handleGridExecution)
+
+	goWorker(func(ctx context.Context) {
+		ticker := time.NewTicker(gridMonitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				syncGridBots(ctx)
+				checkGridTriggers(ctx)
+			}
+		}
+	})
+}
+
+// syncGridBots catches up on what the execution listener missed: fills
+// whose report was dropped, read back from the stored orders, and levels
+// whose order is waiting to be placed again.
+func syncGridBots(ctx context.Context) {
+	bots, err := repository.Find[models.GridBot](ctx, "grid_bots", bson.M{"status": models.GridStatusRunning}, nil)
+	if err != nil {
+		log.Printf("❌ Grid monitor: failed to load bots: %v", err)
+		return
+	}
+	for _, bot := range bots {
+		var resting []int64
+		pending := false
+		for _, lvl := range bot.Levels {
+			if lvl.OrderID != 0 {
+				resting = append(resting, lvl.OrderID)
+			}
+			if lvl.PendingSide != "" {
+				pending = true
+			}
+		}
+
+		if len(resting) > 0 {
+			filter := bson.M{"order_id": bson.M{"$in": resting}, "status": bson.M{"$in": []string{"filled", "FILLED"}}}
+			filled, err := repository.Find[models.OrderRecord](ctx, "orders", filter, nil)
+			if err != nil {
+				log.Printf("❌ Grid %s: failed to load fills: %v", bot.ID.Hex(), err)
+			}
+			for _, o := range filled {
+				gridExecution(ctx, ExecutionReport{
+					OrderID:                  o.OrderID,
+					CurrentOrderStatus:       "FILLED",
+					CumulativeFilledQuantity: o.ExecutedQty,
+					CumulativeQuoteQty:       o.QuoteQty,
+				})
+			}
+		}
+		if pending {
+			retryGridLevels(ctx, bot.ID)
+		}
+	}
+}
+
+func retryGridLevels(ctx context.Context, id primitive.ObjectID) {
+	mu := gridLock(id)
+	mu.Lock()
+	defer mu.Unlock()
+
+	var bot models.GridBot
+	if err := repository.IRepo.FindOneWhere(ctx, "grid_bots", bson.M{"_id": id}, &bot); err != nil || bot.Status != models.GridStatusRunning {
+		return
+	}
+	for i := range bot.Levels {
+		if side := bot.Levels[i].PendingSide; side != "" && bot.Status == models.GridStatusRunning {
+			placeGridLevel(ctx, &bot, i, side)
+		}
+	}
+	if err := saveGridState(ctx, &bot); err != nil {
+		log.Printf("❌ Grid %s: failed to save state: %v", bot.ID.Hex(), err)
+	}
+}
+
+func checkGridTriggers(ctx context.Context) {
+	filter := bson.M{
+		"status": models.GridStatusRunning,
+		"$or": []bson.M{
+			{"stop_loss": bson.M{"$gt": 0}},
+			{"take_profit": bson.M{"$gt": 0}},
+		},
+	}
+	bots, err := repository.Find[models.GridBot](ctx, "grid_bots", filter, nil)
+	if err != nil {
+		log.Printf("❌ Grid monitor: failed to load bots: %v", err)
+		return
+	}
+
+	prices := make(map[string]float64)
+	for _, bot := range bots {
+		price, ok := prices[bot.Symbol]
+		if !ok {
+			price = getPriceFloat(ctx, bot.Symbol)
+			prices[bot.Symbol] = price
+		}
+		if price <= 0 {
+			continue
+		}
+
+		reason := ""
+		if bot.StopLoss > 0 && price <= bot.StopLoss {
+			reason = fmt.Sprintf("stop-loss hit at %.8f", price)
+		} else if bot.TakeProfit > 0 && price >= bot.TakeProfit {
+			reason = fmt.Sprintf("take-profit hit at %.8f", price)
+		}
+		if reason == "" {
+			continue
+		}
+
+		mu := gridLock(bot.ID)
+		mu.Lock()
+		if err := repository.IRepo.FindOneWhere(ctx, "grid_bots", bson.M{"_id": bot.ID}, &bot); err == nil && bot.Status == models.GridStatusRunning {
+			if err := stopGridLocked(ctx, &bot, reason, true); err != nil {
+				log.Printf("❌ Grid %s: failed to stop: %v", bot.ID.Hex(), err)
+			}
+		}
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"exdex/config"
+	models "exdex/internal/src/model"
+	database "exdex/server/databases"
+)
+
+const (
+	healthCheckTimeout = 2 * time.Second
+	// The exchange is asked at most this often, however often probes come.
+	exchangeCheckTTL = 10 * time.Second
+	// Binance pings every 3 minutes; a stream silent for longer is suspect.
+	userStreamSilenceLimit = 10 * time.Minute
+	// A listen key expires an hour after it was created or last kept alive.
+	listenKeyLifetime = 60 * time.Minute
+	marketDataMaxAge  = time.Minute
+)
+
+// userStreamState tracks the user-data stream for readiness.
+type userStreamState struct {
+	mu          sync.RWMutex
+	connected   bool
+	listenKeyAt time.Time
+	keepAliveAt time.Time
+	messageAt   time.Time // last event
+	seenAt      time.Time // last frame of any kind, pings included
+}
+
+var userStream = &userStreamState{}
+
+func (s *userStreamState) listenKeyIssued() {
+	s.mu.Lock()
+	s.listenKeyAt = time.Now()
+	s.keepAliveAt = s.listenKeyAt
+	s.mu.Unlock()
+}
+
+func (s *userStreamState) keptAlive() {
+	s.mu.Lock()
+	s.keepAliveAt = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *userStreamState) setConnected(connected bool) {
+	s.mu.Lock()
+	s.connected = connected
+	if connected {
+		s.seenAt = time.Now()
+	}
+	s.mu.Unlock()
+}
+
+func (s *userStreamState) seen(message bool) {
+	now := time.Now()
+	s.mu.Lock()
+	s.seenAt = now
+	if message {
+		s.messageAt = now
+	}
+	s.mu.Unlock()
+}
+
+// Readiness checks every dependency trading relies on, in parallel.
+func Readiness(ctx context.Context) models.HealthReport {
+	checks := map[string]func(context.Context) models.ComponentHealth{
+		"mongo":            checkMongo,
+		"exchange":         checkExchange,
+		"user_data_stream": checkUserStream,
+		"market_data":      checkMarketData,
+	}
+
+	report := models.HealthReport{
+		Status:     models.HealthOK,
+		Time:       time.Now(),
+		Components: make(map[string]models.ComponentHealth, len(checks)+1),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) models.ComponentHealth) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			result := check(cctx)
+			mu.Lock()
+			report.Components[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	if !AcceptingOrders() {
+		report.Components["orders"] = models.ComponentHealth{Status: models.HealthDown, Error: "shutting down"}
+	}
+	for _, c := range report.Components {
+		report.Status = worseHealth(report.Status, c.Status)
+	}
+	return report
+}
+
+func worseHealth(a, b string) string {
+	rank := map[string]int{models.HealthOK: 0, models.HealthDegraded: 1, models.HealthDown: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+func ms(d time.Duration) *int64 {
+	v := d.Milliseconds()
+	return &v
+}
+
+func checkMongo(ctx context.Context) models.ComponentHealth {
+	start := time.Now()
+	if err := database.Ping(ctx); err != nil {
+		return models.ComponentHealth{Status: models.HealthDown, Error: err.Error()}
+	}
+	return models.ComponentHealth{Status: models.HealthOK, LatencyMS: ms(time.Since(start))}
+}
+
+var (
+	exchangeCheckMu   sync.Mutex
+	exchangeCheckAt   time.Time
+	exchangeCheckLast models.ComponentHealth
+)
+
+// checkExchange calls the REST time endpoint and compares the exchange's
+// clock with ours. Signed requests fail once the skew exceeds recvWindow.
+func checkExchange(ctx context.Context) models.ComponentHealth {
+	exchangeCheckMu.Lock()
+	defer exchangeCheckMu.Unlock()
+	if time.Since(exchangeCheckAt) < exchangeCheckTTL {
+		return exchangeCheckLast
+	}
+
+	result := func() models.ComponentHealth {
+		req, err := http.NewRequest("GET", config.C.Binance.BaseURL+"/api/v3/time", nil)
+		if err != nil {
+			return models.ComponentHealth{Status: models.HealthDown, Error: err.Error()}
+		}
+		sent := time.Now()
+		status, body, err := doExchangeRequest(ctx, req)
+		received := time.Now()
+		if err == nil && status != http.StatusOK {
+			err = parseExchangeError(status, body)
+		}
+		if err != nil {
+			return models.ComponentHealth{Status: models.HealthDown, Error: err.Error()}
+		}
+		var res struct {
+			ServerTime int64 `json:"serverTime"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return models.ComponentHealth{Status: models.HealthDown, Error: err.Error()}
+		}
+
+		rtt := received.Sub(sent)
+		// Assume the exchange read its clock halfway through the round trip.
+		skew := time.UnixMilli(res.ServerTime).Sub(sent.Add(rtt / 2))
+		health := models.ComponentHealth{Status: models.HealthOK, LatencyMS: ms(rtt), ClockSkewMS: ms(skew)}
+
+		window := time.Duration(config.C.Binance.RecvWindow) * time.Millisecond
+		if skew < 0 {
+			skew = -skew
+		}
+		switch {
+		case window > 0 && skew > window:
+			health.Status = models.HealthDown
+			health.Error = fmt.Sprintf("clock skew exceeds recvWindow of %s", window)
+		case window > 0 && skew > window/2:
+			health.Status = models.HealthDegraded
+			health.Error = "clock skew above half of recvWindow"
+		}
+		return health
+	}()
+
+	exchangeCheckAt = time.Now()
+	exchangeCheckLast = result
+	return result
+}
+
+func checkUserStream(context.Context) models.ComponentHealth {
+	s := userStream
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	health := models.ComponentHealth{Status: models.HealthOK}
+	if !s.messageAt.IsZero() {
+		health.LastMessageAgeMS = ms(now.Sub(s.messageAt))
+	}
+	if !s.listenKeyAt.IsZero() {
+		health.ListenKeyAgeMS = ms(now.Sub(s.listenKeyAt))
+		health.LastKeepAliveAgeMS = ms(now.Sub(s.keepAliveAt))
+	}
+
+	switch {
+	case !s.connected:
+		health.Status = models.HealthDown
+		health.Error = "not connected"
+	case now.Sub(s.keepAliveAt) > listenKeyLifetime:
+		health.Status = models.HealthDown
+		health.Error = "listen key has expired"
+	case now.Sub(s.seenAt) > userStreamSilenceLimit:
+		// Events may be rare, but the exchange pings regularly.
+		health.Status = models.HealthDegraded
+		health.Error = fmt.Sprintf("nothing received for over %s", userStreamSilenceLimit)
+	}
+	return health
+}
+
+// checkMarketData reports how fresh the ticker stream is. A stale stream
+// only degrades readiness: prices fall back to REST.
+func checkMarketData(context.Context) models.ComponentHealth {
+	last := MarketData.LastMessageAt()
+	if last.IsZero() {
+		return models.ComponentHealth{Status: models.HealthDegraded, Error: "no ticker received yet"}
+	}
+	age := time.Since(last)
+	health := models.ComponentHealth{Status: models.HealthOK, LastMessageAgeMS: ms(age)}
+	if age > marketDataMaxAge {
+		health.Status = models.HealthDegraded
+		health.Error = fmt.Sprintf("no ticker for over %s", marketDataMaxAge)
+	}
+	return health
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"exdex/config"
+	"exdex/server/httpclient"
+)
+
+const (
+	defaultIdentityBaseURL = "https://api.exdex.com"
+	defaultIdentityTimeout = 10 * time.Second
+)
+
+var ErrInvalidIdentityToken = errors.New("identity provider rejected the token")
+
+// Identity is the profile an identity provider vouches for.
+type Identity struct {
+	ExdexUserID   uint
+	Email         string
+	AccountNumber string
+	FullName      string
+	Role          string
+}
+
+// IdentityProvider verifies a login token issued by an upstream account
+// service and returns who it belongs to.
+type IdentityProvider interface {
+	Verify(ctx context.Context, token string) (Identity, error)
+}
+
+var (
+	identityMu sync.Mutex
+	identity   IdentityProvider
+)
+
+// Identities returns the provider chosen by identity.provider: "exdex"
+// (default) or "fake".
+func Identities() IdentityProvider {
+	identityMu.Lock()
+	defer identityMu.Unlock()
+	if identity == nil {
+		switch config.C.Identity.Provider {
+		case "fake":
+			log.Println("⚠️  Using the fake identity provider, do not run this in production")
+			identity = NewFakeIdentityProviderFromConfig()
+		default:
+			identity = NewExdexIdentityProvider(config.C.Identity.BaseURL, config.C.Identity.Timeout)
+		}
+	}
+	return identity
+}
+
+// SetIdentityProvider replaces the configured provider, for tests and tools.
+// Passing nil goes back to the configured one.
+func SetIdentityProvider(p IdentityProvider) {
+	identityMu.Lock()
+	identity = p
+	identityMu.Unlock()
+}
+
+// ExdexIdentityProvider checks tokens against the EXDEX account service.
+type ExdexIdentityProvider struct {
+	BaseURL string
+	Timeout time.Duration
+}
+
+func NewExdexIdentityProvider(baseURL string, timeout time.Duration) *ExdexIdentityProvider {
+	if baseURL == "" {
+		baseURL = defaultIdentityBaseURL
+	}
+	if timeout <= 0 {
+		timeout = defaultIdentityTimeout
+	}
+	return &ExdexIdentityProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Timeout: timeout,
+	}
+}
+
+type ExdexUserResponse struct {
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	AccountNumber string `json:"accountNumber"`
+	FullName      string `json:"fullName"`
+	Role          string `json:"role"`
+}
+
+type ExdexResponse struct {
+	Code   int               `json:"code"`
+	Status string            `json:"status"`
+	Data   ExdexUserResponse `json:"data"`
+	Error  string            `json:"error,omitempty"`
+}
+
+func (p *ExdexIdentityProvider) Verify(ctx context.Context, token string) (Identity, error) {
+	endpoint := fmt.Sprintf("%s/api/exdex/check?token=%s", p.BaseURL, url.QueryEscape(token))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := httpclient.Do(ctx, "exdex identity", req, httpclient.Options{Timeout: p.Timeout})
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to reach identity provider: %w", err)
+	}
+
+	var apiResp ExdexResponse
+	if err := json.Unmarshal(resp.Body, &apiResp); err != nil {
+		return Identity{}, fmt.Errorf("failed to unmarshal response (HTTP %d): %v", resp.StatusCode, err)
+	}
+	if apiResp.Code != 200 {
+		return Identity{}, fmt.Errorf("%w: code %d, %s", ErrInvalidIdentityToken, apiResp.Code, apiResp.Error)
+	}
+	if apiResp.Data.ID <= 0 || apiResp.Data.Email == "" {
+		return Identity{}, errors.New("identity provider returned an incomplete profile")
+	}
+
+	return Identity{
+		ExdexUserID:   uint(apiResp.Data.ID),
+		Email:         apiResp.Data.Email,
+		AccountNumber: apiResp.Data.AccountNumber,
+		FullName:      apiResp.Data.FullName,
+		Role:          apiResp.Data.Role,
+	}, nil
+}
+
+// FakeIdentityProvider accepts a fixed set of tokens, for local development
+// and tests without the EXDEX account service.
+type FakeIdentityProvider struct {
+	Users map[string]Identity // by token
+}
+
+// NewFakeIdentityProviderFromConfig reads identity.fake_users, a list of
+// {token, id, email, full_name, account_number, role}.
+func NewFakeIdentityProviderFromConfig() *FakeIdentityProvider {
+	p := &FakeIdentityProvider{Users: make(map[string]Identity)}
+	for _, e := range config.C.Identity.FakeUsers {
+		p.Users[e.Token] = Identity{
+			ExdexUserID:   e.ID,
+			Email:         e.Email,
+			AccountNumber: e.AccountNumber,
+			FullName:      e.FullName,
+			Role:          e.Role,
+		}
+	}
+	return p
+}
+
+func (p *FakeIdentityProvider) Verify(ctx context.Context, token string) (Identity, error) {
+	id, ok := p.Users[token]
+	if !ok {
+		return Identity{}, ErrInvalidIdentityToken
+	}
+	return id, nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/security"
+)
+
+// RotationResult summarises a key rotation run.
+type RotationResult struct {
+	APIKeys   int      `json:"api_keys"`
+	TOTP      int      `json:"totp_secrets"`
+	Unchanged int      `json:"unchanged"`
+	Failed    []string `json:"failed,omitempty"`
+}
+
+// RotateEncryptionKeys re-encrypts stored secrets from the old keys to the
+// ones currently configured under api_keys.encryption_key and
+// two_factor.encryption_key. An empty old key leaves that kind of secret
+// alone. Secrets that already decrypt with the new key are skipped, so an
+// interrupted rotation can simply be run again.
+func RotateEncryptionKeys(ctx context.Context, oldAPIKey, oldTwoFactorKey string) (RotationResult, error) {
+	var res RotationResult
+	if oldAPIKey == "" && oldTwoFactorKey == "" {
+		return res, fmt.Errorf("no old key given")
+	}
+
+	if oldAPIKey != "" {
+		if apiKeyEncryptionKey() == "" || apiKeyEncryptionKey() == oldAPIKey {
+			return res, fmt.Errorf("api_keys.encryption_key must be set to the new key")
+		}
+		keys, err := repository.Find[models.APIKey](ctx, "api_keys", bson.M{"secret_encrypted": bson.M{"$ne": ""}}, nil)
+		if err != nil {
+			return res, err
+		}
+		for _, k := range keys {
+			rotated, changed, err := reencrypt(k.SecretEncrypted, oldAPIKey, apiKeyEncryptionKey())
+			if err != nil {
+				res.Failed = append(res.Failed, fmt.Sprintf("api key %s: %v", k.ID.Hex(), err))
+				continue
+			}
+			if !changed {
+				res.Unchanged++
+				continue
+			}
+			update := bson.M{"$set": bson.M{"secret_encrypted": rotated}}
+			if err := repository.IRepo.UpdateOne(ctx, "api_keys", bson.M{"_id": k.ID}, update, false); err != nil {
+				return res, err
+			}
+			res.APIKeys++
+		}
+	}
+
+	if oldTwoFactorKey != "" {
+		if twoFactorEncryptionKey() == "" || twoFactorEncryptionKey() == oldTwoFactorKey {
+			return res, fmt.Errorf("two_factor.encryption_key must be set to the new key")
+		}
+		filter := bson.M{"$or": []bson.M{
+			{"totp_secret": bson.M{"$exists": true, "$ne": ""}},
+			{"totp_pending_secret": bson.M{"$exists": true, "$ne": ""}},
+		}}
+		users, err := repository.Find[models.User](ctx, "users", filter, nil)
+		if err != nil {
+			return res, err
+		}
+		for _, u := range users {
+			set := bson.M{}
+			fields := map[string]string{"totp_secret": u.TOTPSecret, "totp_pending_secret": u.TOTPPendingSecret}
+			for field, value := range fields {
+				if value == "" {
+					continue
+				}
+				rotated, changed, err := reencrypt(value, oldTwoFactorKey, twoFactorEncryptionKey())
+				if err != nil {
+					res.Failed = append(res.Failed, fmt.Sprintf("user %s %s: %v", u.ID, field, err))
+					continue
+				}
+				if changed {
+					set[field] = rotated
+				} else {
+					res.Unchanged++
+				}
+			}
+			if len(set) == 0 {
+				continue
+			}
+			oid, err := primitive.ObjectIDFromHex(u.ID)
+			if err != nil {
+				return res, err
+			}
+			if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, bson.M{"$set": set}, false); err != nil {
+				return res, err
+			}
+			res.TOTP += len(set)
+		}
+	}
+	return res, nil
+}
+
+// reencrypt returns encoded encrypted with newKey. changed is false when it
+// already was.
+func reencrypt(encoded, oldKey, newKey string) (string, bool, error) {
+	if _, err := security.Decrypt(newKey, encoded); err == nil {
+		return encoded, false, nil
+	}
+	plain, err := security.Decrypt(oldKey, encoded)
+	if err != nil {
+		return "", false, fmt.Errorf("decrypts with neither key")
+	}
+	rotated, err := security.Encrypt(newKey, plain)
+	if err != nil {
+		return "", false, err
+	}
+	return rotated, true, nil
+}
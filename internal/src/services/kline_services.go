@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"exdex/config"
+)
+
+// Kline is one OHLCV bar from /api/v3/klines.
+type Kline struct {
+	OpenTime  time.Time
+	CloseTime time.Time
+	Open      float64
+	High      float64
+	Low       float64
+	Close     float64
+	Volume    float64
+}
+
+// GetKlines fetches up to limit bars of the given interval in [start, end].
+// Zero start or end leaves that bound open.
+func GetKlines(ctx context.Context, symbol, interval string, start, end time.Time, limit int) ([]Kline, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("interval", interval)
+	if !start.IsZero() {
+		params.Set("startTime", strconv.FormatInt(start.UnixMilli(), 10))
+	}
+	if !end.IsZero() {
+		params.Set("endTime", strconv.FormatInt(end.UnixMilli(), 10))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	fullURL := fmt.Sprintf("%s/api/v3/klines?%s", config.C.Binance.BaseURL, params.Encode())
+	body, err := sendRequest(ctx, "GET", fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw [][]interface{}
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, len(raw))
+	for _, r := range raw {
+		if len(r) < 7 {
+			continue
+		}
+		openMs, _ := r[0].(float64)
+		closeMs, _ := r[6].(float64)
+		klines = append(klines, Kline{
+			OpenTime:  time.UnixMilli(int64(openMs)),
+			CloseTime: time.UnixMilli(int64(closeMs)),
+			Open:      klineFloat(r[1]),
+			High:      klineFloat(r[2]),
+			Low:       klineFloat(r[3]),
+			Close:     klineFloat(r[4]),
+			Volume:    klineFloat(r[5]),
+		})
+	}
+	return klines, nil
+}
+
+func klineFloat(v interface{}) float64 {
+	s, _ := v.(string)
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
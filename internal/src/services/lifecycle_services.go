@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	acceptingOrders atomic.Bool
+
+	// workerCtx is the parent of every scheduler and runner; StopWorkers
+	// cancels it.
+	workerCtx, cancelWorkers = context.WithCancel(context.Background())
+	workers                  sync.WaitGroup
+)
+
+func init() {
+	acceptingOrders.Store(true)
+}
+
+// AcceptingOrders reports whether new orders may still be placed.
+func AcceptingOrders() bool {
+	return acceptingOrders.Load()
+}
+
+// StopNewOrders is the first step of a shutdown. New order requests are
+// refused and fills no longer trigger grid, copy or strategy orders, while
+// orders already being placed run to completion.
+func StopNewOrders() {
+	acceptingOrders.Store(false)
+}
+
+// goWorker runs fn in a goroutine that StopWorkers waits for. Nothing is
+// started once the workers have been stopped.
+func goWorker(fn func(ctx context.Context)) {
+	if workerCtx.Err() != nil {
+		return
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		fn(workerCtx)
+	}()
+}
+
+// StopWorkers cancels the schedulers, algo runners and strategy runners and
+// waits for them to return, or for ctx to expire.
+func StopWorkers(ctx context.Context) error {
+	cancelWorkers()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
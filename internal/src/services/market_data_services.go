@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"exdex/config"
+)
+
+// Market data comes from the exchange orders are placed on, so prices on
+// testnet match the testnet book.
+const (
+	marketPriceMaxAge   = 10 * time.Second
+	marketReconnectWait = 5 * time.Second
+)
+
+type cachedPrice struct {
+	price     float64
+	updatedAt time.Time
+}
+
+// MarketDataHub keeps one shared ticker stream, caches the last price per
+// symbol and fans tickers out to WebSocket clients.
+type MarketDataHub struct {
+	mu          sync.RWMutex
+	prices      map[string]cachedPrice
+	subscribers map[chan StreamTicker]struct{}
+	lastMessage time.Time
+	closed      bool
+}
+
+var MarketData = &MarketDataHub{
+	prices:      make(map[string]cachedPrice),
+	subscribers: make(map[chan StreamTicker]struct{}),
+}
+
+// Run keeps the ticker stream connected, reconnecting after failures, until
+// ctx is done. Subscriber channels are closed when it returns.
+func (h *MarketDataHub) Run(ctx context.Context) {
+	defer h.close()
+	for {
+		tickers := make(chan StreamTicker, 64)
+		go func() {
+			StartBinanceTickerStream(ctx, tickers)
+			close(tickers)
+		}()
+		for t := range tickers {
+			h.publish(t)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("⚠️  Market data stream disconnected, reconnecting in %s", marketReconnectWait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(marketReconnectWait):
+		}
+	}
+}
+
+// close ends every subscription, which makes the WebSocket clients fed by
+// the hub disconnect.
+func (h *MarketDataHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subscribers {
+		close(ch)
+	}
+	h.subscribers = make(map[chan StreamTicker]struct{})
+}
+
+func (h *MarketDataHub) publish(t StreamTicker) {
+	observeStreamLag("market_data", t.EventTime)
+	price, err := strconv.ParseFloat(t.Price, 64)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.lastMessage = now
+	if err == nil {
+		h.prices[strings.ToUpper(t.Symbol)] = cachedPrice{price: price, updatedAt: now}
+	}
+	for ch := range h.subscribers {
+		select {
+		case ch <- t:
+		default:
+			wsDropped.Inc(WSChannelMarket)
+		}
+	}
+}
+
+// Subscribe returns a channel of tickers. Once the hub has stopped the
+// channel comes back closed.
+func (h *MarketDataHub) Subscribe() chan StreamTicker {
+	ch := make(chan StreamTicker, 64)
+	h.mu.Lock()
+	if h.closed {
+		close(ch)
+	} else {
+		h.subscribers[ch] = struct{}{}
+	}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *MarketDataHub) Unsubscribe(ch chan StreamTicker) {
+	h.mu.Lock()
+	delete(h.subscribers, ch)
+	h.mu.Unlock()
+}
+
+// LastMessageAt is when the stream last delivered a ticker.
+func (h *MarketDataHub) LastMessageAt() time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastMessage
+}
+
+// Price returns a cached price no older than marketPriceMaxAge, falling back
+// to the REST ticker for symbols the stream does not cover.
+func (h *MarketDataHub) Price(symbol string) (float64, error) {
+	symbol = strings.ToUpper(symbol)
+
+	h.mu.RLock()
+	p, ok := h.prices[symbol]
+	h.mu.RUnlock()
+	if ok && time.Since(p.updatedAt) < marketPriceMaxAge {
+		return p.price, nil
+	}
+
+	body, err := sendRequest(context.Background(), "GET", fmt.Sprintf("%s/api/v3/ticker/price?symbol=%s", config.C.Binance.BaseURL, symbol))
+	if err != nil {
+		return 0, err
+	}
+	var res struct {
+		Price string `json:"price"`
+	}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return 0, err
+	}
+	price, err := strconv.ParseFloat(res.Price, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("no price for %s", symbol)
+	}
+
+	h.mu.Lock()
+	h.prices[symbol] = cachedPrice{price: price, updatedAt: time.Now()}
+	h.mu.Unlock()
+	return price, nil
+}
+
+// BookLevel is one price level of the order book.
+type BookLevel struct {
+	Price float64
+	Qty   float64
+}
+
+// OrderBook returns the top of the live order book.
+func (h *MarketDataHub) OrderBook(symbol string, limit int) (bids, asks []BookLevel, err error) {
+	url := fmt.Sprintf("%s/api/v3/depth?symbol=%s&limit=%d", config.C.Binance.BaseURL, strings.ToUpper(symbol), limit)
+	body, err := sendRequest(context.Background(), "GET", url)
+	if err != nil {
+		return nil, nil, err
+	}
+	var res struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return nil, nil, err
+	}
+	return parseBookLevels(res.Bids), parseBookLevels(res.Asks), nil
+}
+
+func parseBookLevels(raw [][]string) []BookLevel {
+	levels := make([]BookLevel, 0, len(raw))
+	for _, l := range raw {
+		if len(l) < 2 {
+			continue
+		}
+		p, _ := strconv.ParseFloat(l[0], 64)
+		q, _ := strconv.ParseFloat(l[1], 64)
+		levels = append(levels, BookLevel{Price: p, Qty: q})
+	}
+	return levels
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	models "exdex/internal/src/model"
+	"exdex/server/metrics"
+)
+
+var lagBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+var (
+	orderPlacementSeconds = metrics.NewHistogram("exdex_order_placement_duration_seconds",
+		"Time to place an order, exchange round trip and storage included.",
+		metrics.DefBuckets, "type", "venue", "outcome")
+
+	exchangeRequests = metrics.NewCounter("exdex_exchange_requests_total",
+		"Exchange REST calls by endpoint and result. code is ok, the exchange error code, http_<status> when it sent none, throttled when the governor refused the call, canceled, or network.",
+		"endpoint", "code")
+	exchangeRequestSeconds = metrics.NewHistogram("exdex_exchange_request_duration_seconds",
+		"Exchange REST call latency.",
+		metrics.DefBuckets, "endpoint")
+
+	wsClients = metrics.NewGauge("exdex_ws_clients",
+		"Open client WebSocket connections.", "channel")
+	wsDropped = metrics.NewCounter("exdex_ws_messages_dropped_total",
+		"Messages not delivered to a client WebSocket because its queue was full.", "channel")
+
+	streamLag = metrics.NewHistogram("exdex_stream_message_lag_seconds",
+		"Delay between the exchange's event time and our receiving it.",
+		lagBuckets, "stream")
+)
+
+// Client WebSocket channels.
+const (
+	WSChannelMarket  = "market"
+	WSChannelPrivate = "private"
+	WSChannelEcho    = "echo"
+)
+
+// TrackWSClient counts an open client socket on channel until the returned
+// func is called.
+func TrackWSClient(channel string) (done func()) {
+	wsClients.Inc(channel)
+	return func() { wsClients.Dec(channel) }
+}
+
+// observeStreamLag records how long an exchange event took to reach us.
+// eventTime is the event's E field in milliseconds.
+func observeStreamLag(stream string, eventTime int64) {
+	if eventTime <= 0 {
+		return
+	}
+	lag := time.Since(time.UnixMilli(eventTime)).Seconds()
+	if lag < 0 {
+		// Our clock is behind the exchange's.
+		lag = 0
+	}
+	streamLag.Observe(lag, stream)
+}
+
+// observeOrder records a placement that began at start. It is deferred with
+// a pointer to the caller's error so the outcome is read when it returns.
+func observeOrder(kind string, paper bool, start time.Time, err *error) {
+	venue := "live"
+	if paper {
+		venue = "paper"
+	}
+	outcome := "ok"
+	if *err != nil {
+		outcome = "error"
+		var coded interface{ ErrorCode() string }
+		if errors.As(*err, &coded) {
+			outcome = strings.ToLower(coded.ErrorCode())
+		}
+	}
+	orderPlacementSeconds.Observe(metrics.Since(start), kind, venue, outcome)
+}
+
+// orderKind is the bounded type label of an order request.
+func orderKind(req models.OrderRequest) string {
+	kind := "other"
+	switch strings.ToUpper(req.OrderType) {
+	case "MARKET":
+		kind = "market"
+	case "LIMIT":
+		kind = "limit"
+	}
+	if req.WithTPSL {
+		kind += "_tpsl"
+	}
+	return kind
+}
+
+// observeExchangeRequest records one REST call. status is zero when no
+// response came back.
+func observeExchangeRequest(endpoint string, start time.Time, status int, body []byte, err error) {
+	code := "ok"
+	switch {
+	case status == 0 && isThrottled(err):
+		code = "throttled"
+	case status == 0 && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)):
+		code = "canceled"
+	case status == 0:
+		code = "network"
+	case status < 200 || status > 299:
+		var exchErr *ExchangeError
+		if errors.As(parseExchangeError(status, body), &exchErr) && exchErr.ExchCode != 0 {
+			code = strconv.Itoa(exchErr.ExchCode)
+		} else {
+			code = "http_" + strconv.Itoa(status)
+		}
+	}
+	exchangeRequests.Inc(endpoint, code)
+	if status != 0 {
+		exchangeRequestSeconds.Observe(metrics.Since(start), endpoint)
+	}
+}
+
+func isThrottled(err error) bool {
+	var rl *RateLimitError
+	return errors.As(err, &rl)
+}
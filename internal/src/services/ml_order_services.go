@@ -2,13 +2,9 @@ package services
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -18,70 +14,109 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"exdex/config"
 	models "exdex/internal/src/model"
 	"exdex/internal/src/repository"
-	database "exdex/server/databases"
 )
 
-func generateSignature(query string) string {
-	h := hmac.New(sha256.New, []byte("npG6JapFCZ9B3iA39ODdpdOkLOOzqoK8S1O5E7I9vgXJ1Axh28ICpcf3c2Rmfd8f"))
-	h.Write([]byte(query))
-	return hex.EncodeToString(h.Sum(nil))
-}
-
-func makeRequest(method, endpoint, params string) ([]byte, error) {
+func makeRequest(ctx context.Context, method, endpoint, params string) ([]byte, error) {
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
-	query := params + "&timestamp=" + timestamp
-	signature := generateSignature(query)
+	query := params + "&recvWindow=" + binanceRecvWindow() + "&timestamp=" + timestamp
+	signature := signQuery(query)
 	finalQuery := query + "&signature=" + signature
 
 	var req *http.Request
 	var err error
 
 	if method == "POST" {
-		req, err = http.NewRequest("POST", "https://testnet.binance.vision"+endpoint, strings.NewReader(finalQuery))
+		req, err = http.NewRequest("POST", config.C.Binance.BaseURL+endpoint, strings.NewReader(finalQuery))
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	} else {
-		req, err = http.NewRequest("GET", "https://testnet.binance.vision"+endpoint+"?"+finalQuery, nil)
+		req, err = http.NewRequest(method, config.C.Binance.BaseURL+endpoint+"?"+finalQuery, nil)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-MBX-APIKEY", "b5J1Wa8FEi7TVeeO6jLOsWBdEdmPsGmwB4gxJXT0F4YKlQx3VXmTtSeVn4Wuhwta")
+	req.Header.Set("X-MBX-APIKEY", config.C.Binance.APIKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	status, body, err := doExchangeRequest(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != 200 {
-		return nil, errors.New(string(body))
+	if status != 200 {
+		return nil, parseExchangeError(status, body)
 	}
 
 	return body, nil
 }
 
-func PlaceMarketOrder(symbol, side, quantity, userID string) (*models.OrderResponse, error) {
+func PlaceMarketOrder(ctx context.Context, symbol, side, quantity, userID string) (*models.OrderResponse, error) {
+	return placeMarketOrder(ctx, symbol, side, quantity, "", userID)
+}
+
+// placeMarketOrder places a market order under clientID, or under an id
+// the exchange picks when clientID is empty.
+func placeMarketOrder(ctx context.Context, symbol, side, quantity, clientID, userID string) (_ *models.OrderResponse, err error) {
+	paper, err := IsPaperUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer observeOrder("market", paper, time.Now(), &err)
+	if paper {
+		return paperMarket(ctx, symbol, side, quantity, "", clientID, userID)
+	}
 	params := fmt.Sprintf("symbol=%s&side=%s&type=MARKET&quantity=%s", symbol, side, quantity)
-	body, err := makeRequest("POST", "/api/v3/order", params)
+	if clientID != "" {
+		params += "&newClientOrderId=" + clientID
+	}
+	body, err := makeRequest(ctx, "POST", "/api/v3/order", params)
 	if err != nil {
 		return nil, err
 	}
 	var order models.OrderResponse
 	json.Unmarshal(body, &order)
-	err = storeOrderToDB(&order, side, "MARKET", quantity, "", "", "", userID)
+	err = storeOrderToDB(ctx, &order, side, "MARKET", quantity, "", "", "", userID)
 	if err != nil {
 		return nil, err
 	}
 	return &order, nil
 }
 
-func PlaceMarketOrderWithTPSL(symbol, side, quantity, tp, sl, userID string) (*models.OrderResponse, *models.OCOOrderResponse, error) {
-	order, err := PlaceMarketOrder(symbol, side, quantity, userID)
+// PlaceMarketQuoteOrder spends (BUY) or receives (SELL) quoteQty of the quote
+// asset at market, letting the exchange work out the base quantity.
+func PlaceMarketQuoteOrder(ctx context.Context, symbol, side, quoteQty, userID string) (_ *models.OrderResponse, err error) {
+	paper, err := IsPaperUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer observeOrder("market_quote", paper, time.Now(), &err)
+	if paper {
+		return PaperMarketQuoteOrder(ctx, symbol, side, quoteQty, userID)
+	}
+	params := fmt.Sprintf("symbol=%s&side=%s&type=MARKET&quoteOrderQty=%s", symbol, side, quoteQty)
+	body, err := makeRequest(ctx, "POST", "/api/v3/order", params)
+	if err != nil {
+		return nil, err
+	}
+	var order models.OrderResponse
+	json.Unmarshal(body, &order)
+	err = storeOrderToDB(ctx, &order, side, "MARKET", order.ExecutedQty, "", "", "", userID)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func PlaceMarketOrderWithTPSL(ctx context.Context, symbol, side, quantity, tp, sl, userID string) (*models.OrderResponse, *models.OCOOrderResponse, error) {
+	paper, err := IsPaperUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if paper {
+		return PaperMarketOrderWithTPSL(ctx, symbol, side, quantity, tp, sl, userID)
+	}
+	order, err := PlaceMarketOrder(ctx, symbol, side, quantity, userID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -94,7 +129,7 @@ func PlaceMarketOrderWithTPSL(symbol, side, quantity, tp, sl, userID string) (*m
 	params := fmt.Sprintf("symbol=%s&side=%s&quantity=%s&price=%s&stopPrice=%s&stopLimitPrice=%s&stopLimitTimeInForce=GTC",
 		symbol, opposite, quantity, tp, sl, sl)
 
-	body, err := makeRequest("POST", "/api/v3/order/oco", params)
+	body, err := makeRequest(ctx, "POST", "/api/v3/order/oco", params)
 	if err != nil {
 		return order, nil, err
 	}
@@ -102,19 +137,46 @@ func PlaceMarketOrderWithTPSL(symbol, side, quantity, tp, sl, userID string) (*m
 	var oco models.OCOOrderResponse
 	json.Unmarshal(body, &oco)
 
-	// order, err := PlaceMarketOrder(symbol, side, quantity)
-	err = storeOrderToDB(order, side, "MARKET", quantity, "", tp, sl, userID)
-	if err != nil {
-		return order, nil, err
+	// The entry was stored by PlaceMarketOrder; attach the exits to it.
+	if err := setOrderTPSL(ctx, order.OrderID, tp, sl); err != nil {
+		return order, &oco, err
 	}
 	return order, &oco, nil
 }
 
-func PlaceLimitOrder(symbol, side, quantity, price, userID string) (*models.OrderResponse, error) {
-	params := fmt.Sprintf("symbol=%s&side=%s&type=LIMIT&timeInForce=GTC&quantity=%s&price=%s",
-		symbol, side, quantity, price)
+func PlaceLimitOrder(ctx context.Context, symbol, side, quantity, price, userID string) (*models.OrderResponse, error) {
+	return placeLimitOrder(ctx, symbol, side, quantity, price, "GTC", "", userID)
+}
+
+// placeLimitOrder is PlaceLimitOrder with a time in force (GTC or IOC) and
+// an optional clientID.
+func placeLimitOrder(ctx context.Context, symbol, side, quantity, price, timeInForce, clientID, userID string) (_ *models.OrderResponse, err error) {
+	paper, err := IsPaperUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer observeOrder("limit", paper, time.Now(), &err)
+	if paper {
+		var order *models.OrderResponse
+		order, err = paperLimit(ctx, symbol, side, quantity, price, "", "", clientID, userID)
+		if err != nil || timeInForce != "IOC" || order.Status != "NEW" {
+			return order, err
+		}
+		// Paper limits rest until matched; an IOC one that did not fill
+		// straight away is cancelled instead.
+		if err = PaperCancelOrder(ctx, order.OrderID); err != nil {
+			return nil, err
+		}
+		order.Status = "CANCELED"
+		return order, nil
+	}
+	params := fmt.Sprintf("symbol=%s&side=%s&type=LIMIT&timeInForce=%s&quantity=%s&price=%s",
+		symbol, side, timeInForce, quantity, price)
+	if clientID != "" {
+		params += "&newClientOrderId=" + clientID
+	}
 
-	body, err := makeRequest("POST", "/api/v3/order", params)
+	body, err := makeRequest(ctx, "POST", "/api/v3/order", params)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +184,7 @@ func PlaceLimitOrder(symbol, side, quantity, price, userID string) (*models.Orde
 	var order models.OrderResponse
 	json.Unmarshal(body, &order)
 
-	err = storeOrderToDB(&order, side, "LIMIT", quantity, price, "", "", userID)
+	err = storeOrderToDB(ctx, &order, side, "LIMIT", quantity, price, "", "", userID)
 
 	if err != nil {
 		return &order, nil
@@ -130,64 +192,65 @@ func PlaceLimitOrder(symbol, side, quantity, price, userID string) (*models.Orde
 	return &order, nil
 }
 
-func PlaceLimitOrderWithTPSL(symbol, side, quantity, price, tp, sl, userID string) (*models.OrderResponse, error) {
-	order, err := PlaceLimitOrder(symbol, side, quantity, price, userID)
+func PlaceLimitOrderWithTPSL(ctx context.Context, symbol, side, quantity, price, tp, sl, userID string) (*models.OrderResponse, error) {
+	paper, err := IsPaperUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-
-	err = storeOrderToDB(order, side, "LIMIT", quantity, price, tp, sl, userID)
-
+	if paper {
+		return PaperLimitOrderWithTPSL(ctx, symbol, side, quantity, price, tp, sl, userID)
+	}
+	order, err := PlaceLimitOrder(ctx, symbol, side, quantity, price, userID)
 	if err != nil {
+		return nil, err
+	}
+
+	if err := setOrderTPSL(ctx, order.OrderID, tp, sl); err != nil {
 		return order, nil
 	}
 	// TP/SL is manual or external logic after limit fill.
 	return order, nil
 }
-func GetMyOrders(userID string, page int, limit int) ([]models.OrderRecord, int64, error) {
-	var orders []models.OrderRecord
 
-	// Convert userID to ObjectID if needed (uncomment below if user_id is ObjectID in MongoDB)
-	// objID, err := primitive.ObjectIDFromHex(userID)
-	// if err != nil {
-	//     return nil, 0, err
-	// }
+func setOrderTPSL(ctx context.Context, orderID int64, tp, sl string) error {
+	update := bson.M{"$set": bson.M{"take_profit_price": tp, "stop_loss_price": sl}}
+	return repository.IRepo.UpdateOne(ctx, "orders", bson.M{"order_id": orderID}, update, false)
+}
 
-	filter := bson.M{
-		"user_id": userID, // use objID here if you uncomment above
+// CancelOrder cancels a single open order. Negative IDs are paper orders.
+func CancelOrder(ctx context.Context, symbol string, orderID int64) error {
+	if orderID < 0 {
+		return PaperCancelOrder(ctx, orderID)
 	}
+	params := fmt.Sprintf("symbol=%s&orderId=%d", symbol, orderID)
+	_, err := makeRequest(ctx, "DELETE", "/api/v3/order", params)
+	return err
+}
 
-	skip := (page - 1) * limit
-
-	opts := options.Find()
-	opts.SetSkip(int64(skip))
-	opts.SetLimit(int64(limit))
-	opts.SetSort(bson.D{{Key: "createdAt", Value: -1}})
-
-	collection := database.DB.Collection("orders")
+func GetMyOrders(ctx context.Context, userID string, page int, limit int) ([]models.OrderRecord, int64, error) {
+	filter := bson.M{"user_id": userID}
 
-	// Get total count of documents matching the filter
-	total, err := collection.CountDocuments(context.TODO(), filter)
+	total, err := repository.IRepo.Count(ctx, "orders", filter)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Find paginated results
-	cursor, err := collection.Find(context.TODO(), filter, opts)
+	opts := options.Find().
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+	orders, err := repository.Find[models.OrderRecord](ctx, "orders", filter, opts)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer cursor.Close(context.TODO())
-
-	// Decode results into the orders slice
-	if err := cursor.All(context.TODO(), &orders); err != nil {
-		return nil, 0, err
-	}
-
 	return orders, total, nil
 }
 
-func storeOrderToDB(order *models.OrderResponse, side, orderType, quantity, price, tp, sl, userID string) error {
+// storeOrderToDB records an order the exchange accepted.
+func storeOrderToDB(ctx context.Context, order *models.OrderResponse, side, orderType, quantity, price, tp, sl, userID string) error {
+	if order.OrderID == 0 {
+		return errors.New("exchange did not acknowledge the order")
+	}
 	record := models.OrderRecord{
 		Symbol:          order.Symbol,
 		Side:            side,
@@ -200,10 +263,12 @@ func storeOrderToDB(order *models.OrderResponse, side, orderType, quantity, pric
 		OrderID:         order.OrderID,
 		ClientOrderID:   order.ClientOrderID,
 		Status:          order.Status,
+		ExecutedQty:     order.ExecutedQty,
+		QuoteQty:        order.CummulativeQuoteQty,
 		CreatedAt:       primitive.NewDateTimeFromTime(time.Now()),
 	}
 
-	err := repository.IRepo.Insert("orders", &record)
+	err := repository.IRepo.Insert(ctx, "orders", &record)
 	if err != nil {
 		return err
 	}
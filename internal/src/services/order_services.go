@@ -2,21 +2,21 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"time"
 
-	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
 
+	"exdex/config"
 	models "exdex/internal/src/model"
 	"exdex/internal/src/repository"
 	"exdex/server/utils"
@@ -24,17 +24,16 @@ import (
 
 type OrderSerices struct{}
 
-func (os OrderSerices) GetHistory(uId string, limit, offset int) ([]models.PlacedOrder, error) {
+func (os OrderSerices) GetHistory(ctx context.Context, uId string, limit, offset int) ([]models.PlacedOrder, error) {
 	filter := models.Filter{
-		Sort:       "createdAt",
+		Sort:       "created_at",
 		SortOrder:  -1,
 		Limit:      limit,
 		Offset:     offset,
 		Conditions: bson.M{"user_id": uId},
 	}
 
-	var items []models.PlacedOrder
-	err := repository.IRepo.GetAllByFiltter("orders", &items, filter.Conditions, filter)
+	items, err := repository.FindPage[models.PlacedOrder](ctx, "orders", filter.Conditions, filter)
 	if err != nil {
 		return items, err
 	}
@@ -42,58 +41,68 @@ func (os OrderSerices) GetHistory(uId string, limit, offset int) ([]models.Place
 	return items, nil
 }
 
-func (os OrderSerices) PlaceOrder(req models.OrderRequest, uId string) (models.PlacedOrder, error) {
+func (os OrderSerices) PlaceOrder(ctx context.Context, req models.OrderRequest, uId string) (_ models.PlacedOrder, err error) {
+	paper := req.Paper
+	if !paper {
+		if paper, err = IsPaperUser(ctx, uId); err != nil {
+			return models.PlacedOrder{}, err
+		}
+	}
+	defer observeOrder(orderKind(req), paper, time.Now(), &err)
+	if paper {
+		return PaperPlaceOrder(ctx, req, uId)
+	}
+
 	var res string
-	var err error
 
 	if req.WithTPSL {
 		if req.OrderType == "LIMIT" {
 			if req.UseAbs {
-				res, err = placeOCOAbsolute(req)
+				res, err = placeOCOAbsolute(ctx, req)
 			} else {
-				res, err = placeOCODynamic(req)
+				res, err = placeOCODynamic(ctx, req)
 			}
 		} else if req.OrderType == "MARKET" {
-			res, err = marketOrderWithTPSL(req)
+			res, err = marketOrderWithTPSL(ctx, req)
 		} else {
-			res, err = simpleOrder(req)
+			res, err = simpleOrder(ctx, req)
 		}
 	} else {
-		res, err = simpleOrder(req)
+		res, err = simpleOrder(ctx, req)
 	}
 
 	if err != nil {
-		return models.PlacedOrder{}, fmt.Errorf("order placement failed: %v", err)
+		return models.PlacedOrder{}, fmt.Errorf("order placement failed: %w", err)
 	}
 
-	placedOrder, err := storeOrderInMongo(req, res, uId)
+	placedOrder, err := storeOrderInMongo(ctx, req, res, uId)
 	if err != nil {
-		return placedOrder, fmt.Errorf("failed to store order: %v", err)
+		return placedOrder, fmt.Errorf("failed to store order: %w", err)
 	}
 
 	return placedOrder, nil
 }
 
-func marketOrderWithTPSL(req models.OrderRequest) (string, error) {
+func marketOrderWithTPSL(ctx context.Context, req models.OrderRequest) (string, error) {
 	// Place main market order
-	// orderResp, err := simpleOrder(req)
+	// orderResp, err := simpleOrder(ctx, req)
 	// if err != nil {
 	// 	return "", fmt.Errorf("main order failed: %v", err)
 	// }
 
 	if !req.WithTPSL {
-		return "orderResp", nil
+		return simpleOrder(ctx, req)
 	}
 
 	// Get current price
-	currentPrice, err := getLastPrice(req.Symbol)
+	currentPrice, err := getLastPrice(ctx, req.Symbol)
 	if err != nil {
-		return "orderResp", fmt.Errorf("failed to get last price: %v", err)
+		return "", fmt.Errorf("failed to get last price: %w", err)
 	}
 
 	priceF, err := strconv.ParseFloat(currentPrice, 64)
 	if err != nil {
-		return "orderResp", fmt.Errorf("failed to parse price: %v", err)
+		return "", fmt.Errorf("failed to parse price: %v", err)
 	}
 
 	var tpPrice, slPrice float64
@@ -113,24 +122,19 @@ func marketOrderWithTPSL(req models.OrderRequest) (string, error) {
 	// Validate prices
 	if req.Side == "BUY" {
 		if tpPrice <= priceF || slPrice >= priceF {
-			return "orderResp", errors.New("for BUY orders, TP must be above current price and SL must be below")
+			return "", errors.New("for BUY orders, TP must be above current price and SL must be below")
 		}
 	} else {
 		if tpPrice >= priceF || slPrice <= priceF {
-			return "orderResp", errors.New("for SELL orders, TP must be below current price and SL must be above")
+			return "", errors.New("for SELL orders, TP must be below current price and SL must be above")
 		}
 	}
 
 	// Place OCO order
-	ocoResp, err := placeOCO(req.Symbol, oppositeSide(req.Side), req.Quantity, tpPrice, slPrice)
-	if err != nil {
-		return fmt.Sprintf("%s (OCO failed: %v)", "orderResp", err), nil
-	}
-
-	return fmt.Sprintf("Market Order: %s\nOCO TP/SL: %s", "orderResp", ocoResp), nil
+	return placeOCO(ctx, req.Symbol, oppositeSide(req.Side), req.Quantity, tpPrice, slPrice)
 }
 
-func placeOCO(symbol, side, qty string, tpPrice, slPrice float64) (string, error) {
+func placeOCO(ctx context.Context, symbol, side, qty string, tpPrice, slPrice float64) (string, error) {
 	endpoint := "/api/v3/order/oco"
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 
@@ -152,16 +156,16 @@ func placeOCO(symbol, side, qty string, tpPrice, slPrice float64) (string, error
 	data.Set("stopLimitPrice", fmt.Sprintf("%.8f", stopLimitPrice))
 	data.Set("stopLimitTimeInForce", "GTC")
 	data.Set("timestamp", timestamp)
-	data.Set("recvWindow", "5000")
+	data.Set("recvWindow", binanceRecvWindow())
 
 	sign := signQuery(data.Encode())
 	data.Set("signature", sign)
 
-	fullURL := fmt.Sprintf("%s%s?%s", viper.GetString("binance.baseURL"), endpoint, data.Encode())
-	return sendRequest("POST", fullURL)
+	fullURL := fmt.Sprintf("%s%s?%s", config.C.Binance.BaseURL, endpoint, data.Encode())
+	return sendRequest(ctx, "POST", fullURL)
 }
 
-func simpleOrder(req models.OrderRequest) (string, error) {
+func simpleOrder(ctx context.Context, req models.OrderRequest) (string, error) {
 	endpoint := "/api/v3/order"
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 
@@ -173,26 +177,26 @@ func simpleOrder(req models.OrderRequest) (string, error) {
 	data.Set("timestamp", timestamp)
 
 	if req.OrderType == "LIMIT" {
-		data.Set("price", fetchPrice(1.00, req.Symbol))
+		data.Set("price", fetchPrice(ctx, 1.00, req.Symbol))
 		data.Set("timeInForce", "GTC")
 	}
 
 	sign := signQuery(data.Encode())
 	data.Set("signature", sign)
 
-	fullURL := fmt.Sprintf("%s%s?%s", viper.GetString("binance.baseURL"), endpoint, data.Encode())
-	return sendRequest("POST", fullURL)
+	fullURL := fmt.Sprintf("%s%s?%s", config.C.Binance.BaseURL, endpoint, data.Encode())
+	return sendRequest(ctx, "POST", fullURL)
 }
 
-func placeOCOAbsolute(req models.OrderRequest) (string, error) {
-	return sendOCO(req.Side, req.Quantity,
+func placeOCOAbsolute(ctx context.Context, req models.OrderRequest) (string, error) {
+	return sendOCO(ctx, req.Side, req.Quantity,
 		fmt.Sprintf("%.8f", req.TPPrice),
 		fmt.Sprintf("%.8f", req.SLPrice),
 		req.Symbol)
 }
 
-func placeOCODynamic(req models.OrderRequest) (string, error) {
-	price := getPriceFloat(req.Symbol)
+func placeOCODynamic(ctx context.Context, req models.OrderRequest) (string, error) {
+	price := getPriceFloat(ctx, req.Symbol)
 	var tp, sl float64
 
 	if req.Side == "BUY" {
@@ -203,12 +207,12 @@ func placeOCODynamic(req models.OrderRequest) (string, error) {
 		sl = price * (1 + req.SLMul)
 	}
 
-	return sendOCO(req.Side, req.Quantity,
+	return sendOCO(ctx, req.Side, req.Quantity,
 		fmt.Sprintf("%.8f", tp),
 		fmt.Sprintf("%.8f", sl),
 		req.Symbol)
 }
-func sendOCO(side, qty, tp, sl, symbol string) (string, error) {
+func sendOCO(ctx context.Context, side, qty, tp, sl, symbol string) (string, error) {
 	endpoint := "/api/v3/order/oco"
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 
@@ -232,19 +236,19 @@ func sendOCO(side, qty, tp, sl, symbol string) (string, error) {
 	data.Set("stopPrice", fmt.Sprintf("%.2f", slFloat))             // Stop Trigger
 	data.Set("stopLimitPrice", fmt.Sprintf("%.8f", stopLimitPrice)) // Actual Stop-Limit Sell Price
 	data.Set("stopLimitTimeInForce", "GTC")
-	data.Set("recvWindow", "5000")
+	data.Set("recvWindow", binanceRecvWindow())
 	data.Set("timestamp", timestamp)
 
 	sign := signQuery(data.Encode())
 	data.Set("signature", sign)
 
-	fullURL := fmt.Sprintf("%s%s?%s", viper.GetString("binance.baseURL"), endpoint, data.Encode())
-	return sendRequest("POST", fullURL)
+	fullURL := fmt.Sprintf("%s%s?%s", config.C.Binance.BaseURL, endpoint, data.Encode())
+	return sendRequest(ctx, "POST", fullURL)
 }
 
-func getLastPrice(symbol string) (string, error) {
-	url := fmt.Sprintf("%s/api/v3/ticker/price?symbol=%s", viper.GetString("binance.baseURL"), symbol)
-	body, err := sendRequest("GET", url)
+func getLastPrice(ctx context.Context, symbol string) (string, error) {
+	url := fmt.Sprintf("%s/api/v3/ticker/price?symbol=%s", config.C.Binance.BaseURL, symbol)
+	body, err := sendRequest(ctx, "GET", url)
 	if err != nil {
 		return "", err
 	}
@@ -259,8 +263,8 @@ func getLastPrice(symbol string) (string, error) {
 	return res.Price, nil
 }
 
-func getPriceFloat(symbol string) float64 {
-	priceStr, err := getLastPrice(symbol)
+func getPriceFloat(ctx context.Context, symbol string) float64 {
+	priceStr, err := getLastPrice(ctx, symbol)
 	if err != nil {
 		return 0
 	}
@@ -268,40 +272,48 @@ func getPriceFloat(symbol string) float64 {
 	return price
 }
 
-func fetchPrice(mult float64, symbol string) string {
-	price := getPriceFloat(symbol)
+func fetchPrice(ctx context.Context, mult float64, symbol string) string {
+	price := getPriceFloat(ctx, symbol)
 	return fmt.Sprintf("%.8f", price*mult)
 }
 
+// binanceRecvWindow is how long, in milliseconds, the exchange accepts a
+// signed request after its timestamp.
+func binanceRecvWindow() string {
+	return strconv.FormatInt(config.C.Binance.RecvWindow, 10)
+}
+
 func signQuery(query string) string {
-	mac := hmac.New(sha256.New, []byte(viper.GetString("binance.apiSecret")))
+	mac := hmac.New(sha256.New, []byte(config.C.Binance.APISecret))
 	mac.Write([]byte(query))
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-func sendRequest(method, url string) (string, error) {
+func sendRequest(ctx context.Context, method, url string) (string, error) {
 	req, err := http.NewRequest(method, url, bytes.NewBuffer(nil))
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("X-MBX-APIKEY", viper.GetString("binance.apiKey"))
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	req.Header.Set("X-MBX-APIKEY", config.C.Binance.APIKey)
+	status, body, err := doExchangeRequest(ctx, req)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
+	if status < 200 || status > 299 {
+		return "", parseExchangeError(status, body)
+	}
 	return string(body), nil
 }
 
-func storeOrderInMongo(req models.OrderRequest, response, userId string) (models.PlacedOrder, error) {
+// storeOrderInMongo saves an order the exchange accepted. Anything that is
+// not an order or order list acknowledgement is refused.
+func storeOrderInMongo(ctx context.Context, req models.OrderRequest, response, userId string) (models.PlacedOrder, error) {
 	var parsedResp models.PlacedOrder
-	err := json.Unmarshal([]byte(response), &parsedResp)
-	if err != nil {
-		parsedResp = models.PlacedOrder{
-			// Response: response,
-		}
+	if err := json.Unmarshal([]byte(response), &parsedResp); err != nil {
+		return parsedResp, fmt.Errorf("unexpected exchange response: %v", err)
+	}
+	if parsedResp.OrderID == 0 && parsedResp.OrderListID == 0 {
+		return parsedResp, fmt.Errorf("exchange did not acknowledge the order")
 	}
 
 	parsedResp.UserID = userId
@@ -319,7 +331,7 @@ func storeOrderInMongo(req models.OrderRequest, response, userId string) (models
 	// parsedResp.Response = response
 
 	var result map[string]interface{}
-	err = json.Unmarshal([]byte(response), &result)
+	err := json.Unmarshal([]byte(response), &result)
 	if err != nil {
 		fmt.Println("Error parsing JSON:", err)
 	}
@@ -332,7 +344,7 @@ func storeOrderInMongo(req models.OrderRequest, response, userId string) (models
 	}
 
 	// helper.ExtractSection()
-	err = repository.IRepo.Create("orders", &parsedResp)
+	err = repository.IRepo.Create(ctx, "orders", &parsedResp)
 	if err != nil {
 		return parsedResp, fmt.Errorf("failed to save order in MongoDB: %v", err)
 	}
@@ -347,40 +359,37 @@ func oppositeSide(side string) string {
 	return "BUY"
 }
 
-func (os OrderSerices) GetSymbolPrice(symbol string) (string, error) {
-	return fetchPrice(1.00, symbol), nil
+func (os OrderSerices) GetSymbolPrice(ctx context.Context, symbol string) (string, error) {
+	return fetchPrice(ctx, 1.00, symbol), nil
 }
 
-func (os OrderSerices) CancelOCOOrder(orderListId int64, symbol string) (string, error) {
+func (os OrderSerices) CancelOCOOrder(ctx context.Context, orderListId int64, symbol string) (string, error) {
 	endpoint := "/api/v3/orderList"
 	params := url.Values{}
 	params.Add("orderListId", fmt.Sprintf("%d", orderListId))
 	params.Add("symbol", symbol)
 	params.Add("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	params.Add("recvWindow", "5000")
+	params.Add("recvWindow", binanceRecvWindow())
 
 	query := params.Encode()
-	signature := utils.Sign(query, viper.GetString("binance.apiSecret"))
+	signature := utils.Sign(query, config.C.Binance.APISecret)
 	query += "&signature=" + signature
 
-	fullURL := fmt.Sprintf("%s%s?%s", viper.GetString("binance.baseURL"), endpoint, query)
+	fullURL := fmt.Sprintf("%s%s?%s", config.C.Binance.BaseURL, endpoint, query)
 
 	req, err := http.NewRequest("DELETE", fullURL, nil)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("X-MBX-APIKEY", viper.GetString("binance.apiKey"))
+	req.Header.Set("X-MBX-APIKEY", config.C.Binance.APIKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	status, body, err := doExchangeRequest(ctx, req)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("Binance API error: %s", body)
+	if status != 200 {
+		return "", parseExchangeError(status, body)
 	}
 
 	return string(body), nil
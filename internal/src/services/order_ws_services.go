@@ -1,53 +1,54 @@
 package services
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"os/signal"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"go.mongodb.org/mongo-driver/bson"
 
+	"exdex/config"
+	models "exdex/internal/src/model"
 	"exdex/internal/src/repository"
 )
 
-const (
-	// Testnet URLs (hardcoded)
-	BaseURL   = "https://testnet.binance.vision"
-	WSBaseURL = "wss://stream.testnet.binance.vision:9443/ws/"
-
-	// Testnet API Keys (hardcoded - FOR TESTING ONLY!)
-	APIKey    = "b5J1Wa8FEi7TVeeO6jLOsWBdEdmPsGmwB4gxJXT0F4YKlQx3VXmTtSeVn4Wuhwta"
-	SecretKey = "npG6JapFCZ9B3iA39ODdpdOkLOOzqoK8S1O5E7I9vgXJ1Axh28ICpcf3c2Rmfd8f"
-)
-
-func OrderWSHandler() {
+// OrderWSHandler streams the account's user data until ctx is done, then
+// closes the stream and its listen key.
+func OrderWSHandler(ctx context.Context) error {
 	client := NewBinanceClient()
 
 	// Get listen key
 	log.Println("Getting listen key...")
 	listenKey, err := client.GetListenKey()
 	if err != nil {
-		log.Fatalf("Failed to get listen key: %v", err)
+		return fmt.Errorf("failed to get listen key: %w", err)
 	}
 
 	log.Printf("Listen key obtained: %s", listenKey[:10]+"...")
+	userStream.listenKeyIssued()
 
 	// Start streaming user data
 	log.Println("Starting user data stream...")
-	if err := client.StreamUserData(listenKey); err != nil {
-		log.Fatalf("Stream error: %v", err)
+	streamErr := client.StreamUserData(ctx, listenKey)
+
+	// The stream is gone either way; do not leave the key open on the exchange.
+	if err := client.CloseListenKey(listenKey); err != nil {
+		log.Printf("Failed to close listen key: %v", err)
+	}
+	if streamErr != nil {
+		return fmt.Errorf("stream error: %w", streamErr)
 	}
+	return nil
 }
 
 // Structs for API responses
@@ -86,6 +87,19 @@ type ExecutionReport struct {
 	QuoteOrderQty            string      `json:"Q"` // Quote Order Qty
 }
 
+// AccountPosition is the outboundAccountPosition event sent after every
+// balance change on the account.
+type AccountPosition struct {
+	EventType  string `json:"e"`
+	EventTime  int64  `json:"E"`
+	LastUpdate int64  `json:"u"`
+	Balances   []struct {
+		Asset  string `json:"a"`
+		Free   string `json:"f"`
+		Locked string `json:"l"`
+	} `json:"B"`
+}
+
 type WSMessage struct {
 	Stream string          `json:"stream,omitempty"`
 	Data   ExecutionReport `json:"data,omitempty"`
@@ -101,12 +115,11 @@ type BinanceClient struct {
 }
 
 func NewBinanceClient() *BinanceClient {
-	log.Println("🧪 Running on Binance Testnet - Hardcoded credentials")
 	return &BinanceClient{
-		APIKey:    APIKey,
-		SecretKey: SecretKey,
-		BaseURL:   BaseURL,
-		WSBaseURL: WSBaseURL,
+		APIKey:    config.C.Binance.APIKey,
+		SecretKey: config.C.Binance.APISecret,
+		BaseURL:   config.C.Binance.BaseURL,
+		WSBaseURL: config.C.Binance.WSBaseURL,
 	}
 }
 
@@ -131,23 +144,16 @@ func (c *BinanceClient) GetListenKey() (string, error) {
 
 	req.Header.Set("X-MBX-APIKEY", c.APIKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	status, body, err := doExchangeRequest(context.Background(), req)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	log.Printf("Response status: %d", resp.StatusCode)
+	log.Printf("Response status: %d", status)
 	log.Printf("Response body: %s", string(body))
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+	if status != 200 {
+		return "", parseExchangeError(status, body)
 	}
 
 	var response ListenKeyResponse
@@ -173,23 +179,48 @@ func (c *BinanceClient) KeepListenKeyAlive(listenKey string) error {
 	req.Header.Set("X-MBX-APIKEY", c.APIKey)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	status, body, err := doExchangeRequest(context.Background(), req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	if status != 200 {
+		return parseExchangeError(status, body)
+	}
+	return nil
+}
 
+// CloseListenKey invalidates the listen key, ending its stream on the
+// exchange side.
+func (c *BinanceClient) CloseListenKey(listenKey string) error {
+	endpoint := "/api/v3/userDataStream"
+
+	data := url.Values{}
+	data.Set("listenKey", listenKey)
+
+	req, err := http.NewRequest("DELETE", c.BaseURL+endpoint+"?"+data.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-MBX-APIKEY", c.APIKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, body, err := doExchangeRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return parseExchangeError(status, body)
+	}
 	return nil
 }
 
-// Connect to user data stream and handle order execution events
-func (c *BinanceClient) StreamUserData(listenKey string) error {
+// Connect to user data stream and handle order execution events until the
+// connection drops or ctx is done.
+func (c *BinanceClient) StreamUserData(ctx context.Context, listenKey string) error {
 	wsURL := c.WSBaseURL + listenKey
-
-	// Setup interrupt handler
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
+	// Reports being handled when ctx ends still get stored.
+	handleCtx := context.WithoutCancel(ctx)
 
 	// Connect to WebSocket
 	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
@@ -199,6 +230,16 @@ func (c *BinanceClient) StreamUserData(listenKey string) error {
 	defer conn.Close()
 
 	log.Println("Connected to Binance User Data Stream")
+	userStream.setConnected(true)
+	defer userStream.setConnected(false)
+	conn.SetPingHandler(func(data string) error {
+		userStream.seen(false)
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	conn.SetPongHandler(func(string) error {
+		userStream.seen(false)
+		return nil
+	})
 
 	// Setup keep-alive ticker (every 30 minutes)
 	keepAliveTicker := time.NewTicker(30 * time.Minute)
@@ -219,16 +260,25 @@ func (c *BinanceClient) StreamUserData(listenKey string) error {
 				log.Printf("WebSocket read error: %v", err)
 				return
 			}
+			userStream.seen(true)
 
 			var wsMsg WSMessage
 			if err := json.Unmarshal(message, &wsMsg); err != nil {
 				log.Printf("JSON unmarshal error: %v", err)
 				continue
 			}
+			observeStreamLag("user_data", wsMsg.EventTime)
 
 			// Handle execution report (order updates)
 			if wsMsg.EventType == "executionReport" {
-				handleExecutionReport(wsMsg.ExecutionReport)
+				handleExecutionReport(handleCtx, wsMsg.ExecutionReport)
+			} else if wsMsg.EventType == "outboundAccountPosition" {
+				var pos AccountPosition
+				if err := json.Unmarshal(message, &pos); err != nil {
+					log.Printf("JSON unmarshal error: %v", err)
+					continue
+				}
+				handleAccountPosition(pos)
 			}
 		}
 	}()
@@ -238,8 +288,8 @@ func (c *BinanceClient) StreamUserData(listenKey string) error {
 		select {
 		case <-done:
 			return nil
-		case <-interrupt:
-			log.Println("Interrupt received, closing connection...")
+		case <-ctx.Done():
+			log.Println("Closing user data stream...")
 
 			// Send close message
 			err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
@@ -258,6 +308,8 @@ func (c *BinanceClient) StreamUserData(listenKey string) error {
 			log.Println("Sending keep-alive for listen key...")
 			if err := c.KeepListenKeyAlive(listenKey); err != nil {
 				log.Printf("Failed to keep listen key alive: %v", err)
+			} else {
+				userStream.keptAlive()
 			}
 		case <-pingTicker.C:
 			// Send ping to keep connection alive
@@ -270,7 +322,13 @@ func (c *BinanceClient) StreamUserData(listenKey string) error {
 }
 
 // Handle order execution events
-func handleExecutionReport(report ExecutionReport) {
+func handleExecutionReport(ctx context.Context, report ExecutionReport) {
+	recordExecutionReport(ctx, report)
+	notifyExecutionListeners(report)
+}
+
+// recordExecutionReport updates the stored order and notifies its owner.
+func recordExecutionReport(ctx context.Context, report ExecutionReport) {
 	log.Printf("=== ORDER EXECUTION REPORT ===")
 	log.Printf("Symbol: %s", report.Symbol)
 	log.Printf("Order ID: %d", report.OrderID)
@@ -298,13 +356,16 @@ func handleExecutionReport(report ExecutionReport) {
 			filter := bson.M{
 				"order_id": report.OrderID,
 			}
-			update := bson.M{
-				"$set": bson.M{
-					"status": "filled",
-				},
+			set := bson.M{
+				"status":       "filled",
+				"executed_qty": report.CumulativeFilledQuantity,
 			}
+			if report.CumulativeQuoteQty != "" {
+				set["quote_qty"] = report.CumulativeQuoteQty
+			}
+			update := bson.M{"$set": set}
 
-			err := repository.IRepo.UpdateOne("orders", filter, update, false)
+			err := repository.IRepo.UpdateOne(ctx, "orders", filter, update, false)
 			if err != nil {
 				log.Printf("❌ Failed to update order: %v", err)
 			} else {
@@ -325,4 +386,146 @@ func handleExecutionReport(report ExecutionReport) {
 
 	log.Printf("Transaction Time: %s", time.Unix(report.TransactionTime/1000, 0).Format("2006-01-02 15:04:05"))
 	log.Printf("==============================\n")
+
+	publishExecutionEvents(ctx, report)
+}
+
+var (
+	executionListenersMu sync.RWMutex
+	executionListeners   []func(ExecutionReport)
+	executionQueue       = make(chan ExecutionReport, 1024)
+	executionWorkerOnce  sync.Once
+)
+
+// RegisterExecutionListener subscribes fn to every execution report from the
+// user-data stream. Listeners run one report at a time, in arrival order, on
+// a worker goroutine so they may call the exchange without stalling the stream.
+func RegisterExecutionListener(fn func(ExecutionReport)) {
+	executionListenersMu.Lock()
+	executionListeners = append(executionListeners, fn)
+	executionListenersMu.Unlock()
+
+	executionWorkerOnce.Do(func() {
+		go runExecutionListeners()
+	})
+}
+
+func notifyExecutionListeners(report ExecutionReport) {
+	if !AcceptingOrders() {
+		log.Printf("⚠️  Shutting down, not reacting to the report for order %d", report.OrderID)
+		return
+	}
+	executionListenersMu.RLock()
+	n := len(executionListeners)
+	executionListenersMu.RUnlock()
+	if n == 0 {
+		return
+	}
+
+	select {
+	case executionQueue <- report:
+	default:
+		log.Printf("❌ Execution listener queue full, dropping report for order %d", report.OrderID)
+	}
+}
+
+func runExecutionListeners() {
+	for report := range executionQueue {
+		executionListenersMu.RLock()
+		listeners := executionListeners
+		executionListenersMu.RUnlock()
+
+		for _, fn := range listeners {
+			callExecutionListener(fn, report)
+		}
+	}
+}
+
+func callExecutionListener(fn func(ExecutionReport), report ExecutionReport) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ Execution listener panic on order %d: %v", report.OrderID, r)
+		}
+	}()
+	fn(report)
+}
+
+// lastExecutionUser remembers who owned the order of the latest execution
+// report. Binance sends outboundAccountPosition right after the execution
+// that caused it, on the same stream, so the balance change is attributed
+// to that user. Paper users never appear here: their fills are simulated.
+var (
+	lastExecutionMu   sync.Mutex
+	lastExecutionUser string
+)
+
+func orderOwner(ctx context.Context, orderID int64) string {
+	record, err := repository.FindOne[models.OrderRecord](ctx, "orders", bson.M{"order_id": orderID})
+	if err != nil {
+		return ""
+	}
+	return record.UserID
+}
+
+// publishExecutionEvents pushes the order state change, and the fill if any,
+// to the owner's private WebSocket.
+func publishExecutionEvents(ctx context.Context, report ExecutionReport) {
+	userID := orderOwner(ctx, report.OrderID)
+
+	lastExecutionMu.Lock()
+	lastExecutionUser = userID
+	lastExecutionMu.Unlock()
+
+	if userID == "" {
+		return
+	}
+
+	UserEvents.Publish(userID, models.EventOrderUpdate, models.OrderUpdateEvent{
+		Symbol:        report.Symbol,
+		OrderID:       report.OrderID,
+		ClientOrderID: report.ClientOrderID,
+		Side:          report.Side,
+		Type:          report.OrderType,
+		Status:        report.CurrentOrderStatus,
+		ExecutionType: report.CurrentExecutionType,
+		Price:         report.OrderPrice,
+		Quantity:      report.OrderQuantity,
+		FilledQty:     report.CumulativeFilledQuantity,
+		RejectReason:  report.OrderRejectReason,
+	})
+
+	if report.CurrentExecutionType == "TRADE" {
+		UserEvents.Publish(userID, models.EventOrderFill, models.FillEvent{
+			Symbol:          report.Symbol,
+			OrderID:         report.OrderID,
+			TradeID:         report.TradeID,
+			Side:            report.Side,
+			Price:           report.LastExecutedPrice,
+			Quantity:        report.LastExecutedQuantity,
+			QuoteQty:        report.LastQuoteQty,
+			FilledQty:       report.CumulativeFilledQuantity,
+			Commission:      report.CommissionAmount,
+			CommissionAsset: report.CommissionAsset,
+		})
+	}
+}
+
+// handleAccountPosition forwards a balance change to the live user whose
+// execution caused it. The attribution is consumed, so a later change with
+// no execution before it (a deposit, a transfer) goes to nobody.
+func handleAccountPosition(pos AccountPosition) {
+	lastExecutionMu.Lock()
+	userID := lastExecutionUser
+	lastExecutionUser = ""
+	lastExecutionMu.Unlock()
+
+	if userID == "" {
+		return
+	}
+
+	balances := make([]models.AssetBalance, 0, len(pos.Balances))
+	for _, b := range pos.Balances {
+		balances = append(balances, models.AssetBalance{Asset: b.Asset, Free: b.Free, Locked: b.Locked})
+	}
+	UserEvents.Publish(userID, models.EventBalanceUpdate, models.BalanceEvent{Balances: balances})
 }
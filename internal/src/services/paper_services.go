@@ -0,0 +1,853 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"exdex/config"
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+)
+
+const (
+	paperMatchInterval  = time.Second
+	paperModeCacheTTL   = 30 * time.Second
+	paperDefaultFeeRate = 0.001
+	paperBookDepth      = 100
+)
+
+var ErrPaperInsufficientBalance = errors.New("insufficient paper balance")
+
+// ErrBalanceNotTracked is returned for live users. Their orders all go
+// through the platform's single exchange account, so there is no balance
+// of their own to check; the exchange enforces what the account holds.
+var ErrBalanceNotTracked = errors.New("balance is only tracked for paper accounts")
+
+type PaperServices struct{}
+
+// nextPaperID allocates a paper order ID from a counter shared by every
+// instance. Paper order IDs are negative so they can never collide with
+// exchange IDs.
+func nextPaperID(ctx context.Context) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := repository.IRepo.FindOneAndUpdate(ctx, "counters", bson.M{"_id": "paper_order_id"}, bson.M{"$inc": bson.M{"seq": int64(1)}}, true, &counter)
+	if err != nil {
+		return 0, fmt.Errorf("allocate paper order id: %w", err)
+	}
+	return -counter.Seq, nil
+}
+
+// paperLocks serialises balance changes per user.
+var paperLocks sync.Map
+
+func paperLock(userID string) *sync.Mutex {
+	m, _ := paperLocks.LoadOrStore(userID, &sync.Mutex{})
+	return m.(*sync.Mutex)
+}
+
+type paperModeEntry struct {
+	enabled bool
+	at      time.Time
+}
+
+var paperModeCache sync.Map
+
+// IsPaperUser reports whether the user has switched their account to paper
+// mode. When that cannot be told, callers must not trade: guessing live
+// would send a paper user's orders to the exchange.
+func IsPaperUser(ctx context.Context, userID string) (bool, error) {
+	if v, ok := paperModeCache.Load(userID); ok {
+		e := v.(paperModeEntry)
+		if time.Since(e.at) < paperModeCacheTTL {
+			return e.enabled, nil
+		}
+	}
+
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid user id %q", userID)
+	}
+	user, err := repository.FindOne[models.User](ctx, "users", bson.M{"_id": oid})
+	if err != nil {
+		return false, fmt.Errorf("could not determine trading mode: %w", err)
+	}
+	paperModeCache.Store(userID, paperModeEntry{enabled: user.PaperMode, at: time.Now()})
+	return user.PaperMode, nil
+}
+
+func (p PaperServices) SetMode(ctx context.Context, userID string, enabled bool) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	update := bson.M{"$set": bson.M{"paper_mode": enabled, "updated_at": time.Now()}}
+	if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, update, false); err != nil {
+		return err
+	}
+	paperModeCache.Store(userID, paperModeEntry{enabled: enabled, at: time.Now()})
+	return nil
+}
+
+func paperFeeRate() float64 {
+	if r := config.C.Paper.FeeRate; r > 0 {
+		return r
+	}
+	return paperDefaultFeeRate
+}
+
+func paperInitialBalances() map[string]models.PaperBalance {
+	balances := make(map[string]models.PaperBalance)
+	for asset, amount := range config.C.Paper.InitialBalances {
+		balances[strings.ToUpper(asset)] = models.PaperBalance{Free: amount}
+	}
+	if len(balances) == 0 {
+		balances["USDT"] = models.PaperBalance{Free: 10000}
+	}
+	return balances
+}
+
+func loadPaperAccount(ctx context.Context, userID string) (models.PaperAccount, error) {
+	acct, err := repository.FindOne[models.PaperAccount](ctx, "paper_accounts", bson.M{"user_id": userID})
+	if err == nil {
+		if acct.Balances == nil {
+			acct.Balances = make(map[string]models.PaperBalance)
+		}
+		return acct, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return acct, err
+	}
+
+	now := time.Now()
+	acct = models.PaperAccount{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Balances:  paperInitialBalances(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return acct, repository.IRepo.Insert(ctx, "paper_accounts", &acct)
+}
+
+func savePaperAccount(ctx context.Context, acct *models.PaperAccount) error {
+	acct.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{"balances": acct.Balances, "updated_at": acct.UpdatedAt}}
+	return repository.IRepo.UpdateOne(ctx, "paper_accounts", bson.M{"_id": acct.ID}, update, false)
+}
+
+func (p PaperServices) Account(ctx context.Context, userID string) (models.PaperAccount, error) {
+	mu := paperLock(userID)
+	mu.Lock()
+	defer mu.Unlock()
+	return loadPaperAccount(ctx, userID)
+}
+
+// Reset cancels all open paper orders and restores the initial balances.
+func (p PaperServices) Reset(ctx context.Context, userID string) (models.PaperAccount, error) {
+	mu := paperLock(userID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	open, err := repository.Find[models.OrderRecord](ctx, "orders", bson.M{"user_id": userID, "paper": true, "status": "NEW"}, nil)
+	if err != nil {
+		return models.PaperAccount{}, err
+	}
+	for _, o := range open {
+		setPaperOrderStatus(ctx, o.OrderID, "CANCELED", nil)
+	}
+
+	acct, err := loadPaperAccount(ctx, userID)
+	if err != nil {
+		return acct, err
+	}
+	acct.Balances = paperInitialBalances()
+	return acct, savePaperAccount(ctx, &acct)
+}
+
+// GetUserFreeBalance returns the free balance of the user's paper account,
+// or ErrBalanceNotTracked for live users.
+func GetUserFreeBalance(ctx context.Context, userID, asset string) (float64, error) {
+	paper, err := IsPaperUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !paper {
+		return 0, ErrBalanceNotTracked
+	}
+	acct, err := PaperServices{}.Account(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return acct.Balances[asset].Free, nil
+}
+
+func paperDebitFree(acct *models.PaperAccount, asset string, amount float64) error {
+	b := acct.Balances[asset]
+	if b.Free+1e-12 < amount {
+		return fmt.Errorf("%w: have %.8f %s, need %.8f", ErrPaperInsufficientBalance, b.Free, asset, amount)
+	}
+	b.Free = math.Max(0, b.Free-amount)
+	acct.Balances[asset] = b
+	return nil
+}
+
+func paperCredit(acct *models.PaperAccount, asset string, amount float64) {
+	b := acct.Balances[asset]
+	b.Free += amount
+	acct.Balances[asset] = b
+}
+
+func paperLockFunds(acct *models.PaperAccount, asset string, amount float64) error {
+	if err := paperDebitFree(acct, asset, amount); err != nil {
+		return err
+	}
+	b := acct.Balances[asset]
+	b.Locked += amount
+	acct.Balances[asset] = b
+	return nil
+}
+
+func paperReleaseLocked(acct *models.PaperAccount, asset string, amount, toFree float64) {
+	b := acct.Balances[asset]
+	b.Locked = math.Max(0, b.Locked-amount)
+	b.Free += toFree
+	acct.Balances[asset] = b
+}
+
+// paperSettle books a fill. locked is the amount reserved for the order, or
+// zero for orders paid from free balance. It returns the commission, charged
+// in the asset received like on the exchange.
+func paperSettle(acct *models.PaperAccount, info SymbolInfo, side string, qty, price, locked float64) (float64, string, error) {
+	fee := paperFeeRate()
+	quote := qty * price
+
+	if side == "BUY" {
+		if locked > 0 {
+			paperReleaseLocked(acct, info.QuoteAsset, locked, math.Max(0, locked-quote))
+		} else if err := paperDebitFree(acct, info.QuoteAsset, quote); err != nil {
+			return 0, "", err
+		}
+		paperCredit(acct, info.BaseAsset, qty*(1-fee))
+		return qty * fee, info.BaseAsset, nil
+	}
+
+	if locked > 0 {
+		paperReleaseLocked(acct, info.BaseAsset, locked, math.Max(0, locked-qty))
+	} else if err := paperDebitFree(acct, info.BaseAsset, qty); err != nil {
+		return 0, "", err
+	}
+	paperCredit(acct, info.QuoteAsset, quote*(1-fee))
+	return quote * fee, info.QuoteAsset, nil
+}
+
+// walkBook simulates a market order against the live book. Exactly one of
+// qty or quoteQty is set. It returns the filled base and quote amounts.
+func walkBook(symbol, side string, qty, quoteQty float64) (float64, float64, error) {
+	bids, asks, err := MarketData.OrderBook(symbol, paperBookDepth)
+	levels := asks
+	if side == "SELL" {
+		levels = bids
+	}
+	if err != nil || len(levels) == 0 {
+		price, perr := MarketData.Price(symbol)
+		if perr != nil {
+			return 0, 0, perr
+		}
+		levels = []BookLevel{{Price: price, Qty: math.Inf(1)}}
+	}
+
+	filled, spent := 0.0, 0.0
+	for i, l := range levels {
+		take := l.Qty
+		// Beyond the visible book, keep filling at the last level's price.
+		if i == len(levels)-1 {
+			take = math.Inf(1)
+		}
+		if qty > 0 {
+			take = math.Min(take, qty-filled)
+		} else {
+			take = math.Min(take, (quoteQty-spent)/l.Price)
+		}
+		filled += take
+		spent += take * l.Price
+		if (qty > 0 && filled >= qty) || (qty == 0 && spent >= quoteQty-1e-12) {
+			break
+		}
+	}
+	return filled, spent, nil
+}
+
+func paperOrderResponse(rec models.OrderRecord) *models.OrderResponse {
+	return &models.OrderResponse{
+		Symbol:              rec.Symbol,
+		OrderID:             rec.OrderID,
+		ClientOrderID:       rec.ClientOrderID,
+		Status:              rec.Status,
+		ExecutedQty:         rec.ExecutedQty,
+		CummulativeQuoteQty: rec.QuoteQty,
+	}
+}
+
+func newPaperRecord(ctx context.Context, userID, symbol, side, orderType, qty, price string) (models.OrderRecord, error) {
+	id, err := nextPaperID(ctx)
+	if err != nil {
+		return models.OrderRecord{}, err
+	}
+	return models.OrderRecord{
+		UserID:        userID,
+		Symbol:        strings.ToUpper(symbol),
+		Side:          side,
+		Type:          orderType,
+		Quantity:      qty,
+		Price:         price,
+		OrderID:       id,
+		ClientOrderID: fmt.Sprintf("paper_%d", -id),
+		Status:        "NEW",
+		Paper:         true,
+		CreatedAt:     primitive.NewDateTimeFromTime(time.Now()),
+	}, nil
+}
+
+func paperMarket(ctx context.Context, symbol, side, quantity, quoteQty, clientID, userID string) (*models.OrderResponse, error) {
+	symbol = strings.ToUpper(symbol)
+	info, err := GetSymbolInfo(symbol)
+	if err != nil {
+		return nil, err
+	}
+	qty, _ := strconv.ParseFloat(quantity, 64)
+	quote, _ := strconv.ParseFloat(quoteQty, 64)
+	if qty <= 0 && quote <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+
+	filled, spent, err := walkBook(symbol, side, qty, quote)
+	if err != nil {
+		return nil, err
+	}
+	if quote > 0 {
+		filled, _ = strconv.ParseFloat(info.FormatQuantity(filled), 64)
+		spent = math.Min(spent, quote)
+	}
+	if filled <= 0 {
+		return nil, errors.New("order too small to fill")
+	}
+	avg := spent / filled
+
+	rec, err := newPaperRecord(ctx, userID, symbol, side, "MARKET", info.FormatQuantity(filled), "")
+	if err != nil {
+		return nil, err
+	}
+
+	mu := paperLock(userID)
+	mu.Lock()
+	acct, err := loadPaperAccount(ctx, userID)
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+	commission, commissionAsset, err := paperSettle(&acct, info, side, filled, avg, 0)
+	if err == nil {
+		err = savePaperAccount(ctx, &acct)
+	}
+	mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if clientID != "" {
+		rec.ClientOrderID = clientID
+	}
+	rec.Status = "FILLED"
+	rec.ExecutedQty = strconv.FormatFloat(filled, 'f', -1, 64)
+	rec.QuoteQty = strconv.FormatFloat(spent, 'f', -1, 64)
+	if err := repository.IRepo.Insert(ctx, "orders", &rec); err != nil {
+		return nil, err
+	}
+
+	emitPaperExecution(ctx, rec, "NEW", "NEW", 0, 0, 0, 0, "")
+	emitPaperExecution(ctx, rec, "TRADE", "FILLED", filled, avg, filled, commission, commissionAsset)
+	publishPaperBalances(userID, acct, info)
+	return paperOrderResponse(rec), nil
+}
+
+func PaperMarketOrder(ctx context.Context, symbol, side, quantity, userID string) (*models.OrderResponse, error) {
+	return paperMarket(ctx, symbol, side, quantity, "", "", userID)
+}
+
+func PaperMarketQuoteOrder(ctx context.Context, symbol, side, quoteQty, userID string) (*models.OrderResponse, error) {
+	return paperMarket(ctx, symbol, side, "", quoteQty, "", userID)
+}
+
+func PaperMarketOrderWithTPSL(ctx context.Context, symbol, side, quantity, tp, sl, userID string) (*models.OrderResponse, *models.OCOOrderResponse, error) {
+	order, err := PaperMarketOrder(ctx, symbol, side, quantity, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A BUY leaves the fee out of the base received, so only the net
+	// quantity can be locked for the exits.
+	exitQty := order.ExecutedQty
+	if side == "BUY" {
+		info, err := GetSymbolInfo(symbol)
+		if err != nil {
+			return order, nil, err
+		}
+		executed, _ := strconv.ParseFloat(order.ExecutedQty, 64)
+		exitQty = info.FormatQuantity(executed * (1 - paperFeeRate()))
+	}
+	oco, err := paperPlaceOCO(ctx, symbol, oppositeSide(side), exitQty, tp, sl, userID)
+	if err != nil {
+		return order, nil, err
+	}
+	return order, oco, nil
+}
+
+func PaperLimitOrder(ctx context.Context, symbol, side, quantity, price, userID string) (*models.OrderResponse, error) {
+	return paperLimit(ctx, symbol, side, quantity, price, "", "", "", userID)
+}
+
+// PaperLimitOrderWithTPSL places a limit order that gets an OCO take-profit /
+// stop-loss pair attached once it fills.
+func PaperLimitOrderWithTPSL(ctx context.Context, symbol, side, quantity, price, tp, sl, userID string) (*models.OrderResponse, error) {
+	return paperLimit(ctx, symbol, side, quantity, price, tp, sl, "", userID)
+}
+
+func paperLimit(ctx context.Context, symbol, side, quantity, price, tp, sl, clientID, userID string) (*models.OrderResponse, error) {
+	symbol = strings.ToUpper(symbol)
+	info, err := GetSymbolInfo(symbol)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := parsePositive("quantity", quantity)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := parsePositive("price", price)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := newPaperRecord(ctx, userID, symbol, side, "LIMIT", quantity, price)
+	if err != nil {
+		return nil, err
+	}
+	if clientID != "" {
+		rec.ClientOrderID = clientID
+	}
+	rec.TakeProfitPrice = tp
+	rec.StopLossPrice = sl
+	if side == "BUY" {
+		rec.LockedAsset, rec.LockedAmount = info.QuoteAsset, qty*limit
+	} else {
+		rec.LockedAsset, rec.LockedAmount = info.BaseAsset, qty
+	}
+
+	mu := paperLock(userID)
+	mu.Lock()
+	acct, err := loadPaperAccount(ctx, userID)
+	if err == nil {
+		err = paperLockFunds(&acct, rec.LockedAsset, rec.LockedAmount)
+	}
+	if err == nil {
+		err = savePaperAccount(ctx, &acct)
+	}
+	mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repository.IRepo.Insert(ctx, "orders", &rec); err != nil {
+		return nil, err
+	}
+	emitPaperExecution(ctx, rec, "NEW", "NEW", 0, 0, 0, 0, "")
+	publishPaperBalances(userID, acct, info)
+
+	// A marketable limit takes liquidity straight away, at the better price.
+	if current, err := MarketData.Price(symbol); err == nil {
+		if (side == "BUY" && current <= limit) || (side == "SELL" && current >= limit) {
+			fillPaperOrder(ctx, rec, current)
+			if err := repository.IRepo.FindOneWhere(ctx, "orders", bson.M{"order_id": rec.OrderID}, &rec); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paperOrderResponse(rec), nil
+}
+
+// paperPlaceOCO rests a take-profit limit and a stop-loss leg that cancel
+// each other when one fills.
+func paperPlaceOCO(ctx context.Context, symbol, side, quantity, tp, sl, userID string) (*models.OCOOrderResponse, error) {
+	symbol = strings.ToUpper(symbol)
+	info, err := GetSymbolInfo(symbol)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := parsePositive("quantity", quantity)
+	if err != nil {
+		return nil, err
+	}
+	tpF, err := parsePositive("take profit", tp)
+	if err != nil {
+		return nil, err
+	}
+	slF, err := parsePositive("stop loss", sl)
+	if err != nil {
+		return nil, err
+	}
+
+	listID, err := nextPaperID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tpLeg, err := newPaperRecord(ctx, userID, symbol, side, "LIMIT_MAKER", quantity, tp)
+	if err != nil {
+		return nil, err
+	}
+	slLeg, err := newPaperRecord(ctx, userID, symbol, side, "STOP_LOSS_LIMIT", quantity, sl)
+	if err != nil {
+		return nil, err
+	}
+	slLeg.StopPrice = sl
+	for _, leg := range []*models.OrderRecord{&tpLeg, &slLeg} {
+		leg.OrderListID = listID
+		if side == "BUY" {
+			leg.LockedAsset, leg.LockedAmount = info.QuoteAsset, qty*math.Max(tpF, slF)
+		} else {
+			leg.LockedAsset, leg.LockedAmount = info.BaseAsset, qty
+		}
+	}
+
+	// Both legs share one reservation.
+	mu := paperLock(userID)
+	mu.Lock()
+	acct, err := loadPaperAccount(ctx, userID)
+	if err == nil {
+		err = paperLockFunds(&acct, tpLeg.LockedAsset, tpLeg.LockedAmount)
+	}
+	if err == nil {
+		err = savePaperAccount(ctx, &acct)
+	}
+	mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, leg := range []models.OrderRecord{tpLeg, slLeg} {
+		if err := repository.IRepo.Insert(ctx, "orders", &leg); err != nil {
+			return nil, err
+		}
+		emitPaperExecution(ctx, leg, "NEW", "NEW", 0, 0, 0, 0, "")
+	}
+	publishPaperBalances(userID, acct, info)
+
+	return &models.OCOOrderResponse{
+		OrderListID:       listID,
+		ContingencyType:   "OCO",
+		ListStatusType:    "EXEC_STARTED",
+		ListOrderStatus:   "EXECUTING",
+		ListClientOrderID: fmt.Sprintf("paper_%d", -listID),
+	}, nil
+}
+
+// PaperCancelOrder cancels an open paper order, and its OCO sibling if any,
+// releasing the reserved balance.
+func PaperCancelOrder(ctx context.Context, orderID int64) error {
+	rec, err := repository.FindOne[models.OrderRecord](ctx, "orders", bson.M{"order_id": orderID, "paper": true})
+	if err != nil {
+		return fmt.Errorf("unknown paper order %d", orderID)
+	}
+
+	mu := paperLock(rec.UserID)
+	mu.Lock()
+	if err := repository.IRepo.FindOneWhere(ctx, "orders", bson.M{"order_id": orderID}, &rec); err != nil || rec.Status != "NEW" {
+		mu.Unlock()
+		return fmt.Errorf("paper order %d is not open", orderID)
+	}
+
+	legs := []models.OrderRecord{rec}
+	if rec.OrderListID != 0 {
+		var siblings []models.OrderRecord
+		repository.IRepo.FindByFilter(ctx, "orders", &siblings, bson.M{"order_list_id": rec.OrderListID, "status": "NEW"}, nil)
+		legs = siblings
+	}
+
+	acct, err := loadPaperAccount(ctx, rec.UserID)
+	if err != nil {
+		mu.Unlock()
+		return err
+	}
+
+	// Claim the order while it is still open; a fill that got there first wins.
+	cancel := bson.M{"$set": bson.M{"status": "CANCELED"}}
+	claimed, err := repository.IRepo.UpdateOneMatched(ctx, "orders", bson.M{"order_id": orderID, "status": "NEW"}, cancel)
+	if err != nil || !claimed {
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("paper order %d is not open", orderID)
+	}
+	for _, leg := range legs {
+		if leg.OrderID != orderID {
+			setPaperOrderStatus(ctx, leg.OrderID, "CANCELED", nil)
+		}
+	}
+	paperReleaseLocked(&acct, rec.LockedAsset, rec.LockedAmount, rec.LockedAmount)
+	err = savePaperAccount(ctx, &acct)
+	mu.Unlock()
+
+	for _, leg := range legs {
+		leg.Status = "CANCELED"
+		emitPaperExecution(ctx, leg, "CANCELED", "CANCELED", 0, 0, 0, 0, "")
+	}
+	if info, ierr := GetSymbolInfo(rec.Symbol); ierr == nil {
+		publishPaperBalances(rec.UserID, acct, info)
+	}
+	return err
+}
+
+func setPaperOrderStatus(ctx context.Context, orderID int64, status string, extra bson.M) {
+	set := bson.M{"status": status}
+	for k, v := range extra {
+		set[k] = v
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "orders", bson.M{"order_id": orderID}, bson.M{"$set": set}, false); err != nil {
+		log.Printf("❌ Paper order %d: failed to set status %s: %v", orderID, status, err)
+	}
+}
+
+// PaperPlaceOrder mirrors OrderSerices.PlaceOrder for paper accounts.
+func PaperPlaceOrder(ctx context.Context, req models.OrderRequest, userID string) (models.PlacedOrder, error) {
+	price, err := MarketData.Price(req.Symbol)
+	if err != nil {
+		return models.PlacedOrder{}, err
+	}
+	info, err := GetSymbolInfo(req.Symbol)
+	if err != nil {
+		return models.PlacedOrder{}, err
+	}
+
+	tp, sl := "", ""
+	if req.WithTPSL {
+		tpF, slF := req.TPPrice, req.SLPrice
+		if !req.UseAbs {
+			if req.Side == "BUY" {
+				tpF, slF = price*(1+req.TPMul), price*(1-req.SLMul)
+			} else {
+				tpF, slF = price*(1-req.TPMul), price*(1+req.SLMul)
+			}
+		}
+		tp, sl = info.FormatPrice(tpF), info.FormatPrice(slF)
+	}
+
+	var order *models.OrderResponse
+	if req.OrderType == "LIMIT" {
+		order, err = paperLimit(ctx, req.Symbol, req.Side, req.Quantity, info.FormatPrice(price), tp, sl, "", userID)
+	} else if req.WithTPSL {
+		order, _, err = PaperMarketOrderWithTPSL(ctx, req.Symbol, req.Side, req.Quantity, tp, sl, userID)
+	} else {
+		order, err = PaperMarketOrder(ctx, req.Symbol, req.Side, req.Quantity, userID)
+	}
+	if err != nil {
+		return models.PlacedOrder{}, fmt.Errorf("order placement failed: %v", err)
+	}
+
+	return models.PlacedOrder{
+		Symbol:        order.Symbol,
+		UserID:        userID,
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		TransactTime:  time.Now().UnixMilli(),
+		OrigQty:       req.Quantity,
+		ExecutedQty:   order.ExecutedQty,
+		Status:        order.Status,
+		Type:          req.OrderType,
+		Side:          req.Side,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// StartPaperMatcher fills resting paper orders against the live price. It
+// only runs on the instance with cron.is_runner set.
+func StartPaperMatcher() {
+	goWorker(func(ctx context.Context) {
+		ticker := time.NewTicker(paperMatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				matchPaperOrders(ctx)
+			}
+		}
+	})
+}
+
+func matchPaperOrders(ctx context.Context) {
+	open, err := repository.Find[models.OrderRecord](ctx, "orders", bson.M{"paper": true, "status": "NEW"}, nil)
+	if err != nil {
+		log.Printf("❌ Paper matcher: failed to load open orders: %v", err)
+		return
+	}
+
+	prices := make(map[string]float64)
+	for _, o := range open {
+		price, ok := prices[o.Symbol]
+		if !ok {
+			p, err := MarketData.Price(o.Symbol)
+			if err != nil {
+				continue
+			}
+			price = p
+			prices[o.Symbol] = p
+		}
+
+		limit, _ := strconv.ParseFloat(o.Price, 64)
+		stop, _ := strconv.ParseFloat(o.StopPrice, 64)
+		switch {
+		case o.StopPrice != "" && o.Side == "SELL" && price <= stop,
+			o.StopPrice != "" && o.Side == "BUY" && price >= stop:
+			// Triggered stops fill at the market, slippage included.
+			fillPaperOrder(ctx, o, price)
+		case o.StopPrice == "" && o.Side == "BUY" && price <= limit,
+			o.StopPrice == "" && o.Side == "SELL" && price >= limit:
+			fillPaperOrder(ctx, o, limit)
+		}
+	}
+}
+
+// fillPaperOrder fully fills a resting paper order at price.
+func fillPaperOrder(ctx context.Context, o models.OrderRecord, price float64) {
+	info, err := GetSymbolInfo(o.Symbol)
+	if err != nil {
+		return
+	}
+	qty, _ := strconv.ParseFloat(o.Quantity, 64)
+
+	mu := paperLock(o.UserID)
+	mu.Lock()
+	if err := repository.IRepo.FindOneWhere(ctx, "orders", bson.M{"order_id": o.OrderID}, &o); err != nil || o.Status != "NEW" {
+		mu.Unlock()
+		return
+	}
+
+	// Claim the order while it is still open, so it is settled once even
+	// when a cancel or another instance gets to it at the same time.
+	open := o
+	o.Status = "FILLED"
+	o.ExecutedQty = o.Quantity
+	o.QuoteQty = strconv.FormatFloat(qty*price, 'f', -1, 64)
+	claim := bson.M{"$set": bson.M{"status": o.Status, "executed_qty": o.ExecutedQty, "quote_qty": o.QuoteQty}}
+	claimed, err := repository.IRepo.UpdateOneMatched(ctx, "orders", bson.M{"order_id": o.OrderID, "status": "NEW"}, claim)
+	if err != nil || !claimed {
+		mu.Unlock()
+		if err != nil {
+			log.Printf("❌ Paper order %d: failed to claim the fill: %v", o.OrderID, err)
+		}
+		return
+	}
+
+	acct, err := loadPaperAccount(ctx, o.UserID)
+	var commission float64
+	var commissionAsset string
+	if err == nil {
+		commission, commissionAsset, err = paperSettle(&acct, info, o.Side, qty, price, o.LockedAmount)
+	}
+	if err == nil {
+		err = savePaperAccount(ctx, &acct)
+	}
+	if err != nil {
+		// Reopen the order so the matcher tries again.
+		setPaperOrderStatus(ctx, o.OrderID, "NEW", bson.M{"executed_qty": open.ExecutedQty, "quote_qty": open.QuoteQty})
+		mu.Unlock()
+		log.Printf("❌ Paper order %d: settlement failed: %v", o.OrderID, err)
+		return
+	}
+
+	var siblings []models.OrderRecord
+	if o.OrderListID != 0 {
+		repository.IRepo.FindByFilter(ctx, "orders", &siblings, bson.M{"order_list_id": o.OrderListID, "status": "NEW"}, nil)
+		for _, s := range siblings {
+			setPaperOrderStatus(ctx, s.OrderID, "CANCELED", nil)
+		}
+	}
+	mu.Unlock()
+
+	emitPaperExecution(ctx, o, "TRADE", "FILLED", qty, price, qty, commission, commissionAsset)
+	for _, s := range siblings {
+		s.Status = "CANCELED"
+		emitPaperExecution(ctx, s, "CANCELED", "CANCELED", 0, 0, 0, 0, "")
+	}
+	publishPaperBalances(o.UserID, acct, info)
+
+	if o.TakeProfitPrice != "" && o.StopLossPrice != "" && o.OrderListID == 0 {
+		received := qty
+		if o.Side == "BUY" {
+			received = qty * (1 - paperFeeRate())
+		}
+		if _, err := paperPlaceOCO(ctx, o.Symbol, oppositeSide(o.Side), info.FormatQuantity(received), o.TakeProfitPrice, o.StopLossPrice, o.UserID); err != nil {
+			log.Printf("❌ Paper order %d: failed to attach TP/SL: %v", o.OrderID, err)
+		}
+	}
+}
+
+// emitPaperExecution feeds a synthetic execution report through the same
+// pipeline as the exchange's user-data stream.
+func emitPaperExecution(ctx context.Context, o models.OrderRecord, execType, status string, lastQty, lastPrice, cumQty, commission float64, commissionAsset string) {
+	now := time.Now().UnixMilli()
+	report := ExecutionReport{
+		EventType:                "executionReport",
+		EventTime:                now,
+		Symbol:                   o.Symbol,
+		ClientOrderID:            o.ClientOrderID,
+		Side:                     o.Side,
+		OrderType:                o.Type,
+		TimeInForce:              "GTC",
+		OrderQuantity:            o.Quantity,
+		OrderPrice:               o.Price,
+		StopPrice:                o.StopPrice,
+		OrderListID:              o.OrderListID,
+		CurrentExecutionType:     execType,
+		CurrentOrderStatus:       status,
+		OrderID:                  o.OrderID,
+		LastExecutedQuantity:     strconv.FormatFloat(lastQty, 'f', -1, 64),
+		CumulativeFilledQuantity: strconv.FormatFloat(cumQty, 'f', -1, 64),
+		LastExecutedPrice:        strconv.FormatFloat(lastPrice, 'f', -1, 64),
+		CommissionAmount:         strconv.FormatFloat(commission, 'f', -1, 64),
+		CommissionAsset:          commissionAsset,
+		TransactionTime:          now,
+		TradeID:                  -now,
+		LastQuoteQty:             strconv.FormatFloat(lastQty*lastPrice, 'f', -1, 64),
+		CumulativeQuoteQty:       o.QuoteQty,
+	}
+	if o.OrderListID == 0 {
+		report.OrderListID = -1
+	}
+	handleExecutionReport(ctx, report)
+}
+
+func publishPaperBalances(userID string, acct models.PaperAccount, info SymbolInfo) {
+	balances := make([]models.AssetBalance, 0, 2)
+	for _, asset := range []string{info.BaseAsset, info.QuoteAsset} {
+		b := acct.Balances[asset]
+		balances = append(balances, models.AssetBalance{
+			Asset:  asset,
+			Free:   strconv.FormatFloat(b.Free, 'f', -1, 64),
+			Locked: strconv.FormatFloat(b.Locked, 'f', -1, 64),
+		})
+	}
+	UserEvents.Publish(userID, models.EventBalanceUpdate, models.BalanceEvent{Balances: balances})
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+)
+
+// ReconcileResult summarises a reconcile run.
+type ReconcileResult struct {
+	Checked int      `json:"checked"`
+	Updated int      `json:"updated"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// ReconcileOrders asks the exchange for the current state of every order the
+// user has stored as open and saves it, catching fills and cancels missed
+// while the user-data stream was down. Paper orders are skipped.
+func ReconcileOrders(ctx context.Context, userID string) (ReconcileResult, error) {
+	var res ReconcileResult
+	filter := bson.M{
+		"user_id":  userID,
+		"paper":    bson.M{"$ne": true},
+		"order_id": bson.M{"$gt": 0},
+		"status":   bson.M{"$in": []string{"NEW", "PARTIALLY_FILLED"}},
+	}
+	orders, err := repository.Find[models.OrderRecord](ctx, "orders", filter, nil)
+	if err != nil {
+		return res, err
+	}
+
+	for _, o := range orders {
+		res.Checked++
+		body, err := makeRequest(ctx, "GET", "/api/v3/order", fmt.Sprintf("symbol=%s&orderId=%d", o.Symbol, o.OrderID))
+		if err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("order %d: %v", o.OrderID, err))
+			continue
+		}
+		var current models.OrderResponse
+		if err := json.Unmarshal(body, &current); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("order %d: %v", o.OrderID, err))
+			continue
+		}
+		if current.Status == o.Status && current.ExecutedQty == o.ExecutedQty {
+			continue
+		}
+		update := bson.M{"$set": bson.M{
+			"status":       current.Status,
+			"executed_qty": current.ExecutedQty,
+			"quote_qty":    current.CummulativeQuoteQty,
+		}}
+		if err := repository.IRepo.UpdateOne(ctx, "orders", bson.M{"_id": o.ID}, update, false); err != nil {
+			return res, err
+		}
+		res.Updated++
+	}
+	return res, nil
+}
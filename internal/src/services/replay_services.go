@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReplayResult summarises a replay run.
+type ReplayResult struct {
+	Replayed int `json:"replayed"`
+	Skipped  int `json:"skipped"`
+}
+
+// ReplayUserEvents feeds recorded user-data stream events, one JSON object
+// per line, through the same handling as the live stream: the order record
+// is updated, the owner is notified and grid bots and copy trading react to
+// the fill. It runs synchronously so every event is handled when it returns.
+// Both grid and copy handling ignore fills they have already processed, so
+// replaying an overlapping range is safe. Running strategies are not fed;
+// they only exist inside a serving instance.
+func ReplayUserEvents(ctx context.Context, r io.Reader) (ReplayResult, error) {
+	var res ReplayResult
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		var event struct {
+			EventType string `json:"e"`
+		}
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			return res, fmt.Errorf("line %d: %w", line, err)
+		}
+		if event.EventType != "executionReport" {
+			res.Skipped++
+			continue
+		}
+		var report ExecutionReport
+		if err := json.Unmarshal([]byte(text), &report); err != nil {
+			return res, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		recordExecutionReport(ctx, report)
+		callExecutionListener(handleGridExecution, report)
+		if report.CurrentExecutionType == "TRADE" {
+			callExecutionListener(func(r ExecutionReport) { mirrorLeaderFill(ctx, r) }, report)
+		}
+		res.Replayed++
+	}
+	return res, scanner.Err()
+}
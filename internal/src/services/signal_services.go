@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/security"
+	"exdex/server/validator"
+)
+
+var ErrInvalidSignalToken = errors.New("invalid or disabled signal token")
+
+type SignalServices struct{}
+
+// CreateToken issues a new signal token for the user, replacing any previous
+// one. The plain token is only returned here.
+func (s SignalServices) CreateToken(ctx context.Context, userID string) (string, error) {
+	token, err := security.GenerateAPIKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	filter := bson.M{"user_id": userID}
+	update := bson.M{
+		"$set": bson.M{
+			"token_hash": security.HashToken(token),
+			"enabled":    true,
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{
+			"user_id":    userID,
+			"created_at": now,
+		},
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "signal_webhooks", filter, update, true); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s SignalServices) Disable(ctx context.Context, userID string) error {
+	update := bson.M{"$set": bson.M{"enabled": false, "updated_at": time.Now()}}
+	return repository.IRepo.UpdateOne(ctx, "signal_webhooks", bson.M{"user_id": userID}, update, false)
+}
+
+func (s SignalServices) Logs(ctx context.Context, userID string, page, limit int) ([]models.SignalLog, int64, error) {
+	query := bson.M{"user_id": userID}
+	filter := models.Filter{
+		Sort:      "created_at",
+		SortOrder: -1,
+		Limit:     limit,
+		Offset:    (page - 1) * limit,
+	}
+
+	logs, err := repository.FindPage[models.SignalLog](ctx, "signal_logs", query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := repository.IRepo.Count(ctx, "signal_logs", query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// Ingest authenticates a raw signal by its token, validates it, places the
+// order and records the outcome. urlToken takes precedence over the token in
+// the body. The returned log is stored even when validation or placement fails.
+// When the owner may no longer trade, the order is refused and the error is
+// returned alongside the log.
+func (s SignalServices) Ingest(ctx context.Context, urlToken string, raw []byte, sourceIP string) (models.SignalLog, error) {
+	var payload models.SignalPayload
+	parseErr := json.Unmarshal(raw, &payload)
+
+	token := urlToken
+	if token == "" {
+		token = payload.Token
+	}
+	if token == "" {
+		return models.SignalLog{}, ErrInvalidSignalToken
+	}
+
+	hook, err := findSignalHook(ctx, token)
+	if err != nil {
+		return models.SignalLog{}, err
+	}
+
+	payload.Token = ""
+	entry := models.SignalLog{
+		UserID:     hook.UserID,
+		WebhookID:  hook.ID,
+		RawPayload: redactSignalToken(string(raw), token),
+		Payload:    payload,
+		SourceIP:   sourceIP,
+		CreatedAt:  time.Now(),
+	}
+
+	var ownerErr error
+	if parseErr != nil {
+		entry.ValidationError = fmt.Sprintf("invalid JSON: %v", parseErr)
+	} else if err := normalizeSignal(&payload); err != nil {
+		entry.ValidationError = err.Error()
+	} else if ownerErr = authorizeOwnerOrder(ctx, hook.UserID); ownerErr != nil {
+		entry.Valid = true
+		entry.Payload = payload
+		entry.OrderError = ownerErr.Error()
+	} else {
+		entry.Valid = true
+		entry.Payload = payload
+		result, orderID, status, err := executeSignal(ctx, payload, hook.UserID)
+		entry.OrderResult = result
+		entry.OrderID = orderID
+		entry.OrderStatus = status
+		if err != nil {
+			entry.OrderError = err.Error()
+		}
+	}
+
+	if err := repository.IRepo.Insert(ctx, "signal_logs", &entry); err != nil {
+		log.Printf("❌ Failed to store signal log for user %s: %v", hook.UserID, err)
+	}
+	return entry, ownerErr
+}
+
+// SignalTokenOwner returns the user an enabled signal token belongs to.
+func SignalTokenOwner(ctx context.Context, token string) (string, error) {
+	hook, err := findSignalHook(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return hook.UserID, nil
+}
+
+func findSignalHook(ctx context.Context, token string) (models.SignalWebhook, error) {
+	hook, err := repository.FindOne[models.SignalWebhook](ctx, "signal_webhooks", bson.M{"token_hash": security.HashToken(token), "enabled": true})
+	if err != nil {
+		return hook, ErrInvalidSignalToken
+	}
+	return hook, nil
+}
+
+func redactSignalToken(raw, token string) string {
+	return strings.ReplaceAll(raw, token, "***")
+}
+
+// normalizeSignal validates the payload and fills in defaults and canonical casing.
+func normalizeSignal(p *models.SignalPayload) error {
+	if err := validator.Validate(p); err != nil {
+		return err
+	}
+
+	p.Symbol = strings.ToUpper(strings.TrimSpace(p.Symbol))
+	p.Side = strings.ToUpper(strings.TrimSpace(p.Side))
+	p.OrderType = strings.ToUpper(strings.TrimSpace(p.OrderType))
+	p.SizeMode = strings.ToLower(strings.TrimSpace(p.SizeMode))
+
+	if p.Side != "BUY" && p.Side != "SELL" {
+		return fmt.Errorf("side must be buy or sell, got %q", p.Side)
+	}
+	if p.OrderType == "" {
+		p.OrderType = "MARKET"
+	}
+	if p.OrderType != "MARKET" && p.OrderType != "LIMIT" {
+		return fmt.Errorf("order_type must be market or limit, got %q", p.OrderType)
+	}
+	if p.SizeMode == "" {
+		p.SizeMode = "base"
+	}
+	if p.SizeMode != "base" && p.SizeMode != "quote" {
+		return fmt.Errorf("size_mode must be base or quote, got %q", p.SizeMode)
+	}
+
+	if _, err := parsePositive("size", p.Size); err != nil {
+		return err
+	}
+	var price float64
+	if p.OrderType == "LIMIT" {
+		v, err := parsePositive("price", p.Price)
+		if err != nil {
+			return err
+		}
+		price = v
+	}
+
+	if (p.TakeProfit == "") != (p.StopLoss == "") {
+		return errors.New("take_profit and stop_loss must be given together")
+	}
+	if p.TakeProfit != "" {
+		tp, err := parsePositive("take_profit", p.TakeProfit)
+		if err != nil {
+			return err
+		}
+		sl, err := parsePositive("stop_loss", p.StopLoss)
+		if err != nil {
+			return err
+		}
+		if p.Side == "BUY" && tp <= sl {
+			return errors.New("for buy signals take_profit must be above stop_loss")
+		}
+		if p.Side == "SELL" && tp >= sl {
+			return errors.New("for sell signals take_profit must be below stop_loss")
+		}
+		if price > 0 && p.Side == "BUY" && (tp <= price || sl >= price) {
+			return errors.New("for buy signals take_profit must be above and stop_loss below the limit price")
+		}
+		if price > 0 && p.Side == "SELL" && (tp >= price || sl <= price) {
+			return errors.New("for sell signals take_profit must be below and stop_loss above the limit price")
+		}
+	}
+	return nil
+}
+
+func parsePositive(field, value string) (float64, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number", field)
+	}
+	return v, nil
+}
+
+// executeSignal maps a validated signal onto the market and limit order services.
+func executeSignal(ctx context.Context, p models.SignalPayload, userID string) (interface{}, int64, string, error) {
+	withTPSL := p.TakeProfit != ""
+
+	if p.SizeMode == "quote" && p.OrderType == "MARKET" && !withTPSL {
+		order, err := PlaceMarketQuoteOrder(ctx, p.Symbol, p.Side, p.Size, userID)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		return order, order.OrderID, order.Status, nil
+	}
+
+	quantity := p.Size
+	if p.SizeMode == "quote" {
+		qty, err := quoteToBaseQuantity(ctx, p.Symbol, p.Size, p.Price)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		quantity = qty
+	}
+
+	switch {
+	case p.OrderType == "MARKET" && withTPSL:
+		order, oco, err := PlaceMarketOrderWithTPSL(ctx, p.Symbol, p.Side, quantity, p.TakeProfit, p.StopLoss, userID)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		return map[string]interface{}{"order": order, "oco": oco}, order.OrderID, order.Status, nil
+	case p.OrderType == "MARKET":
+		order, err := PlaceMarketOrder(ctx, p.Symbol, p.Side, quantity, userID)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		return order, order.OrderID, order.Status, nil
+	case withTPSL:
+		order, err := PlaceLimitOrderWithTPSL(ctx, p.Symbol, p.Side, quantity, p.Price, p.TakeProfit, p.StopLoss, userID)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		return order, order.OrderID, order.Status, nil
+	default:
+		order, err := PlaceLimitOrder(ctx, p.Symbol, p.Side, quantity, p.Price, userID)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		return order, order.OrderID, order.Status, nil
+	}
+}
+
+// quoteToBaseQuantity converts a quote amount to a base quantity at price, or
+// at the last traded price when price is empty, rounded to the lot size.
+func quoteToBaseQuantity(ctx context.Context, symbol, quote, price string) (string, error) {
+	quoteF, err := strconv.ParseFloat(quote, 64)
+	if err != nil {
+		return "", err
+	}
+	if price == "" {
+		last, err := getLastPrice(ctx, symbol)
+		if err != nil {
+			return "", err
+		}
+		price = last
+	}
+	priceF, err := strconv.ParseFloat(price, 64)
+	if err != nil || priceF <= 0 {
+		return "", fmt.Errorf("no usable price for %s", symbol)
+	}
+
+	info, err := GetSymbolInfo(symbol)
+	if err != nil {
+		return "", err
+	}
+	qty := info.FormatQuantity(quoteF / priceF)
+	if q, _ := strconv.ParseFloat(qty, 64); q <= 0 {
+		return "", fmt.Errorf("quote amount %s is below the minimum lot for %s", quote, symbol)
+	}
+	return qty, nil
+}
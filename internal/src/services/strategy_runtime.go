@@ -0,0 +1,508 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+)
+
+const (
+	strategyQueueSize    = 256
+	strategyPollInterval = 5 * time.Second
+	strategyTickerMaxAge = 10 * time.Second
+	strategyCandleDelay  = 2 * time.Second // let the exchange close the bar
+)
+
+// Strategy is implemented by user strategies hosted in the runtime. Hooks for
+// one instance are called from a single goroutine, so implementations need no
+// locking of their own.
+type Strategy interface {
+	OnTicker(sc *StrategyContext, t StreamTicker)
+	OnCandle(sc *StrategyContext, c models.Candle)
+	OnExecution(sc *StrategyContext, r ExecutionReport)
+}
+
+// strategyKinds builds a strategy from an instance's config, rejecting
+// invalid configs.
+var strategyKinds = map[string]func(config map[string]interface{}) (Strategy, error){
+	"price_alert": newPriceAlertStrategy,
+	"sma_cross":   newSMACrossStrategy,
+}
+
+// StrategyContext is what a strategy sees of its instance: config, mutable
+// state and order helpers scoped to the instance's user and symbol.
+type StrategyContext struct {
+	ID     primitive.ObjectID
+	UserID string
+	Symbol string
+	Config map[string]interface{}
+	State  map[string]interface{}
+	dirty  bool
+}
+
+func (sc *StrategyContext) Set(key string, value interface{}) {
+	sc.State[key] = value
+	sc.dirty = true
+}
+
+func (sc *StrategyContext) Bool(key string) bool {
+	b, _ := sc.State[key].(bool)
+	return b
+}
+
+func (sc *StrategyContext) Float(key string) float64 {
+	return toFloat(sc.State[key])
+}
+
+// Floats reads a number list back from state, which Mongo decodes as
+// primitive.A after a restart.
+func (sc *StrategyContext) Floats(key string) []float64 {
+	switch v := sc.State[key].(type) {
+	case []float64:
+		return v
+	case primitive.A:
+		out := make([]float64, 0, len(v))
+		for _, x := range v {
+			out = append(out, toFloat(x))
+		}
+		return out
+	}
+	return nil
+}
+
+func (sc *StrategyContext) ConfigFloat(key string, def float64) float64 {
+	if v, ok := sc.Config[key]; ok {
+		return toFloat(v)
+	}
+	return def
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+// MarketOrder places a market order for the instance, in paper mode when the
+// user is.
+func (sc *StrategyContext) MarketOrder(side, quantity string) (*models.OrderResponse, error) {
+	ctx := context.Background()
+	if err := authorizeOwnerOrder(ctx, sc.UserID); err != nil {
+		return nil, err
+	}
+	clientID := Strategies.claimClientID(sc.ID)
+	order, err := placeMarketOrder(ctx, sc.Symbol, side, quantity, clientID, sc.UserID)
+	if err != nil {
+		Strategies.clientOwners.Delete(clientID)
+		return nil, err
+	}
+	Strategies.tagOrder(ctx, order.OrderID, clientID, sc.ID)
+	return order, nil
+}
+
+func (sc *StrategyContext) LimitOrder(side, quantity, price string) (*models.OrderResponse, error) {
+	ctx := context.Background()
+	if err := authorizeOwnerOrder(ctx, sc.UserID); err != nil {
+		return nil, err
+	}
+	clientID := Strategies.claimClientID(sc.ID)
+	order, err := placeLimitOrder(ctx, sc.Symbol, side, quantity, price, "GTC", clientID, sc.UserID)
+	if err != nil {
+		Strategies.clientOwners.Delete(clientID)
+		return nil, err
+	}
+	Strategies.tagOrder(ctx, order.OrderID, clientID, sc.ID)
+	return order, nil
+}
+
+func (sc *StrategyContext) CancelOrder(orderID int64) error {
+	return CancelOrder(context.Background(), sc.Symbol, orderID)
+}
+
+// Alert pushes an alert to the user's private WebSocket.
+func (sc *StrategyContext) Alert(message, price string) {
+	PublishAlert(sc.UserID, models.AlertEvent{
+		AlertID: sc.ID.Hex(),
+		Symbol:  sc.Symbol,
+		Message: message,
+		Price:   price,
+	})
+}
+
+type strategyEvent struct {
+	ticker *StreamTicker
+	candle *models.Candle
+	exec   *ExecutionReport
+}
+
+type strategyRunner struct {
+	inst       models.StrategyInstance
+	strategy   Strategy
+	sc         *StrategyContext
+	events     chan strategyEvent
+	cancel     context.CancelFunc
+	paused     atomic.Bool
+	eventCount atomic.Int64
+	lastEvent  atomic.Int64 // unix nanos
+}
+
+// StrategyRuntime hosts loaded strategy instances, one goroutine each, and
+// routes tickers, candles and executions to them.
+type StrategyRuntime struct {
+	mu          sync.RWMutex
+	runners     map[primitive.ObjectID]*strategyRunner
+	orderOwners sync.Map // order ID -> instance ID
+	// clientOwners maps the client order id of an order being placed to its
+	// instance, so executions reported before placement returns (paper
+	// fills are reported straight away) still reach it.
+	clientOwners sync.Map
+	startOnce    sync.Once
+	hosting      atomic.Bool
+}
+
+// ErrStrategyRuntimeUnavailable is returned on nodes that do not host the
+// strategy runtime, which only runs where cron.is_runner is set.
+var ErrStrategyRuntimeUnavailable error = strategyRuntimeUnavailable{}
+
+type strategyRuntimeUnavailable struct{}
+
+func (strategyRuntimeUnavailable) Error() string {
+	return "strategies are hosted on the scheduler node, this node cannot run them"
+}
+
+func (strategyRuntimeUnavailable) HTTPStatus() int { return http.StatusServiceUnavailable }
+
+func (strategyRuntimeUnavailable) ErrorCode() string { return "STRATEGY_RUNTIME_UNAVAILABLE" }
+
+// requireHost refuses changes to instances on nodes without the runtime:
+// they would be saved as running but never receive events.
+func (rt *StrategyRuntime) requireHost() error {
+	if !rt.hosting.Load() {
+		return ErrStrategyRuntimeUnavailable
+	}
+	return nil
+}
+
+var Strategies = &StrategyRuntime{runners: make(map[primitive.ObjectID]*strategyRunner)}
+
+// StartStrategyRuntime hooks the runtime into the ticker and execution feeds
+// and reloads instances that were running or paused before a restart.
+func StartStrategyRuntime() {
+	ctx := context.Background()
+	Strategies.startOnce.Do(func() {
+		RegisterExecutionListener(Strategies.routeExecution)
+		go Strategies.fanOutTickers()
+		Strategies.hosting.Store(true)
+	})
+
+	filter := bson.M{"status": bson.M{"$in": []string{models.StrategyStatusRunning, models.StrategyStatusPaused}}}
+	instances, err := repository.Find[models.StrategyInstance](ctx, "strategy_instances", filter, nil)
+	if err != nil {
+		log.Printf("❌ Failed to load strategy instances: %v", err)
+		return
+	}
+	for _, inst := range instances {
+		if err := Strategies.load(inst); err != nil {
+			log.Printf("❌ Strategy %s: failed to resume: %v", inst.ID.Hex(), err)
+			markStrategyError(ctx, inst.ID, err.Error(), false)
+			continue
+		}
+		log.Printf("🔄 Resumed strategy %s (%s on %s)", inst.ID.Hex(), inst.Kind, inst.Symbol)
+	}
+}
+
+func (rt *StrategyRuntime) load(inst models.StrategyInstance) error {
+	newStrategy, ok := strategyKinds[inst.Kind]
+	if !ok {
+		return fmt.Errorf("unknown strategy kind %q", inst.Kind)
+	}
+	strategy, err := newStrategy(inst.Config)
+	if err != nil {
+		return err
+	}
+	if inst.State == nil {
+		inst.State = make(map[string]interface{})
+	}
+
+	ctx, cancel := context.WithCancel(workerCtx)
+	r := &strategyRunner{
+		inst:     inst,
+		strategy: strategy,
+		sc: &StrategyContext{
+			ID:     inst.ID,
+			UserID: inst.UserID,
+			Symbol: inst.Symbol,
+			Config: inst.Config,
+			State:  inst.State,
+		},
+		events: make(chan strategyEvent, strategyQueueSize),
+		cancel: cancel,
+	}
+	r.paused.Store(inst.Status == models.StrategyStatusPaused)
+
+	rt.mu.Lock()
+	if old, ok := rt.runners[inst.ID]; ok {
+		old.cancel()
+	}
+	rt.runners[inst.ID] = r
+	rt.mu.Unlock()
+
+	goWorker(func(context.Context) { rt.run(ctx, r) })
+	return nil
+}
+
+func (rt *StrategyRuntime) unload(id primitive.ObjectID) {
+	rt.mu.Lock()
+	if r, ok := rt.runners[id]; ok {
+		r.cancel()
+		delete(rt.runners, id)
+	}
+	rt.mu.Unlock()
+}
+
+func (rt *StrategyRuntime) setPaused(id primitive.ObjectID, paused bool) bool {
+	rt.mu.RLock()
+	r, ok := rt.runners[id]
+	rt.mu.RUnlock()
+	if ok {
+		r.paused.Store(paused)
+	}
+	return ok
+}
+
+func (rt *StrategyRuntime) info(id primitive.ObjectID) *models.StrategyRuntimeInfo {
+	rt.mu.RLock()
+	r, ok := rt.runners[id]
+	rt.mu.RUnlock()
+	if !ok {
+		return &models.StrategyRuntimeInfo{}
+	}
+	info := &models.StrategyRuntimeInfo{
+		Loaded:     true,
+		QueueDepth: len(r.events),
+		Events:     r.eventCount.Load(),
+	}
+	if ns := r.lastEvent.Load(); ns > 0 {
+		t := time.Unix(0, ns)
+		info.LastEventAt = &t
+	}
+	return info
+}
+
+// claimClientID returns a fresh client order id owned by the instance.
+func (rt *StrategyRuntime) claimClientID(instanceID primitive.ObjectID) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	clientID := "st_" + hex.EncodeToString(b)
+	rt.clientOwners.Store(clientID, instanceID)
+	return clientID
+}
+
+func (rt *StrategyRuntime) tagOrder(ctx context.Context, orderID int64, clientID string, instanceID primitive.ObjectID) {
+	rt.orderOwners.Store(orderID, instanceID)
+	rt.clientOwners.Delete(clientID)
+	update := bson.M{"$set": bson.M{"parent_id": instanceID.Hex()}}
+	if err := repository.IRepo.UpdateOne(ctx, "orders", bson.M{"order_id": orderID}, update, false); err != nil {
+		log.Printf("❌ Strategy %s: failed to tag order %d: %v", instanceID.Hex(), orderID, err)
+	}
+}
+
+func (rt *StrategyRuntime) deliver(id primitive.ObjectID, ev strategyEvent) {
+	rt.mu.RLock()
+	r, ok := rt.runners[id]
+	rt.mu.RUnlock()
+	if !ok {
+		return
+	}
+	select {
+	case r.events <- ev:
+	default:
+		log.Printf("⚠️  Strategy %s: event queue full, dropping event", id.Hex())
+	}
+}
+
+func (rt *StrategyRuntime) fanOutTickers() {
+	tickers := MarketData.Subscribe()
+	for t := range tickers {
+		ticker := t
+		rt.mu.RLock()
+		for id, r := range rt.runners {
+			if r.inst.Symbol == ticker.Symbol {
+				select {
+				case r.events <- strategyEvent{ticker: &ticker}:
+				default:
+					log.Printf("⚠️  Strategy %s: event queue full, dropping ticker", id.Hex())
+				}
+			}
+		}
+		rt.mu.RUnlock()
+	}
+}
+
+func (rt *StrategyRuntime) routeExecution(report ExecutionReport) {
+	ctx := context.Background()
+	if v, ok := rt.orderOwners.Load(report.OrderID); ok {
+		rt.deliver(v.(primitive.ObjectID), strategyEvent{exec: &report})
+		return
+	}
+	if v, ok := rt.clientOwners.Load(report.ClientOrderID); ok {
+		rt.deliver(v.(primitive.ObjectID), strategyEvent{exec: &report})
+		return
+	}
+	var record models.OrderRecord
+	if err := repository.IRepo.FindOneWhere(ctx, "orders", bson.M{"order_id": report.OrderID}, &record); err != nil || record.ParentID == "" {
+		return
+	}
+	if id, err := primitive.ObjectIDFromHex(record.ParentID); err == nil {
+		rt.deliver(id, strategyEvent{exec: &report})
+	}
+}
+
+// run is the instance's event loop. Symbols outside the shared ticker stream
+// are polled, and candles are fetched just after each bar closes.
+func (rt *StrategyRuntime) run(ctx context.Context, r *strategyRunner) {
+	poll := time.NewTicker(strategyPollInterval)
+	defer poll.Stop()
+
+	var candleTimer <-chan time.Time
+	var lastCandle time.Time
+	step, _ := intervalDuration(r.inst.Interval)
+	if step > 0 {
+		candleTimer = time.After(time.Until(time.Now().Truncate(step).Add(step + strategyCandleDelay)))
+	}
+	var lastTicker time.Time
+
+	for {
+		var ev strategyEvent
+		select {
+		case <-ctx.Done():
+			return
+		case ev = <-r.events:
+		case <-poll.C:
+			if time.Since(lastTicker) < strategyTickerMaxAge {
+				continue
+			}
+			price, err := MarketData.Price(r.inst.Symbol)
+			if err != nil {
+				continue
+			}
+			ev.ticker = &StreamTicker{Symbol: r.inst.Symbol, Price: strconv.FormatFloat(price, 'f', -1, 64)}
+		case <-candleTimer:
+			candleTimer = time.After(time.Until(time.Now().Truncate(step).Add(step + strategyCandleDelay)))
+			c, ok := latestClosedCandle(r.inst.Symbol, r.inst.Interval)
+			if !ok || !c.OpenTime.After(lastCandle) {
+				continue
+			}
+			lastCandle = c.OpenTime
+			ev.candle = &c
+		}
+
+		if ev.ticker != nil {
+			lastTicker = time.Now()
+		}
+		if r.paused.Load() {
+			continue
+		}
+		r.eventCount.Add(1)
+		r.lastEvent.Store(time.Now().UnixNano())
+		if !rt.dispatch(ctx, r, ev) {
+			return
+		}
+	}
+}
+
+// dispatch calls one hook with panic isolation. A panicking instance is
+// stopped with status error; other instances are unaffected.
+func (rt *StrategyRuntime) dispatch(ctx context.Context, r *strategyRunner, ev strategyEvent) (ok bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("❌ Strategy %s panicked: %v\n%s", r.inst.ID.Hex(), p, debug.Stack())
+			rt.unload(r.inst.ID)
+			markStrategyError(ctx, r.inst.ID, fmt.Sprintf("panic: %v", p), true)
+			ok = false
+		}
+	}()
+
+	switch {
+	case ev.ticker != nil:
+		r.strategy.OnTicker(r.sc, *ev.ticker)
+	case ev.candle != nil:
+		r.strategy.OnCandle(r.sc, *ev.candle)
+	case ev.exec != nil:
+		r.strategy.OnExecution(r.sc, *ev.exec)
+	}
+
+	if r.sc.dirty {
+		r.sc.dirty = false
+		update := bson.M{"$set": bson.M{"state": r.sc.State, "updated_at": time.Now()}}
+		if err := repository.IRepo.UpdateOne(ctx, "strategy_instances", bson.M{"_id": r.inst.ID}, update, false); err != nil {
+			log.Printf("❌ Strategy %s: failed to save state: %v", r.inst.ID.Hex(), err)
+		}
+	}
+	return true
+}
+
+func markStrategyError(ctx context.Context, id primitive.ObjectID, reason string, panicked bool) {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":     models.StrategyStatusError,
+		"last_error": reason,
+		"stopped_at": now,
+		"updated_at": now,
+	}}
+	if panicked {
+		update["$inc"] = bson.M{"panic_count": 1}
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "strategy_instances", bson.M{"_id": id}, update, false); err != nil {
+		log.Printf("❌ Strategy %s: failed to record error: %v", id.Hex(), err)
+	}
+}
+
+func latestClosedCandle(symbol, interval string) (models.Candle, bool) {
+	klines, err := GetKlines(context.Background(), symbol, interval, time.Time{}, time.Time{}, 2)
+	if err != nil {
+		return models.Candle{}, false
+	}
+	for i := len(klines) - 1; i >= 0; i-- {
+		k := klines[i]
+		if k.CloseTime.Before(time.Now()) {
+			return models.Candle{
+				Symbol:    symbol,
+				Interval:  interval,
+				OpenTime:  k.OpenTime,
+				CloseTime: k.CloseTime,
+				Open:      k.Open,
+				High:      k.High,
+				Low:       k.Low,
+				Close:     k.Close,
+				Volume:    k.Volume,
+			}, true
+		}
+	}
+	return models.Candle{}, false
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/validator"
+)
+
+type StrategyServices struct{}
+
+func (s StrategyServices) Create(ctx context.Context, userID string, req models.StrategyInstanceRequest) (models.StrategyInstance, error) {
+	if err := Strategies.requireHost(); err != nil {
+		return models.StrategyInstance{}, err
+	}
+	if err := validator.Validate(&req); err != nil {
+		return models.StrategyInstance{}, err
+	}
+	req.Kind = strings.ToLower(req.Kind)
+	req.Symbol = strings.ToUpper(req.Symbol)
+
+	newStrategy, ok := strategyKinds[req.Kind]
+	if !ok {
+		return models.StrategyInstance{}, fmt.Errorf("unknown strategy kind %q", req.Kind)
+	}
+	if req.Config == nil {
+		req.Config = make(map[string]interface{})
+	}
+	if _, err := newStrategy(req.Config); err != nil {
+		return models.StrategyInstance{}, err
+	}
+	if req.Interval != "" {
+		if _, err := intervalDuration(req.Interval); err != nil {
+			return models.StrategyInstance{}, err
+		}
+	}
+	if req.Kind == "sma_cross" && req.Interval == "" {
+		return models.StrategyInstance{}, errors.New("sma_cross needs a candle interval")
+	}
+
+	now := time.Now()
+	inst := models.StrategyInstance{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Symbol:    req.Symbol,
+		Interval:  req.Interval,
+		Config:    req.Config,
+		State:     make(map[string]interface{}),
+		Status:    models.StrategyStatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+		StartedAt: &now,
+	}
+	if req.Paused {
+		inst.Status = models.StrategyStatusPaused
+	}
+	if err := repository.IRepo.Insert(ctx, "strategy_instances", &inst); err != nil {
+		return models.StrategyInstance{}, err
+	}
+	if err := Strategies.load(inst); err != nil {
+		return inst, err
+	}
+	inst.Runtime = Strategies.info(inst.ID)
+	return inst, nil
+}
+
+func (s StrategyServices) List(ctx context.Context, userID string) ([]models.StrategyInstance, error) {
+	instances, err := repository.Find[models.StrategyInstance](ctx, "strategy_instances", bson.M{"user_id": userID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range instances {
+		instances[i].Runtime = Strategies.info(instances[i].ID)
+	}
+	return instances, nil
+}
+
+// Get returns the instance with its live runtime status.
+func (s StrategyServices) Get(ctx context.Context, userID, id string) (models.StrategyInstance, error) {
+	var inst models.StrategyInstance
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return inst, errors.New("invalid strategy id")
+	}
+	if err := repository.IRepo.FindOneWhere(ctx, "strategy_instances", bson.M{"_id": oid, "user_id": userID}, &inst); err != nil {
+		return inst, errors.New("strategy not found")
+	}
+	inst.Runtime = Strategies.info(inst.ID)
+	return inst, nil
+}
+
+// Start runs a stopped, failed or paused instance. State is kept, so a
+// restarted strategy carries on where it left off.
+func (s StrategyServices) Start(ctx context.Context, userID, id string) (models.StrategyInstance, error) {
+	if err := Strategies.requireHost(); err != nil {
+		return models.StrategyInstance{}, err
+	}
+	inst, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return inst, err
+	}
+	if inst.Status == models.StrategyStatusRunning && inst.Runtime.Loaded {
+		return inst, nil
+	}
+
+	now := time.Now()
+	set := bson.M{"status": models.StrategyStatusRunning, "updated_at": now}
+	if inst.Status != models.StrategyStatusPaused {
+		set["started_at"] = now
+	}
+	update := bson.M{"$set": set, "$unset": bson.M{"last_error": "", "stopped_at": ""}}
+	if err := repository.IRepo.UpdateOne(ctx, "strategy_instances", bson.M{"_id": inst.ID}, update, false); err != nil {
+		return inst, err
+	}
+	inst.Status = models.StrategyStatusRunning
+	inst.LastError = ""
+
+	if !Strategies.setPaused(inst.ID, false) {
+		if err := Strategies.load(inst); err != nil {
+			markStrategyError(ctx, inst.ID, err.Error(), false)
+			return inst, err
+		}
+	}
+	inst.Runtime = Strategies.info(inst.ID)
+	return inst, nil
+}
+
+// Pause keeps the instance loaded but drops its events until started again.
+func (s StrategyServices) Pause(ctx context.Context, userID, id string) (models.StrategyInstance, error) {
+	if err := Strategies.requireHost(); err != nil {
+		return models.StrategyInstance{}, err
+	}
+	inst, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return inst, err
+	}
+	if inst.Status != models.StrategyStatusRunning {
+		return inst, fmt.Errorf("strategy is %s", inst.Status)
+	}
+	update := bson.M{"$set": bson.M{"status": models.StrategyStatusPaused, "updated_at": time.Now()}}
+	if err := repository.IRepo.UpdateOne(ctx, "strategy_instances", bson.M{"_id": inst.ID}, update, false); err != nil {
+		return inst, err
+	}
+	Strategies.setPaused(inst.ID, true)
+	inst.Status = models.StrategyStatusPaused
+	inst.Runtime = Strategies.info(inst.ID)
+	return inst, nil
+}
+
+// Stop unloads the instance. Open orders it placed are left on the book.
+func (s StrategyServices) Stop(ctx context.Context, userID, id string) (models.StrategyInstance, error) {
+	if err := Strategies.requireHost(); err != nil {
+		return models.StrategyInstance{}, err
+	}
+	inst, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return inst, err
+	}
+	Strategies.unload(inst.ID)
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": models.StrategyStatusStopped, "stopped_at": now, "updated_at": now}}
+	if err := repository.IRepo.UpdateOne(ctx, "strategy_instances", bson.M{"_id": inst.ID}, update, false); err != nil {
+		return inst, err
+	}
+	inst.Status = models.StrategyStatusStopped
+	inst.StoppedAt = &now
+	inst.Runtime = Strategies.info(inst.ID)
+	return inst, nil
+}
+
+func (s StrategyServices) Delete(ctx context.Context, userID, id string) error {
+	if err := Strategies.requireHost(); err != nil {
+		return err
+	}
+	inst, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	Strategies.unload(inst.ID)
+	return repository.IRepo.RemoveByFilter(ctx, "strategy_instances", bson.M{"_id": inst.ID})
+}
+
+// priceAlertStrategy alerts once each time price crosses above or below the
+// configured levels.
+type priceAlertStrategy struct {
+	above float64
+	below float64
+}
+
+func newPriceAlertStrategy(config map[string]interface{}) (Strategy, error) {
+	p := &priceAlertStrategy{above: toFloat(config["above"]), below: toFloat(config["below"])}
+	if p.above <= 0 && p.below <= 0 {
+		return nil, errors.New("price_alert needs above and/or below")
+	}
+	return p, nil
+}
+
+func (p *priceAlertStrategy) OnTicker(sc *StrategyContext, t StreamTicker) {
+	price, err := strconv.ParseFloat(t.Price, 64)
+	if err != nil {
+		return
+	}
+	if p.above > 0 {
+		crossed := price >= p.above
+		if crossed && !sc.Bool("above_fired") {
+			sc.Alert(fmt.Sprintf("%s is above %s", sc.Symbol, strconv.FormatFloat(p.above, 'f', -1, 64)), t.Price)
+		}
+		if crossed != sc.Bool("above_fired") {
+			sc.Set("above_fired", crossed)
+		}
+	}
+	if p.below > 0 {
+		crossed := price <= p.below
+		if crossed && !sc.Bool("below_fired") {
+			sc.Alert(fmt.Sprintf("%s is below %s", sc.Symbol, strconv.FormatFloat(p.below, 'f', -1, 64)), t.Price)
+		}
+		if crossed != sc.Bool("below_fired") {
+			sc.Set("below_fired", crossed)
+		}
+	}
+}
+
+func (p *priceAlertStrategy) OnCandle(sc *StrategyContext, c models.Candle) {}
+
+func (p *priceAlertStrategy) OnExecution(sc *StrategyContext, r ExecutionReport) {}
+
+// smaCrossStrategy goes long when the fast moving average of closes crosses
+// above the slow one and flattens on the opposite cross.
+type smaCrossStrategy struct {
+	fast     int
+	slow     int
+	quantity string
+}
+
+func newSMACrossStrategy(config map[string]interface{}) (Strategy, error) {
+	s := &smaCrossStrategy{fast: 9, slow: 21}
+	if v := toFloat(config["fast"]); v > 0 {
+		s.fast = int(v)
+	}
+	if v := toFloat(config["slow"]); v > 0 {
+		s.slow = int(v)
+	}
+	if s.fast >= s.slow {
+		return nil, errors.New("fast must be shorter than slow")
+	}
+	qty := toFloat(config["quantity"])
+	if qty <= 0 {
+		return nil, errors.New("sma_cross needs a positive quantity")
+	}
+	s.quantity = strconv.FormatFloat(qty, 'f', -1, 64)
+	return s, nil
+}
+
+func sma(values []float64, n int) float64 {
+	sum := 0.0
+	for _, v := range values[len(values)-n:] {
+		sum += v
+	}
+	return sum / float64(n)
+}
+
+func (s *smaCrossStrategy) OnTicker(sc *StrategyContext, t StreamTicker) {}
+
+func (s *smaCrossStrategy) OnCandle(sc *StrategyContext, c models.Candle) {
+	closes := append(sc.Floats("closes"), c.Close)
+	if len(closes) > s.slow+1 {
+		closes = closes[len(closes)-s.slow-1:]
+	}
+	sc.Set("closes", closes)
+	if len(closes) < s.slow+1 || sc.Bool("pending") {
+		return
+	}
+
+	prev := closes[:len(closes)-1]
+	wasAbove := sma(prev, s.fast) > sma(prev, s.slow)
+	isAbove := sma(closes, s.fast) > sma(closes, s.slow)
+	long := sc.Bool("long")
+
+	side := ""
+	if isAbove && !wasAbove && !long {
+		side = "BUY"
+	} else if !isAbove && wasAbove && long {
+		side = "SELL"
+	}
+	if side == "" {
+		return
+	}
+	if _, err := sc.MarketOrder(side, s.quantity); err != nil {
+		log.Printf("❌ Strategy %s: %s failed: %v", sc.ID.Hex(), side, err)
+		return
+	}
+	sc.Set("pending", true)
+}
+
+func (s *smaCrossStrategy) OnExecution(sc *StrategyContext, r ExecutionReport) {
+	switch r.CurrentOrderStatus {
+	case "FILLED":
+		sc.Set("long", r.Side == "BUY")
+		sc.Set("pending", false)
+	case "CANCELED", "REJECTED", "EXPIRED":
+		sc.Set("pending", false)
+	}
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"exdex/config"
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/jwt"
+	"exdex/server/rbac"
+	"exdex/server/security"
+)
+
+const (
+	defaultRefreshExpiration = 30 * 24 * time.Hour
+	revocationCacheTTL       = 5 * time.Second
+	revocationCacheMax       = 100000
+	revocationSweepEvery     = time.Minute
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionUnavailable  = errors.New("user is inactive or suspended")
+)
+
+type TokenServices struct{}
+
+func refreshExpiration() time.Duration {
+	if d := config.C.JWT.RefreshExpiration; d > 0 {
+		return d
+	}
+	return defaultRefreshExpiration
+}
+
+// familyLocks serialises refreshes within a token family so two concurrent
+// refreshes with the same token cannot both succeed.
+var familyLocks sync.Map
+
+func familyLock(familyID string) *sync.Mutex {
+	m, _ := familyLocks.LoadOrStore(familyID, &sync.Mutex{})
+	return m.(*sync.Mutex)
+}
+
+// IssueSession starts a new refresh token family for the user.
+func (t TokenServices) IssueSession(ctx context.Context, user models.User, userAgent, ip string) (models.TokenPair, error) {
+	return issueTokenPair(ctx, user, primitive.NewObjectID().Hex(), userAgent, ip)
+}
+
+func issueTokenPair(ctx context.Context, user models.User, familyID, userAgent, ip string) (models.TokenPair, error) {
+	access, claims, err := jwt.GenerateJWT(user.ID, user.Email, user.Role, familyID)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	raw, err := security.GenerateAPIKey()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	now := time.Now()
+	rt := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: security.HashToken(raw),
+		ExpiresAt: now.Add(refreshExpiration()),
+		UserAgent: userAgent,
+		IP:        ip,
+		CreatedAt: now,
+	}
+	if err := repository.IRepo.Insert(ctx, "refresh_tokens", &rt); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return models.TokenPair{
+		AccessToken:      access,
+		RefreshToken:     raw,
+		TokenType:        "Bearer",
+		ExpiresAt:        claims.ExpiresAt,
+		RefreshExpiresAt: rt.ExpiresAt,
+	}, nil
+}
+
+// Refresh rotates a refresh token. A token that was already used means it
+// leaked, so the whole family is revoked along with its access tokens.
+func (t TokenServices) Refresh(ctx context.Context, raw, userAgent, ip string) (models.TokenPair, error) {
+	hash := security.HashToken(raw)
+	rt, err := repository.FindOne[models.RefreshToken](ctx, "refresh_tokens", bson.M{"token_hash": hash})
+	if err != nil {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	mu := familyLock(rt.FamilyID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := repository.IRepo.FindOneWhere(ctx, "refresh_tokens", bson.M{"_id": rt.ID}, &rt); err != nil {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+	if rt.RevokedAt != nil || time.Now().After(rt.ExpiresAt) {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+	if rt.UsedAt != nil {
+		return models.TokenPair{}, refreshTokenReused(ctx, rt)
+	}
+
+	user, err := loadSessionUser(ctx, rt.UserID)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	if user.TokensRevokedAfter != nil && !rt.CreatedAt.After(*user.TokensRevokedAfter) {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	// Mark the token used only if it still is unused. Another instance may
+	// have rotated it since it was read, which makes this a reuse too.
+	unused := bson.M{"_id": rt.ID, "used_at": bson.M{"$exists": false}}
+	claimed, err := repository.IRepo.UpdateOneMatched(ctx, "refresh_tokens", unused, bson.M{"$set": bson.M{"used_at": time.Now()}})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	if !claimed {
+		return models.TokenPair{}, refreshTokenReused(ctx, rt)
+	}
+	return issueTokenPair(ctx, user, rt.FamilyID, userAgent, ip)
+}
+
+// refreshTokenReused ends the session of a refresh token presented after it
+// was already used, since either copy may be a stolen one.
+func refreshTokenReused(ctx context.Context, rt models.RefreshToken) error {
+	log.Printf("⚠️  Refresh token reuse for user %s, revoking session %s", rt.UserID, rt.FamilyID)
+	if err := revokeSession(ctx, rt.UserID, rt.FamilyID); err != nil {
+		log.Printf("❌ Failed to revoke session %s: %v", rt.FamilyID, err)
+	}
+	return ErrRefreshTokenReused
+}
+
+func loadSessionUser(ctx context.Context, userID string) (models.User, error) {
+	var user models.User
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return user, ErrInvalidRefreshToken
+	}
+	if err := repository.IRepo.FindOneWhere(ctx, "users", bson.M{"_id": oid}, &user); err != nil {
+		return user, ErrInvalidRefreshToken
+	}
+	if !user.Status || user.SuspendedAt != nil || user.DeletedAt != nil {
+		return user, ErrSessionUnavailable
+	}
+	user.Role = rbac.NormalizeRole(user.Role)
+	return user, nil
+}
+
+// Logout ends the caller's session: the current access token and the refresh
+// token family it belongs to. A refresh token, if given, picks the family
+// instead.
+func (t TokenServices) Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error {
+	familyID := claims.SessionID
+	if refreshToken != "" {
+		rt, err := repository.FindOne[models.RefreshToken](ctx, "refresh_tokens", bson.M{"token_hash": security.HashToken(refreshToken), "user_id": claims.UserID})
+		if err != nil {
+			return ErrInvalidRefreshToken
+		}
+		familyID = rt.FamilyID
+	}
+
+	if err := revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	if familyID == "" {
+		return nil
+	}
+	return revokeSession(ctx, claims.UserID, familyID)
+}
+
+// RevokeUserSessions ends every session of a user immediately: all refresh
+// tokens are revoked and access tokens issued up to now are rejected.
+func (t TokenServices) RevokeUserSessions(ctx context.Context, userID string) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	now := time.Now().Truncate(time.Second)
+	if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, bson.M{"$set": bson.M{"tokens_revoked_after": now}}, false); err != nil {
+		return err
+	}
+	revocations.put("user:"+userID, revocationEntry{after: now, until: time.Now().Add(revocationCacheTTL)})
+
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	return repository.IRepo.UpdateMany(ctx, "refresh_tokens", filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+}
+
+// Sessions lists the user's live refresh token families.
+func (t TokenServices) Sessions(ctx context.Context, userID string) ([]models.RefreshToken, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"used_at":    bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	tokens, err := repository.Find[models.RefreshToken](ctx, "refresh_tokens", filter, nil)
+	return tokens, err
+}
+
+func revokeAccessToken(ctx context.Context, claims *jwt.Claims) error {
+	rev := models.RevokedToken{
+		Kind:      models.RevokedAccessToken,
+		Value:     claims.JTI,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := repository.IRepo.Insert(ctx, "revoked_tokens", &rev); err != nil {
+		return err
+	}
+	revocations.put("jti:"+claims.JTI, revocationEntry{revoked: true, until: claims.ExpiresAt})
+	return nil
+}
+
+func revokeSession(ctx context.Context, userID, familyID string) error {
+	now := time.Now()
+	filter := bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}}
+	if err := repository.IRepo.UpdateMany(ctx, "refresh_tokens", filter, bson.M{"$set": bson.M{"revoked_at": now}}); err != nil {
+		return err
+	}
+
+	// Access tokens of the session live at most one access token lifetime.
+	expiration := config.C.JWT.Expiration
+	if expiration <= 0 {
+		expiration = 24 * time.Hour
+	}
+	rev := models.RevokedToken{
+		Kind:      models.RevokedSession,
+		Value:     familyID,
+		UserID:    userID,
+		ExpiresAt: now.Add(expiration),
+		CreatedAt: now,
+	}
+	if err := repository.IRepo.Insert(ctx, "revoked_tokens", &rev); err != nil {
+		return err
+	}
+	revocations.put("sid:"+familyID, revocationEntry{revoked: true, until: rev.ExpiresAt})
+	return nil
+}
+
+type revocationEntry struct {
+	revoked bool
+	after   time.Time
+	until   time.Time // the entry is used until then
+}
+
+// revocationCache holds lookups so the check does not hit Mongo on every
+// request. Revocations made on this instance apply at once and are kept
+// until the token would have expired anyway; other lookups for a few
+// seconds. Expired entries are swept out, and once the cache is full new
+// entries are not kept, so lookups go to Mongo instead.
+type revocationCache struct {
+	mu        sync.Mutex
+	entries   map[string]revocationEntry
+	lastSweep time.Time
+}
+
+var revocations = &revocationCache{entries: make(map[string]revocationEntry)}
+
+func (rc *revocationCache) get(key string) (revocationEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	e, ok := rc.entries[key]
+	if !ok || !time.Now().Before(e.until) {
+		return revocationEntry{}, false
+	}
+	return e, true
+}
+
+func (rc *revocationCache) put(key string, e revocationEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	now := time.Now()
+	if now.Sub(rc.lastSweep) >= revocationSweepEvery || len(rc.entries) >= revocationCacheMax {
+		rc.lastSweep = now
+		for k, old := range rc.entries {
+			if !now.Before(old.until) {
+				delete(rc.entries, k)
+			}
+		}
+	}
+	if _, ok := rc.entries[key]; !ok && len(rc.entries) >= revocationCacheMax {
+		return
+	}
+	rc.entries[key] = e
+}
+
+func (rc *revocationCache) lookup(key string, load func() (revocationEntry, error)) (revocationEntry, error) {
+	if e, ok := rc.get(key); ok {
+		return e, nil
+	}
+	e, err := load()
+	if err != nil {
+		return revocationEntry{}, err
+	}
+	if e.until.IsZero() {
+		e.until = time.Now().Add(revocationCacheTTL)
+	}
+	rc.put(key, e)
+	return e, nil
+}
+
+func isRevoked(ctx context.Context, kind, value string) (revocationEntry, error) {
+	rev, err := repository.FindOne[models.RevokedToken](ctx, "revoked_tokens", bson.M{"kind": kind, "value": value})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return revocationEntry{}, nil
+	}
+	if err != nil {
+		return revocationEntry{}, err
+	}
+	return revocationEntry{revoked: true, until: rev.ExpiresAt}, nil
+}
+
+// IsAccessTokenRevoked reports whether the token, its session or all of the
+// user's tokens have been revoked. It returns an error when that cannot be
+// checked; callers must then refuse the token.
+func IsAccessTokenRevoked(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	token, err := revocations.lookup("jti:"+claims.JTI, func() (revocationEntry, error) {
+		return isRevoked(ctx, models.RevokedAccessToken, claims.JTI)
+	})
+	if err != nil || token.revoked {
+		return token.revoked, err
+	}
+	if claims.SessionID != "" {
+		session, err := revocations.lookup("sid:"+claims.SessionID, func() (revocationEntry, error) {
+			return isRevoked(ctx, models.RevokedSession, claims.SessionID)
+		})
+		if err != nil || session.revoked {
+			return session.revoked, err
+		}
+	}
+
+	user, err := revocations.lookup("user:"+claims.UserID, func() (revocationEntry, error) {
+		oid, err := primitive.ObjectIDFromHex(claims.UserID)
+		if err != nil {
+			return revocationEntry{}, nil
+		}
+		u, err := repository.FindOne[models.User](ctx, "users", bson.M{"_id": oid})
+		if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && u.TokensRevokedAfter == nil) {
+			return revocationEntry{}, nil
+		}
+		if err != nil {
+			return revocationEntry{}, err
+		}
+		return revocationEntry{after: *u.TokensRevokedAfter}, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return !user.after.IsZero() && !claims.IssuedAt.After(user.after), nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/jwt"
+	"exdex/server/rbac"
+)
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	ctx := context.Background()
+	useMemoryRepo(t)
+	useJWTConfig(t)
+
+	user := models.User{Email: "bob@example.com", Role: rbac.RoleUser, Status: true}
+	if err := repository.IRepo.Insert(ctx, "users", &user); err != nil {
+		t.Fatal(err)
+	}
+	user, err := repository.FindOne[models.User](ctx, "users", bson.M{"email": user.Email})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenServices := TokenServices{}
+	first, err := tokenServices.IssueSession(ctx, user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	second, err := tokenServices.Refresh(ctx, first.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh returned the same refresh token")
+	}
+
+	// Presenting the used token again ends the whole session.
+	if _, err := tokenServices.Refresh(ctx, first.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, err := tokenServices.Refresh(ctx, second.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("refresh after reuse error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	claims, err := jwt.ParseToken(second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := IsAccessTokenRevoked(ctx, claims)
+	if err != nil || !revoked {
+		t.Fatalf("IsAccessTokenRevoked = %v, %v, want the session's access token revoked", revoked, err)
+	}
+	if n, _ := repository.IRepo.Count(ctx, "refresh_tokens", bson.M{"revoked_at": bson.M{"$exists": false}}); n != 0 {
+		t.Fatalf("%d refresh tokens left unrevoked", n)
+	}
+}
@@ -1,18 +1,22 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"strings"
 
 	"github.com/gorilla/websocket"
+
+	"exdex/config"
 )
 
 // Ticker structure from Binance stream
 type StreamTicker struct {
-	Symbol string `json:"s"` // Symbol
-	Price  string `json:"c"` // Last price
-	Change string `json:"P"` // Price change %
+	EventTime int64  `json:"E"` // Event time
+	Symbol    string `json:"s"` // Symbol
+	Price     string `json:"c"` // Last price
+	Change    string `json:"P"` // Price change %
 }
 
 // Define interested symbols
@@ -22,13 +26,16 @@ var interestedSymbols = []string{
 	"PEPEUSDT", "SOLUSDT",
 }
 
-// WebSocket streaming from Binance
-func StartBinanceTickerStream(send chan<- StreamTicker) {
+// WebSocket streaming from Binance, until the connection drops or ctx is done.
+func StartBinanceTickerStream(ctx context.Context, send chan<- StreamTicker) {
 	var streams []string
 	for _, symbol := range interestedSymbols {
 		streams = append(streams, strings.ToLower(symbol)+"@ticker")
 	}
-	url := "wss://stream.binance.com:9443/stream?streams=" + strings.Join(streams, "/")
+	// wsBaseURL is the raw stream endpoint (…/ws/); combined streams live
+	// next to it under /stream.
+	base := strings.TrimSuffix(strings.TrimSuffix(config.C.Binance.WSBaseURL, "/"), "/ws")
+	url := base + "/stream?streams=" + strings.Join(streams, "/")
 
 	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
@@ -36,11 +43,16 @@ func StartBinanceTickerStream(send chan<- StreamTicker) {
 		return
 	}
 	defer conn.Close()
+	// Closing the connection unblocks the read below.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
 
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("WebSocket read error:", err)
+			if ctx.Err() == nil {
+				log.Println("WebSocket read error:", err)
+			}
 			return
 		}
 
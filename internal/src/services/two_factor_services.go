@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"exdex/config"
+	models "exdex/internal/src/model"
+	"exdex/internal/src/repository"
+	"exdex/server/security"
+)
+
+const (
+	recoveryCodeCount      = 10
+	defaultStepUpTTL       = 10 * time.Minute
+	maxTwoFactorFailures   = 5
+	twoFactorFailureWindow = 15 * time.Minute
+)
+
+var (
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotPending  = errors.New("start enrollment first")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrTwoFactorLocked      = errors.New("too many invalid two-factor codes, try again later")
+	ErrStepUpRequired       = errors.New("confirm with a two-factor code to continue")
+	ErrTwoFactorRequired    = errors.New("enable two-factor authentication to continue")
+	ErrFreshLoginRequired   = errors.New("log in again to set up two-factor authentication")
+)
+
+type TwoFactorServices struct{}
+
+func twoFactorEncryptionKey() string {
+	return config.C.TwoFactor.EncryptionKey
+}
+
+func stepUpTTL() time.Duration {
+	if d := config.C.TwoFactor.StepUpTTL; d > 0 {
+		return d
+	}
+	return defaultStepUpTTL
+}
+
+func twoFactorUser(ctx context.Context, userID string) (models.User, primitive.ObjectID, error) {
+	var user models.User
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return user, oid, errors.New("invalid user id")
+	}
+	if err := repository.IRepo.FindOneWhere(ctx, "users", bson.M{"_id": oid}, &user); err != nil {
+		return user, oid, errors.New("user not found")
+	}
+	return user, oid, nil
+}
+
+func (t TwoFactorServices) Status(ctx context.Context, userID, sessionID string) (models.TwoFactorStatus, error) {
+	user, _, err := twoFactorUser(ctx, userID)
+	if err != nil {
+		return models.TwoFactorStatus{}, err
+	}
+	status := models.TwoFactorStatus{
+		Enabled:                user.TOTPEnabled,
+		EnabledAt:              user.TOTPEnabledAt,
+		Pending:                user.TOTPPendingSecret != "",
+		RecoveryCodesRemaining: len(user.RecoveryCodes),
+	}
+	if at, ok := lastStepUp(ctx, sessionID); ok && time.Since(at) < stepUpTTL() {
+		until := at.Add(stepUpTTL())
+		status.StepUpValidUntil = &until
+	}
+	return status, nil
+}
+
+// Enroll starts TOTP enrollment with a new secret. It only takes effect once
+// confirmed with a code from the authenticator app. With no second factor
+// to step up with yet, the session must have logged in within the step-up
+// window, so an older stolen token cannot bind its own authenticator.
+func (t TwoFactorServices) Enroll(ctx context.Context, userID, sessionID string) (models.TwoFactorEnrollment, error) {
+	user, oid, err := twoFactorUser(ctx, userID)
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	if user.TOTPEnabled {
+		return models.TwoFactorEnrollment{}, ErrTwoFactorEnabled
+	}
+	if started, ok := sessionStarted(sessionID); !ok || time.Since(started) > stepUpTTL() {
+		return models.TwoFactorEnrollment{}, ErrFreshLoginRequired
+	}
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	encrypted, err := security.Encrypt(twoFactorEncryptionKey(), secret)
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	update := bson.M{"$set": bson.M{"totp_pending_secret": encrypted, "updated_at": time.Now()}}
+	if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, update, false); err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+
+	issuer := config.C.TwoFactor.Issuer
+	if issuer == "" {
+		issuer = "EXDEX"
+	}
+	return models.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables TOTP and returns the recovery codes, shown only once.
+func (t TwoFactorServices) Confirm(ctx context.Context, userID, sessionID, code string) ([]string, error) {
+	user, oid, err := twoFactorUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, ErrTwoFactorNotPending
+	}
+	secret, err := security.Decrypt(twoFactorEncryptionKey(), user.TOTPPendingSecret)
+	if err != nil {
+		return nil, err
+	}
+	if locked, err := twoFactorLocked(ctx, userID); err != nil || locked {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrTwoFactorLocked
+	}
+	step, ok := security.VerifyTOTP(secret, code, time.Now())
+	if !ok {
+		recordTwoFactorFailure(ctx, userID)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"totp_enabled":    true,
+			"totp_enabled_at": now,
+			"totp_secret":     user.TOTPPendingSecret,
+			"totp_last_step":  step,
+			"recovery_codes":  hashes,
+			"updated_at":      now,
+		},
+		"$unset": bson.M{"totp_pending_secret": ""},
+	}
+	if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, update, false); err != nil {
+		return nil, err
+	}
+	clearTwoFactorFailures(ctx, userID)
+	recordStepUp(ctx, userID, sessionID)
+	return codes, nil
+}
+
+// Verify confirms the second factor for the session with a TOTP code or a
+// recovery code, opening the step-up window for sensitive routes.
+func (t TwoFactorServices) Verify(ctx context.Context, userID, sessionID, code string) error {
+	if err := checkSecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+	recordStepUp(ctx, userID, sessionID)
+	return nil
+}
+
+// Disable turns TOTP off. It needs a valid code so a stolen token cannot.
+func (t TwoFactorServices) Disable(ctx context.Context, userID, code string) error {
+	if err := checkSecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+	oid, _ := primitive.ObjectIDFromHex(userID)
+	update := bson.M{
+		"$set": bson.M{"totp_enabled": false, "updated_at": time.Now()},
+		"$unset": bson.M{
+			"totp_secret": "", "totp_pending_secret": "", "totp_enabled_at": "",
+			"totp_last_step": "", "recovery_codes": "",
+		},
+	}
+	return repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, update, false)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes.
+func (t TwoFactorServices) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if err := checkSecondFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	oid, _ := primitive.ObjectIDFromHex(userID)
+	update := bson.M{"$set": bson.M{"recovery_codes": hashes, "updated_at": time.Now()}}
+	if err := repository.IRepo.UpdateOne(ctx, "users", bson.M{"_id": oid}, update, false); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkSecondFactor accepts a TOTP code not used before, or consumes a
+// recovery code.
+func checkSecondFactor(ctx context.Context, userID, code string) error {
+	user, oid, err := twoFactorUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if locked, err := twoFactorLocked(ctx, userID); err != nil || locked {
+		if err != nil {
+			return err
+		}
+		return ErrTwoFactorLocked
+	}
+
+	code = strings.TrimSpace(code)
+	if strings.Contains(code, "-") {
+		for _, hash := range user.RecoveryCodes {
+			if security.CheckPasswordHash(strings.ToLower(code), hash) {
+				// Only the request that removes the code may use it.
+				filter := bson.M{"_id": oid, "recovery_codes": hash}
+				pulled, err := repository.IRepo.UpdateOneMatched(ctx, "users", filter, bson.M{"$pull": bson.M{"recovery_codes": hash}})
+				if err != nil {
+					return err
+				}
+				if !pulled {
+					recordTwoFactorFailure(ctx, userID)
+					return ErrInvalidTwoFactorCode
+				}
+				log.Printf("🔑 User %s used a recovery code, %d left", userID, len(user.RecoveryCodes)-1)
+				clearTwoFactorFailures(ctx, userID)
+				return nil
+			}
+		}
+		recordTwoFactorFailure(ctx, userID)
+		return ErrInvalidTwoFactorCode
+	}
+
+	secret, err := security.Decrypt(twoFactorEncryptionKey(), user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := security.VerifyTOTP(secret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		recordTwoFactorFailure(ctx, userID)
+		return ErrInvalidTwoFactorCode
+	}
+	// A code is accepted once: the step only moves forward from the one we
+	// read, so a concurrent request with the same code does not match.
+	filter := bson.M{"_id": oid, "$or": []bson.M{
+		{"totp_last_step": bson.M{"$exists": false}},
+		{"totp_last_step": bson.M{"$lt": step}},
+	}}
+	advanced, err := repository.IRepo.UpdateOneMatched(ctx, "users", filter, bson.M{"$set": bson.M{"totp_last_step": step}})
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		recordTwoFactorFailure(ctx, userID)
+		return ErrInvalidTwoFactorCode
+	}
+	clearTwoFactorFailures(ctx, userID)
+	return nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := security.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := security.HashPassword(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+func recordStepUp(ctx context.Context, userID, sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	update := bson.M{"$set": bson.M{"user_id": userID, "verified_at": time.Now()}}
+	if err := repository.IRepo.UpdateOne(ctx, "step_ups", bson.M{"session_id": sessionID}, update, true); err != nil {
+		log.Printf("❌ Failed to record step-up for session %s: %v", sessionID, err)
+	}
+}
+
+// sessionStarted returns when the session logged in. Session ids are the
+// ObjectID of the refresh token family created at login.
+func sessionStarted(sessionID string) (time.Time, bool) {
+	oid, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return oid.Timestamp(), true
+}
+
+func lastStepUp(ctx context.Context, sessionID string) (time.Time, bool) {
+	if sessionID == "" {
+		return time.Time{}, false
+	}
+	s, err := repository.FindOne[models.StepUp](ctx, "step_ups", bson.M{"session_id": sessionID})
+	if err != nil {
+		return time.Time{}, false
+	}
+	return s.VerifiedAt, true
+}
+
+// CheckStepUp reports whether the session may use a sensitive route. Users
+// without TOTP pass unless two_factor.required is set.
+func CheckStepUp(ctx context.Context, userID, sessionID string) error {
+	user, _, err := twoFactorUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		if config.C.TwoFactor.Required {
+			return ErrTwoFactorRequired
+		}
+		return nil
+	}
+	if at, ok := lastStepUp(ctx, sessionID); ok && time.Since(at) < stepUpTTL() {
+		return nil
+	}
+	return ErrStepUpRequired
+}
+
+// twoFactorFailure counts a user's failed codes within a window, shared by
+// every instance to slow down guessing. A TTL index removes expired ones.
+type twoFactorFailure struct {
+	UserID    string    `bson:"user_id"`
+	Count     int       `bson:"count"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+func recordTwoFactorFailure(ctx context.Context, userID string) {
+	if err := countTwoFactorFailure(ctx, userID); err != nil {
+		log.Printf("❌ Failed to record a 2FA failure for user %s: %v", userID, err)
+	}
+}
+
+func countTwoFactorFailure(ctx context.Context, userID string) error {
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now()
+		inc := bson.M{"$inc": bson.M{"count": 1}}
+		counted, err := repository.IRepo.UpdateOneMatched(ctx, "two_factor_failures", bson.M{"user_id": userID, "expires_at": bson.M{"$gt": now}}, inc)
+		if err != nil || counted {
+			return err
+		}
+		// No open window: restart an expired one, or start the first.
+		restart := bson.M{"$set": bson.M{"count": 1, "expires_at": now.Add(twoFactorFailureWindow)}}
+		counted, err = repository.IRepo.UpdateOneMatched(ctx, "two_factor_failures", bson.M{"user_id": userID, "expires_at": bson.M{"$lte": now}}, restart)
+		if err != nil || counted {
+			return err
+		}
+		err = repository.IRepo.Insert(ctx, "two_factor_failures", &twoFactorFailure{UserID: userID, Count: 1, ExpiresAt: now.Add(twoFactorFailureWindow)})
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		// Another request started the window first; count into it.
+	}
+	return nil
+}
+
+// twoFactorLocked reports whether the user has too many recent failures.
+// Callers refuse the code when the check itself fails.
+func twoFactorLocked(ctx context.Context, userID string) (bool, error) {
+	filter := bson.M{"user_id": userID, "count": bson.M{"$gte": maxTwoFactorFailures}, "expires_at": bson.M{"$gt": time.Now()}}
+	n, err := repository.IRepo.Count(ctx, "two_factor_failures", filter)
+	return n > 0, err
+}
+
+func clearTwoFactorFailures(ctx context.Context, userID string) {
+	if err := repository.IRepo.RemoveByFilter(ctx, "two_factor_failures", bson.M{"user_id": userID}); err != nil {
+		log.Printf("❌ Failed to clear 2FA failures for user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"exdex/internal/src/repository"
+)
+
+func TestTwoFactorLockout(t *testing.T) {
+	ctx := context.Background()
+	useMemoryRepo(t).Unique("two_factor_failures", "user_id")
+
+	locked := func() bool {
+		t.Helper()
+		l, err := twoFactorLocked(ctx, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	for i := 0; i < maxTwoFactorFailures; i++ {
+		if locked() {
+			t.Fatalf("locked after %d failures", i)
+		}
+		recordTwoFactorFailure(ctx, "u1")
+	}
+	if !locked() {
+		t.Fatalf("not locked after %d failures", maxTwoFactorFailures)
+	}
+	if l, _ := twoFactorLocked(ctx, "u2"); l {
+		t.Fatal("another user is locked too")
+	}
+
+	// An expired window starts over.
+	expired := bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Second)}}
+	if err := repository.IRepo.UpdateOne(ctx, "two_factor_failures", bson.M{"user_id": "u1"}, expired, false); err != nil {
+		t.Fatal(err)
+	}
+	if locked() {
+		t.Fatal("still locked after the window expired")
+	}
+	recordTwoFactorFailure(ctx, "u1")
+	f, err := repository.FindOne[twoFactorFailure](ctx, "two_factor_failures", bson.M{"user_id": "u1"})
+	if err != nil || f.Count != 1 {
+		t.Fatalf("failure count = %d, %v, want a fresh window with 1", f.Count, err)
+	}
+
+	for i := 0; i < maxTwoFactorFailures; i++ {
+		recordTwoFactorFailure(ctx, "u1")
+	}
+	clearTwoFactorFailures(ctx, "u1")
+	if locked() {
+		t.Fatal("still locked after a successful code")
+	}
+}
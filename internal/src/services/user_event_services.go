@@ -0,0 +1,234 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	models "exdex/internal/src/model"
+)
+
+const (
+	userEventBufferSize = 256
+	userEventChanSize   = 64
+	wsTicketTTL         = 30 * time.Second
+	// A user's replay buffer is kept this long after their last socket
+	// closes or their last event, whichever is later.
+	userEventIdleTTL = 15 * time.Minute
+)
+
+// UserSubscription is one private WebSocket connection listening to a user's events.
+type UserSubscription struct {
+	UserID string
+	C      chan models.UserEvent
+}
+
+type userEventStream struct {
+	seq         int64
+	buffer      []models.UserEvent
+	subscribers map[*UserSubscription]struct{}
+	lastActive  time.Time
+}
+
+// UserEventHub fans out per-user events to every open private socket of that
+// user and keeps a short replay buffer so reconnecting clients can catch up.
+type UserEventHub struct {
+	mu        sync.Mutex
+	streams   map[string]*userEventStream
+	closed    bool
+	lastSweep time.Time
+}
+
+var UserEvents = NewUserEventHub()
+
+func NewUserEventHub() *UserEventHub {
+	return &UserEventHub{streams: make(map[string]*userEventStream)}
+}
+
+func (h *UserEventHub) stream(userID string) *userEventStream {
+	now := time.Now()
+	h.sweep(now)
+	s, ok := h.streams[userID]
+	if !ok {
+		s = &userEventStream{subscribers: make(map[*UserSubscription]struct{})}
+		h.streams[userID] = s
+	}
+	s.lastActive = now
+	return s
+}
+
+// sweep forgets users with no open socket and no recent activity, at most
+// once a minute. A client reconnecting later is told to resync.
+func (h *UserEventHub) sweep(now time.Time) {
+	if now.Sub(h.lastSweep) < time.Minute {
+		return
+	}
+	h.lastSweep = now
+	for userID, s := range h.streams {
+		if len(s.subscribers) == 0 && now.Sub(s.lastActive) > userEventIdleTTL {
+			delete(h.streams, userID)
+		}
+	}
+}
+
+// Subscribe registers a new listener for userID and returns the buffered
+// events after `since` for replay. When some of them were already evicted
+// the replay starts with a resync event, and the client has to resync over
+// REST.
+func (h *UserEventHub) Subscribe(userID string, since int64) (sub *UserSubscription, replay []models.UserEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stream(userID)
+	sub = &UserSubscription{UserID: userID, C: make(chan models.UserEvent, userEventChanSize)}
+	if h.closed {
+		close(sub.C)
+	} else {
+		s.subscribers[sub] = struct{}{}
+	}
+
+	complete := true
+	if since > 0 && since < s.seq {
+		if len(s.buffer) == 0 || s.buffer[0].Seq > since+1 {
+			complete = false
+		}
+		for _, ev := range s.buffer {
+			if ev.Seq > since {
+				replay = append(replay, ev)
+			}
+		}
+	} else if since > s.seq {
+		// Client is ahead of us, e.g. after a server restart.
+		complete = false
+	}
+	if complete {
+		return sub, replay
+	}
+
+	// The resync takes the next number so no two events share one. It only
+	// goes to this socket; the user's other sockets see the gap and resync
+	// too, which is harmless.
+	s.seq++
+	resync := models.UserEvent{
+		Seq:  s.seq,
+		Type: models.EventResync,
+		Time: time.Now().UnixMilli(),
+		Data: models.ResyncEvent{Reason: "events since requested sequence are no longer buffered", LastSeq: s.seq},
+	}
+	return sub, []models.UserEvent{resync}
+}
+
+func (h *UserEventHub) Unsubscribe(sub *UserSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.streams[sub.UserID]
+	if !ok {
+		return
+	}
+	delete(s.subscribers, sub)
+	s.lastActive = time.Now()
+}
+
+// Close ends every subscription so the private sockets disconnect, and
+// makes later subscriptions start closed.
+func (h *UserEventHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, s := range h.streams {
+		for sub := range s.subscribers {
+			close(sub.C)
+		}
+		s.subscribers = make(map[*UserSubscription]struct{})
+	}
+}
+
+// Publish assigns the next sequence number for userID and delivers the event
+// to every subscriber. Slow subscribers drop events instead of blocking the
+// caller; they notice the gap through the sequence number.
+func (h *UserEventHub) Publish(userID, eventType string, data interface{}) {
+	if userID == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stream(userID)
+	s.seq++
+	ev := models.UserEvent{
+		Seq:  s.seq,
+		Type: eventType,
+		Time: time.Now().UnixMilli(),
+		Data: data,
+	}
+
+	s.buffer = append(s.buffer, ev)
+	if len(s.buffer) > userEventBufferSize {
+		s.buffer = s.buffer[len(s.buffer)-userEventBufferSize:]
+	}
+
+	for sub := range s.subscribers {
+		select {
+		case sub.C <- ev:
+		default:
+			wsDropped.Inc(WSChannelPrivate)
+		}
+	}
+}
+
+// PublishAlert pushes an alert trigger to the user's private socket.
+func PublishAlert(userID string, alert models.AlertEvent) {
+	UserEvents.Publish(userID, models.EventAlertTrigger, alert)
+}
+
+type wsTicket struct {
+	userID    string
+	expiresAt time.Time
+}
+
+var (
+	wsTicketsMu sync.Mutex
+	wsTickets   = make(map[string]wsTicket)
+)
+
+// IssueWSTicket creates a short-lived one-time ticket that can be passed as
+// ?ticket= when opening /ws/private from clients that cannot set headers.
+func IssueWSTicket(userID string) (string, time.Time, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := hex.EncodeToString(b)
+	expiresAt := time.Now().Add(wsTicketTTL)
+
+	wsTicketsMu.Lock()
+	defer wsTicketsMu.Unlock()
+
+	now := time.Now()
+	for k, t := range wsTickets {
+		if now.After(t.expiresAt) {
+			delete(wsTickets, k)
+		}
+	}
+	wsTickets[ticket] = wsTicket{userID: userID, expiresAt: expiresAt}
+	return ticket, expiresAt, nil
+}
+
+// RedeemWSTicket consumes a ticket and returns the user it was issued to.
+func RedeemWSTicket(ticket string) (string, bool) {
+	wsTicketsMu.Lock()
+	defer wsTicketsMu.Unlock()
+
+	t, ok := wsTickets[ticket]
+	if !ok {
+		return "", false
+	}
+	delete(wsTickets, ticket)
+	if time.Now().After(t.expiresAt) {
+		return "", false
+	}
+	return t.userID, true
+}
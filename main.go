@@ -1,13 +1,11 @@
 package main
 
 import (
-	"exdex/internal/app"
-	"exdex/internal/router"
+	"os"
+
+	"exdex/internal/cli"
 )
 
 func main() {
-
-	r := router.NewRouter()
-	ap := app.NewApp(r)
-	ap.Start()
+	os.Exit(cli.Run(os.Args[1:]))
 }
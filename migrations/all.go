@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"exdex/server/rbac"
+)
+
+// All is every migration, in any order. Never renumber or remove an entry
+// once it has shipped; add a new one instead.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "users_default_role",
+		// Users created before roles existed have no role; they are plain users.
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			filter := bson.M{"$or": []bson.M{{"role": bson.M{"$exists": false}}, {"role": ""}}}
+			_, err := db.Collection("users").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"role": rbac.RoleUser}})
+			return err
+		},
+	},
+}
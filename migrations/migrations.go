@@ -0,0 +1,253 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Collection records which migrations have been applied.
+const Collection = "schema_migrations"
+
+const (
+	statePending = "pending" // claimed by an instance that is running it
+	stateApplied = "applied"
+
+	claimPoll = time.Second
+	// claimWait bounds how long Up waits for another instance to finish a
+	// migration before it fails.
+	claimWait = 10 * time.Minute
+)
+
+// Migration is one versioned change to the database. Down may be nil for
+// changes that cannot be undone.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+type record struct {
+	Version   int       `bson:"version"`
+	Name      string    `bson:"name"`
+	State     string    `bson:"state,omitempty"` // empty on records written before claims
+	ClaimedAt time.Time `bson:"claimed_at,omitempty"`
+	AppliedAt time.Time `bson:"applied_at,omitempty"`
+}
+
+func (r record) isApplied() bool {
+	return r.State != statePending
+}
+
+func (r record) fill(s *State) {
+	if r.isApplied() {
+		at := r.AppliedAt
+		s.AppliedAt = &at
+	} else {
+		at := r.ClaimedAt
+		s.InProgressSince = &at
+	}
+}
+
+// State is a migration and when it was applied, if it has been, or since
+// when an instance has been applying it.
+type State struct {
+	Version         int        `json:"version"`
+	Name            string     `json:"name"`
+	AppliedAt       *time.Time `json:"applied_at,omitempty"`
+	InProgressSince *time.Time `json:"in_progress_since,omitempty"`
+}
+
+func sorted() ([]Migration, error) {
+	list := append([]Migration(nil), All...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i := 1; i < len(list); i++ {
+		if list[i].Version == list[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", list[i].Version)
+		}
+	}
+	return list, nil
+}
+
+// records returns the migration records by version, pending ones included.
+func records(ctx context.Context, db *mongo.Database) (map[int]record, error) {
+	cursor, err := db.Collection(Collection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	out := make(map[int]record, len(records))
+	for _, r := range records {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it ran. Each migration is claimed by inserting a pending record first, so
+// when several instances start together only one of them runs it; the
+// others wait for it to be marked applied before going on to the next.
+func Up(ctx context.Context, db *mongo.Database) ([]Migration, error) {
+	list, err := sorted()
+	if err != nil {
+		return nil, err
+	}
+	done, err := records(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := db.Collection(Collection)
+	var ran []Migration
+	for _, m := range list {
+		if r, ok := done[m.Version]; ok && r.isApplied() {
+			continue
+		}
+		claimed, err := claim(ctx, coll, m)
+		if err != nil {
+			return ran, err
+		}
+		if !claimed {
+			continue
+		}
+		log.Printf("⬆️  Applying migration %d %s", m.Version, m.Name)
+		if err := m.Up(ctx, db); err != nil {
+			release(ctx, coll, m)
+			return ran, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+		}
+		update := bson.M{"$set": bson.M{"state": stateApplied, "applied_at": time.Now()}}
+		if _, err := coll.UpdateOne(ctx, bson.M{"version": m.Version}, update); err != nil {
+			return ran, fmt.Errorf("migration %d %s ran but could not be marked applied: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// claim records m as pending for this instance. While another instance
+// holds the claim it waits: it returns false once that instance has applied
+// m, takes the claim over if the other instance released it after failing,
+// and gives up after claimWait.
+func claim(ctx context.Context, coll *mongo.Collection, m Migration) (bool, error) {
+	deadline := time.Now().Add(claimWait)
+	logged := false
+	for {
+		_, err := coll.InsertOne(ctx, record{Version: m.Version, Name: m.Name, State: statePending, ClaimedAt: time.Now()})
+		if err == nil {
+			return true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return false, err
+		}
+
+		var r record
+		err = coll.FindOne(ctx, bson.M{"version": m.Version}).Decode(&r)
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			continue
+		case err != nil:
+			return false, err
+		case r.isApplied():
+			return false, nil
+		case time.Now().After(deadline):
+			return false, fmt.Errorf("migration %d %s is still being applied by another instance, claimed at %s; if that instance died, delete the %s record with version %d",
+				m.Version, m.Name, r.ClaimedAt.Format(time.RFC3339), Collection, m.Version)
+		}
+		if !logged {
+			log.Printf("⏳ Waiting for another instance to apply migration %d %s", m.Version, m.Name)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(claimPoll):
+		}
+	}
+}
+
+// release drops this instance's claim on m after it failed, so the next
+// attempt can run it again. It still runs when ctx is what failed.
+func release(ctx context.Context, coll *mongo.Collection, m Migration) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if _, err := coll.DeleteOne(ctx, bson.M{"version": m.Version, "state": statePending}); err != nil {
+		log.Printf("❌ Failed to release claim on migration %d %s: %v", m.Version, m.Name, err)
+	}
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func Down(ctx context.Context, db *mongo.Database, steps int) ([]Migration, error) {
+	list, err := sorted()
+	if err != nil {
+		return nil, err
+	}
+	done, err := records(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := db.Collection(Collection)
+	var reverted []Migration
+	for i := len(list) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := list[i]
+		r, ok := done[m.Version]
+		if !ok {
+			continue
+		}
+		if !r.isApplied() {
+			return reverted, fmt.Errorf("migration %d %s is being applied, try again once it is done", m.Version, m.Name)
+		}
+		if m.Down == nil {
+			return reverted, fmt.Errorf("migration %d %s cannot be reverted", m.Version, m.Name)
+		}
+		log.Printf("⬇️  Reverting migration %d %s", m.Version, m.Name)
+		if err := m.Down(ctx, db); err != nil {
+			return reverted, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+		}
+		if _, err := coll.DeleteOne(ctx, bson.M{"version": m.Version}); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// Status lists every known migration with its applied time. Records for
+// versions this build does not know about are included too.
+func Status(ctx context.Context, db *mongo.Database) ([]State, error) {
+	list, err := sorted()
+	if err != nil {
+		return nil, err
+	}
+	done, err := records(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []State
+	for _, m := range list {
+		s := State{Version: m.Version, Name: m.Name}
+		if r, ok := done[m.Version]; ok {
+			r.fill(&s)
+			delete(done, m.Version)
+		}
+		out = append(out, s)
+	}
+	for _, r := range done {
+		s := State{Version: r.Version, Name: r.Name + " (unknown)"}
+		r.fill(&s)
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
@@ -14,6 +14,7 @@ var (
 	GATEWAYTIMEOUT       = 504
 	UNSUPPORTEDMEDIATYPE = 415
 	UNPROCESSABLEENTITY  = 422
+	TOOMANYREQUESTS      = 429
 	PENDING              = "PENDING"
 	COMPLETED            = "COMPLETED"
 	DEPOSIT              = "DEPOSIT"
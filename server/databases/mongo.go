@@ -2,15 +2,18 @@ package database
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
-	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
+	"exdex/config"
 	"exdex/indexing"
+	"exdex/migrations"
 )
 
 var once sync.Once
@@ -19,16 +22,19 @@ var DB *mongo.Database
 func Init() *mongo.Database {
 	once.Do(func() {
 
-		uri := viper.GetString("mongo.uri")
-		dbname := viper.GetString("mongo.dbname")
-		username := viper.GetString("mongo.username")
-		password := viper.GetString("mongo.password")
+		uri := config.C.Mongo.URI
+		dbname := config.C.Mongo.DBName
+		username := config.C.Mongo.Username
+		password := config.C.Mongo.Password
 
 		clientOptions := options.Client().ApplyURI(uri)
-		clientOptions.SetAuth(options.Credential{
-			Username: username,
-			Password: password,
-		})
+		// Credentials may also be part of the URI.
+		if username != "" {
+			clientOptions.SetAuth(options.Credential{
+				Username: username,
+				Password: password,
+			})
+		}
 
 		// Create a context with a timeout
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -52,11 +58,38 @@ func Init() *mongo.Database {
 		// Log successful connection
 
 		log.Info().Msgf("Successfully established connection to %s/%s", uri, dbname)
-		// index := indexing.Indexining{}
-		indexing.InitIndexining(ctx, client)
-		// index.CreatIndex(client.Database(dbname))
 		DB = client.Database(dbname)
 
+		// Index builds and migrations can outlast the connect timeout.
+		setupCtx, setupCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer setupCancel()
+		if err := indexing.EnsureIndexes(setupCtx, DB); err != nil {
+			log.Panic().Msgf("Index creation failed: %v", err)
+		}
+		if config.C.Mongo.MigrateOnStart {
+			ran, err := migrations.Up(setupCtx, DB)
+			if err != nil {
+				log.Panic().Msgf("Migrations failed: %v", err)
+			}
+			log.Info().Msgf("Applied %d migration(s)", len(ran))
+		}
 	})
 	return DB
 }
+
+// Ping checks that the primary answers.
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("not connected")
+	}
+	return DB.Client().Ping(ctx, readpref.Primary())
+}
+
+// Close disconnects from MongoDB, waiting for in-use connections until ctx
+// expires.
+func Close(ctx context.Context) error {
+	if DB == nil {
+		return nil
+	}
+	return DB.Client().Disconnect(ctx)
+}
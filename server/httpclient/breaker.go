@@ -0,0 +1,159 @@
+package httpclient
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	failureThreshold = 5 // consecutive failures that open the breaker
+	openDuration     = 30 * time.Second
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open" // one probe call is let through
+)
+
+type breaker struct {
+	mu sync.Mutex
+
+	name        string
+	state       string
+	failures    int // consecutive
+	openedAt    time.Time
+	probing     bool
+	lastError   string
+	lastFailure time.Time
+	lastSuccess time.Time
+	requests    int64
+	errors      int64
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker)
+)
+
+func breakerFor(name string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[name]
+	if !ok {
+		b = &breaker{name: name, state: StateClosed}
+		breakers[name] = b
+	}
+	return b
+}
+
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if wait := openDuration - time.Since(b.openedAt); wait > 0 {
+			return &CircuitOpenError{Upstream: b.name, RetryAfter: wait}
+		}
+		b.state = StateHalfOpen
+		b.probing = false
+	}
+	if b.state == StateHalfOpen {
+		if b.probing {
+			return &CircuitOpenError{Upstream: b.name, RetryAfter: time.Second}
+		}
+		b.probing = true
+	}
+	b.requests++
+	return nil
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateClosed {
+		log.Printf("✅ Upstream %s recovered, closing circuit", b.name)
+	}
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+	b.lastSuccess = time.Now()
+}
+
+// release gives back a call that ended without telling anything about the
+// upstream, such as one the caller canceled.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) failure(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.errors++
+	b.lastError = reason
+	b.lastFailure = time.Now()
+	b.probing = false
+
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= failureThreshold) {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+		log.Printf("🔌 Upstream %s failing (%s), opening circuit for %s", b.name, reason, openDuration)
+	}
+}
+
+// UpstreamStatus is the breaker view of one upstream.
+type UpstreamStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Requests            int64      `json:"requests"`
+	Errors              int64      `json:"errors"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+// Status lists every upstream called since start.
+func Status() []UpstreamStatus {
+	breakersMu.Lock()
+	list := make([]*breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	out := make([]UpstreamStatus, 0, len(list))
+	for _, b := range list {
+		b.mu.Lock()
+		s := UpstreamStatus{
+			Name:                b.name,
+			State:               b.state,
+			ConsecutiveFailures: b.failures,
+			Requests:            b.requests,
+			Errors:              b.errors,
+			LastError:           b.lastError,
+		}
+		if !b.lastFailure.IsZero() {
+			t := b.lastFailure
+			s.LastFailureAt = &t
+		}
+		if !b.lastSuccess.IsZero() {
+			t := b.lastSuccess
+			s.LastSuccessAt = &t
+		}
+		if b.state == StateOpen {
+			t := b.openedAt.Add(openDuration)
+			s.OpenUntil = &t
+		}
+		b.mu.Unlock()
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
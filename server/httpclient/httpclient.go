@@ -0,0 +1,162 @@
+// Package httpclient is the shared client for calls to upstream services.
+// Connections are pooled, each attempt has its own timeout bounded by the
+// caller's context, idempotent reads are retried with jitter and every
+// upstream sits behind its own circuit breaker.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	maxAttempts    = 3
+	baseBackoff    = 200 * time.Millisecond
+	maxBackoff     = 2 * time.Second
+)
+
+var client = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// Response is a fully read upstream response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Options tune a single call. Zero values use the defaults.
+type Options struct {
+	Timeout time.Duration // per attempt
+	NoRetry bool
+}
+
+// Do sends req to the named upstream. Only GET and HEAD are retried, so a
+// request with a body is sent at most once. Responses of any status are
+// returned; 5xx responses count against the breaker, calls the caller's
+// context canceled do not.
+func Do(ctx context.Context, upstream string, req *http.Request, opts ...Options) (*Response, error) {
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	b := breakerFor(upstream)
+	attempts := 1
+	if !o.NoRetry && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		attempts = maxAttempts
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+		if err := b.allow(); err != nil {
+			return nil, err
+		}
+
+		resp, err := send(ctx, req, o.Timeout)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// The caller gave up; that says nothing about the upstream.
+			b.release()
+			return nil, ctx.Err()
+		case err != nil:
+			b.failure(err.Error())
+			lastErr = err
+		case resp.StatusCode >= 500:
+			b.failure(fmt.Sprintf("HTTP %d", resp.StatusCode))
+			if attempt == attempts-1 {
+				return resp, nil
+			}
+			lastErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+		default:
+			b.success()
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", upstream, lastErr)
+}
+
+func send(ctx context.Context, req *http.Request, timeout time.Duration) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := client.Do(req.Clone(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// backoff is exponential with full jitter.
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << (attempt - 1)
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+var ErrCircuitOpen = errors.New("upstream is unavailable")
+
+// CircuitOpenError is returned without calling the upstream while its
+// breaker is open.
+type CircuitOpenError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v: %s, retry in %s", ErrCircuitOpen, e.Upstream, e.RetryAfter.Round(time.Second))
+}
+
+func (e *CircuitOpenError) Unwrap() error { return ErrCircuitOpen }
+
+func (e *CircuitOpenError) HTTPStatus() int { return http.StatusServiceUnavailable }
+
+func (e *CircuitOpenError) ErrorCode() string { return "UPSTREAM_UNAVAILABLE" }
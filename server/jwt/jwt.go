@@ -1,37 +1,105 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/spf13/viper"
+
+	"exdex/config"
 )
 
-func GenerateJWT(userID string, email string, role string) (string, error) {
-	expiration, err := time.ParseDuration(viper.GetString("jwt.expiration"))
-	if err != nil {
-		return "", err
+// Claims is the verified content of an access token.
+type Claims struct {
+	UserID    string
+	Email     string
+	Role      string
+	JTI       string // unique per token, for revocation
+	SessionID string // refresh token family the token was issued from
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func GenerateJWT(userID string, email string, role string, sessionID string) (string, *Claims, error) {
+	expiration := config.C.JWT.Expiration
+	if expiration <= 0 {
+		return "", nil, errors.New("jwt.expiration is not configured")
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		JTI:       hex.EncodeToString(jti),
+		SessionID: sessionID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(expiration),
 	}
 
-	// fmt.Println("lsfkjglsfjg", viper.GetString("jwt.expiration"))
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"id":    userID,
 		"email": email,
 		"role":  role, // Include role in the JWT token
-		"exp":   time.Now().Add(expiration).Unix(),
+		"jti":   claims.JTI,
+		"sid":   sessionID,
+		"iat":   now.Unix(),
+		"exp":   claims.ExpiresAt.Unix(),
 	})
 
-	tokenString, err := token.SignedString([]byte(viper.GetString("jwt.secret")))
+	tokenString, err := token.SignedString([]byte(config.C.JWT.Secret))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, claims, nil
+}
+
+// ParseToken verifies the signature and expiry of an access token and
+// returns its claims.
+func ParseToken(tokenString string) (*Claims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(config.C.JWT.Secret), nil
+	})
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("invalid token: %v", err)
 	}
 
-	return tokenString, nil
+	mc, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	claims := &Claims{}
+	claims.UserID, _ = mc["id"].(string)
+	claims.Email, _ = mc["email"].(string)
+	claims.Role, _ = mc["role"].(string)
+	claims.JTI, _ = mc["jti"].(string)
+	claims.SessionID, _ = mc["sid"].(string)
+	if iat, ok := mc["iat"].(float64); ok {
+		claims.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	if exp, ok := mc["exp"].(float64); ok {
+		claims.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	if claims.UserID == "" || claims.JTI == "" {
+		return nil, fmt.Errorf("invalid claim format")
+	}
+	return claims, nil
 }
+
 func ExtractClaims(tokenString string) (string, string, string, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(viper.GetString("jwt.secret")), nil // Use correct secret key
+		return []byte(config.C.JWT.Secret), nil // Use correct secret key
 	})
 
 	if err != nil {
@@ -59,7 +127,7 @@ func ValidateToken(tokenString string) (bool, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(viper.GetString("jwt.secret")), nil
+		return []byte(config.C.JWT.Secret), nil
 	})
 
 	if err != nil {
@@ -0,0 +1,133 @@
+// Package lifecycle starts the server's subsystems in order and stops them
+// in reverse order when the process is asked to exit.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Hook is one subsystem. Start must not block: long-running work belongs in
+// a goroutine that reports failures through Manager.Fail. Stop should
+// return once the subsystem has finished, or when ctx expires. Either may
+// be nil.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// Manager runs registered hooks. Hooks start in registration order and stop
+// in reverse, so a subsystem is only stopped after everything registered
+// after it, which may depend on it, has stopped.
+type Manager struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started []Hook
+	failed  chan error
+}
+
+func New() *Manager {
+	return &Manager{failed: make(chan error, 1)}
+}
+
+// Register adds a hook. Register everything before calling Start or Run.
+func (m *Manager) Register(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, h)
+}
+
+// Fail reports that a running subsystem died; Run then shuts everything
+// down and returns err. Only the first failure is kept.
+func (m *Manager) Fail(err error) {
+	select {
+	case m.failed <- err:
+	default:
+	}
+}
+
+// Start runs the start hooks in order. When one fails, the hooks already
+// started are stopped again before the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	for _, h := range hooks {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				err = fmt.Errorf("starting %s: %w", h.Name, err)
+				if stopErr := m.Stop(ctx); stopErr != nil {
+					err = errors.Join(err, stopErr)
+				}
+				return err
+			}
+		}
+		m.mu.Lock()
+		m.started = append(m.started, h)
+		m.mu.Unlock()
+		log.Printf("▶️  Started %s", h.Name)
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the started subsystems in reverse order. Each
+// hook shares what is left of ctx's deadline; a hook that fails or runs out
+// of time does not keep the rest from stopping.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		h := started[i]
+		if h.Stop == nil {
+			continue
+		}
+		begin := time.Now()
+		if err := h.Stop(ctx); err != nil {
+			log.Printf("❌ Stopping %s: %v", h.Name, err)
+			errs = append(errs, fmt.Errorf("stopping %s: %w", h.Name, err))
+			continue
+		}
+		log.Printf("⏹️  Stopped %s in %s", h.Name, time.Since(begin).Round(time.Millisecond))
+	}
+	return errors.Join(errs...)
+}
+
+// Run starts every hook, waits for SIGINT, SIGTERM or a failure reported
+// through Fail, and then stops everything within timeout.
+func (m *Manager) Run(timeout time.Duration) error {
+	if err := m.Start(context.Background()); err != nil {
+		return err
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	var cause error
+	select {
+	case sig := <-quit:
+		log.Printf("🛑 %s received, shutting down", sig)
+	case cause = <-m.failed:
+		log.Printf("🛑 %v, shutting down", cause)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := m.Stop(ctx); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
@@ -0,0 +1,337 @@
+// Package metrics keeps counters, gauges and histograms in memory and
+// writes them in the Prometheus text exposition format.
+//
+// Every label value ends up as a separate series, so label values must come
+// from a small fixed set: route patterns rather than paths, order types
+// rather than symbols, never user or order ids. As a safety net each metric
+// stops creating series after maxSeries and counts further values under
+// OverflowLabel instead.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxSeries = 500
+
+// OverflowLabel replaces the label values of series past maxSeries.
+const OverflowLabel = "other"
+
+// DefBuckets suits request latencies in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector is one metric family.
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	registry = append(registry, c)
+	registryMu.Unlock()
+}
+
+// Write writes every registered metric to w.
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	cs := append([]collector(nil), registry...)
+	registryMu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range cs {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Since returns the seconds elapsed since start, the unit histograms of
+// durations are observed in.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// family holds what every metric type shares: its name, help and labels,
+// and the series created so far, keyed by their joined label values.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]any
+}
+
+func newFamily(name, help, kind string, labels []string) family {
+	return family{name: name, help: help, kind: kind, labels: labels, series: make(map[string]any)}
+}
+
+// get returns the series for values, creating it with create.
+func (f *family) get(values []string, create func() any) any {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	if len(f.series) >= maxSeries {
+		overflow := make([]string, len(values))
+		for i := range overflow {
+			overflow[i] = OverflowLabel
+		}
+		key = strings.Join(overflow, "\xff")
+		if s, ok := f.series[key]; ok {
+			return s
+		}
+	}
+	s := create()
+	f.series[key] = s
+	return s
+}
+
+// sorted returns the series ordered by label values, with their values.
+func (f *family) sorted() ([][]string, []any) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([][]string, len(keys))
+	series := make([]any, len(keys))
+	for i, k := range keys {
+		if len(f.labels) > 0 {
+			values[i] = strings.Split(k, "\xff")
+		}
+		series[i] = f.series[k]
+	}
+	f.mu.Unlock()
+	return values, series
+}
+
+func (f *family) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+}
+
+// Counter is a value that only goes up, split by labels.
+type Counter struct {
+	family
+}
+
+// NewCounter registers a counter with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newFamily(name, help, "counter", labels)}
+	register(c)
+	return c
+}
+
+type floatValue struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *floatValue) add(d float64) {
+	v.mu.Lock()
+	v.v += d
+	v.mu.Unlock()
+}
+
+func (v *floatValue) set(x float64) {
+	v.mu.Lock()
+	v.v = x
+	v.mu.Unlock()
+}
+
+func (v *floatValue) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+func (c *Counter) value(values []string) *floatValue {
+	return c.get(values, func() any { return &floatValue{} }).(*floatValue)
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(values ...string) {
+	c.value(values).add(1)
+}
+
+// Add adds d, which must not be negative.
+func (c *Counter) Add(d float64, values ...string) {
+	if d < 0 {
+		return
+	}
+	c.value(values).add(d)
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.header(w)
+	values, series := c.sorted()
+	for i, s := range series {
+		writeSample(w, c.name, c.labels, values[i], "", "", s.(*floatValue).get())
+	}
+}
+
+// Gauge is a value that goes up and down, split by labels.
+type Gauge struct {
+	family
+}
+
+// NewGauge registers a gauge with the given label names.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newFamily(name, help, "gauge", labels)}
+	register(g)
+	return g
+}
+
+func (g *Gauge) value(values []string) *floatValue {
+	return g.get(values, func() any { return &floatValue{} }).(*floatValue)
+}
+
+func (g *Gauge) Set(v float64, values ...string) { g.value(values).set(v) }
+func (g *Gauge) Add(d float64, values ...string) { g.value(values).add(d) }
+func (g *Gauge) Inc(values ...string)            { g.value(values).add(1) }
+func (g *Gauge) Dec(values ...string)            { g.value(values).add(-1) }
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.header(w)
+	values, series := g.sorted()
+	for i, s := range series {
+		writeSample(w, g.name, g.labels, values[i], "", "", s.(*floatValue).get())
+	}
+}
+
+// GaugeFunc is a gauge without labels whose value is read when scraped.
+type GaugeFunc struct {
+	family
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge that calls fn on every scrape.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{family: newFamily(name, help, "gauge", nil), fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.header(w)
+	writeSample(w, g.name, nil, nil, "", "", g.fn())
+}
+
+// Histogram counts observations into cumulative buckets, split by labels.
+type Histogram struct {
+	family
+	buckets []float64
+}
+
+// NewHistogram registers a histogram. buckets are upper bounds in
+// increasing order; +Inf is implied.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{family: newFamily(name, help, "histogram", labels), buckets: buckets}
+	register(h)
+	return h
+}
+
+type histogramValue struct {
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// Observe records v in the series with the given label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	s := h.get(values, func() any {
+		return &histogramValue{counts: make([]uint64, len(h.buckets)+1)}
+	}).(*histogramValue)
+
+	i := sort.SearchFloat64s(h.buckets, v)
+	s.mu.Lock()
+	s.counts[i]++
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.header(w)
+	values, series := h.sorted()
+	for i, raw := range series {
+		s := raw.(*histogramValue)
+		s.mu.Lock()
+		counts := append([]uint64(nil), s.counts...)
+		sum, count := s.sum, s.count
+		s.mu.Unlock()
+
+		var cumulative uint64
+		for b, upper := range h.buckets {
+			cumulative += counts[b]
+			writeSample(w, h.name+"_bucket", h.labels, values[i], "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, values[i], "le", "+Inf", float64(count))
+		writeSample(w, h.name+"_sum", h.labels, values[i], "", "", sum)
+		writeSample(w, h.name+"_count", h.labels, values[i], "", "", float64(count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", l, escapeLabel(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/services"
+	"exdex/server/constant"
+)
+
+// RefuseWritesWhileDraining answers 503 to every request that could place
+// an order once the server has begun shutting down. Reads keep working
+// while in-flight requests drain, and the load balancer retries writes
+// elsewhere.
+func RefuseWritesWhileDraining() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if services.AcceptingOrders() {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		c.Set(fiber.HeaderConnection, "close")
+		c.Set(fiber.HeaderRetryAfter, "5")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"code":   constant.SERVICEUNAVAILABLE,
+			"status": false,
+			"error":  "Server is shutting down, retry shortly",
+		})
+	}
+}
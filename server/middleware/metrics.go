@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/server/metrics"
+)
+
+var (
+	httpRequests = metrics.NewCounter("exdex_http_requests_total",
+		"HTTP requests by method, route pattern and status.", "method", "route", "status")
+	httpRequestSeconds = metrics.NewHistogram("exdex_http_request_duration_seconds",
+		"HTTP request latency by method and route pattern.",
+		metrics.DefBuckets, "method", "route")
+)
+
+// Metrics counts requests and times them. Routes are labelled by their
+// pattern, e.g. /v1/auth/order/:id, never the raw path; requests that
+// match no route are labelled "unmatched".
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not written the response yet.
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+
+		path := c.Route().Path
+		if isRouteNotFound(c, err) {
+			// Only middleware ran, so the route is a middleware prefix.
+			path = "unmatched"
+		}
+		method := c.Method()
+		switch method {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodPost, fiber.MethodPut,
+			fiber.MethodPatch, fiber.MethodDelete, fiber.MethodOptions:
+		default:
+			method = "OTHER"
+		}
+
+		httpRequests.Inc(method, path, strconv.Itoa(status))
+		if status != fiber.StatusSwitchingProtocols {
+			// An upgraded request lasts as long as its WebSocket.
+			httpRequestSeconds.Observe(metrics.Since(start), method, path)
+		}
+		return err
+	}
+}
+
+// isRouteNotFound reports whether err is the router's own 404 for a path
+// no route matches, as opposed to a handler answering 404.
+func isRouteNotFound(c *fiber.Ctx, err error) bool {
+	var fe *fiber.Error
+	return errors.As(err, &fe) && fe.Code == fiber.StatusNotFound &&
+		strings.HasPrefix(fe.Message, "Cannot "+c.Method()+" ")
+}
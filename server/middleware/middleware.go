@@ -1,15 +1,47 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 
+	"exdex/internal/src/services"
 	"exdex/server/constant"
 	"exdex/server/jwt"
+	"exdex/server/rbac"
 )
 
+// RevocationCheck reports whether a verified access token has since been
+// revoked. An error means it could not tell, and the token is refused.
+type RevocationCheck func(ctx context.Context, claims *jwt.Claims) (bool, error)
+
+var (
+	revocationMu    sync.RWMutex
+	revocationCheck RevocationCheck
+)
+
+// SetRevocationCheck installs the check JWTMiddleware runs on every token.
+// Until one is set every token is refused.
+func SetRevocationCheck(check RevocationCheck) {
+	revocationMu.Lock()
+	revocationCheck = check
+	revocationMu.Unlock()
+}
+
+func tokenRevoked(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	revocationMu.RLock()
+	check := revocationCheck
+	revocationMu.RUnlock()
+	if check == nil {
+		return false, errors.New("no revocation check configured")
+	}
+	return check(ctx, claims)
+}
+
 // JWTMiddleware validates the token and attaches claims to the context
 func JWTMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -24,8 +56,8 @@ func JWTMiddleware() fiber.Handler {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		valid, err := jwt.ValidateToken(tokenString)
-		if err != nil || !valid {
+		claims, err := jwt.ParseToken(tokenString)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"code":   constant.UNAUTHORIZED,
 				"status": false,
@@ -33,14 +65,22 @@ func JWTMiddleware() fiber.Handler {
 			})
 		}
 
-		id, email, role, err := jwt.ExtractClaims(tokenString)
+		revoked, err := tokenRevoked(c.UserContext(), claims)
 		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"code":   constant.SERVICEUNAVAILABLE,
+				"status": false,
+				"error":  "Could not verify token, try again",
+			})
+		}
+		if revoked {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"code":   constant.UNAUTHORIZED,
 				"status": false,
-				"error":  "Failed to extract token claims",
+				"error":  "Token has been revoked",
 			})
 		}
+		id, email, role := claims.UserID, claims.Email, claims.Role
 
 		fmt.Println(">>>")
 		fmt.Println(">>>", id)
@@ -49,6 +89,7 @@ func JWTMiddleware() fiber.Handler {
 		c.Locals("userID", id)
 		c.Locals("email", email)
 		c.Locals("role", role)
+		c.Locals("claims", claims)
 
 		return c.Next()
 	}
@@ -67,3 +108,133 @@ func RoleMiddleware(expectedRole string) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// RequirePermission allows the request only if the caller's role grants all
+// of the given permissions, and for API keys only if the key's scopes do too.
+// It must run after JWTMiddleware or AuthMiddleware.
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		scopes, viaAPIKey := c.Locals("apiKeyScopes").([]string)
+		for _, p := range permissions {
+			if !rbac.HasPermission(role, p) || (viaAPIKey && !rbac.ScopesAllow(scopes, p)) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"code":   constant.FORBIDDEN,
+					"status": false,
+					"error":  "Access denied: missing permission " + p,
+				})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// AuthMiddleware accepts either a signed API key request or a JWT.
+func AuthMiddleware() fiber.Handler {
+	jwtHandler := JWTMiddleware()
+	apiKeyHandler := APIKeyMiddleware()
+	return func(c *fiber.Ctx) error {
+		if c.Get("X-API-KEY") != "" {
+			return apiKeyHandler(c)
+		}
+		return jwtHandler(c)
+	}
+}
+
+// APIKeyMiddleware authenticates a request signed with an API key secret.
+// The client sends X-API-KEY, X-API-TIMESTAMP (unix ms), an optional
+// X-API-RECV-WINDOW (ms) and X-API-SIGNATURE, the hex HMAC-SHA256 of
+// timestamp + method + path with query string + raw body.
+func APIKeyMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timestamp := c.Get("X-API-TIMESTAMP")
+		req := services.SignedRequest{
+			Key:        c.Get("X-API-KEY"),
+			Timestamp:  timestamp,
+			RecvWindow: c.Get("X-API-RECV-WINDOW"),
+			Signature:  c.Get("X-API-SIGNATURE"),
+			Payload:    timestamp + c.Method() + c.OriginalURL() + string(c.Body()),
+			IP:         c.IP(),
+		}
+		if req.Key == "" || req.Timestamp == "" || req.Signature == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"code":   constant.UNAUTHORIZED,
+				"status": false,
+				"error":  "Missing API key, timestamp or signature header",
+			})
+		}
+
+		apiKeyServices := services.APIKeyServices{}
+		key, user, err := apiKeyServices.Authenticate(c.UserContext(), req)
+		if err != nil {
+			status := fiber.StatusUnauthorized
+			code := constant.UNAUTHORIZED
+			if errors.Is(err, services.ErrIPNotAllowed) {
+				status, code = fiber.StatusForbidden, constant.FORBIDDEN
+			} else if errors.Is(err, services.ErrAPIAuthUnavailable) {
+				status, code = fiber.StatusServiceUnavailable, constant.SERVICEUNAVAILABLE
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"code":   code,
+				"status": false,
+				"error":  err.Error(),
+			})
+		}
+
+		// Anything that changes state needs the trade scope.
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead && !rbac.ScopesAllow(key.Scopes, rbac.PermOrdersWrite) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"code":   constant.FORBIDDEN,
+				"status": false,
+				"error":  "API key is read-only",
+			})
+		}
+
+		c.Locals("userID", user.ID)
+		c.Locals("email", user.Email)
+		c.Locals("role", user.Role)
+		c.Locals("apiKeyID", key.ID.Hex())
+		c.Locals("apiKeyScopes", key.Scopes)
+
+		return c.Next()
+	}
+}
+
+// SessionOnly rejects API key requests, for routes such as key management
+// and logout that only make sense for an interactive session.
+func SessionOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("apiKeyID") != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"code":   constant.FORBIDDEN,
+				"status": false,
+				"error":  "This endpoint cannot be used with an API key",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireStepUp guards sensitive routes: the session must have confirmed a
+// two-factor code recently. API keys cannot step up and are refused.
+func RequireStepUp() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("claims").(*jwt.Claims)
+		if !ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"code":   constant.FORBIDDEN,
+				"status": false,
+				"error":  "This endpoint needs an interactive session",
+			})
+		}
+		if err := services.CheckStepUp(c.UserContext(), claims.UserID, claims.SessionID); err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"code":       constant.FORBIDDEN,
+				"status":     false,
+				"error":      err.Error(),
+				"error_code": "STEP_UP_REQUIRED",
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/spf13/viper"
+
+	"exdex/config"
+	"exdex/server/constant"
+)
+
+// RateLimit limits requests per caller within a named bucket. Callers are
+// keyed by user when authenticated and by IP otherwise, so it should run
+// after the auth middleware on protected routes. rate_limit.<name>.max and
+// rate_limit.<name>.window override the defaults; rate_limit.enabled: false
+// turns all limits off.
+func RateLimit(name string, max int, window time.Duration) fiber.Handler {
+	return RateLimitBy(name, max, window, func(c *fiber.Ctx) string {
+		if id, ok := c.Locals("userID").(string); ok && id != "" {
+			return "user:" + id
+		}
+		return "ip:" + c.IP()
+	})
+}
+
+// RateLimitBy is RateLimit with a custom caller key, for public routes where
+// the IP says little, such as webhooks sent from shared infrastructure.
+func RateLimitBy(name string, max int, window time.Duration, key func(c *fiber.Ctx) string) fiber.Handler {
+	if v := viper.GetInt("rate_limit." + name + ".max"); v > 0 {
+		max = v
+	}
+	if v := viper.GetDuration("rate_limit." + name + ".window"); v > 0 {
+		window = v
+	}
+	disabled := !config.C.RateLimit.Enabled
+
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: window,
+		Next: func(c *fiber.Ctx) bool {
+			return disabled
+		},
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return name + ":" + key(c)
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"code":        constant.TOOMANYREQUESTS,
+				"status":      false,
+				"error":       "Too many requests, slow down",
+				"retry_after": c.GetRespHeader(fiber.HeaderRetryAfter),
+				"limit":       strconv.Itoa(max) + " per " + window.String(),
+			})
+		},
+	})
+}
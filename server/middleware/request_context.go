@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// RequestContext gives every request a context with a deadline of timeout,
+// read by handlers through c.UserContext(). Calls made on its behalf stop
+// when the deadline passes instead of running on after the response.
+// WebSocket upgrades are left alone as they outlive any request deadline.
+func RequestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if timeout <= 0 || websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
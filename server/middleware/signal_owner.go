@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+
+	"exdex/internal/src/services"
+)
+
+// SignalOwner resolves the signal token, from the URL or the "token" body
+// field, to its owner and stores it as userID, so per-user limits such as
+// the shared orders bucket count webhooks against the user they trade for.
+// Unknown tokens pass through untouched; the handler rejects them.
+func SignalOwner() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Params("token")
+		if token == "" {
+			var body struct {
+				Token string `json:"token"`
+			}
+			json.Unmarshal(c.Body(), &body)
+			token = body.Token
+		}
+		if token == "" {
+			return c.Next()
+		}
+		if userID, err := services.SignalTokenOwner(c.UserContext(), token); err == nil {
+			c.Locals("userID", userID)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,95 @@
+package rbac
+
+import "strings"
+
+const (
+	RoleUser    = "user"
+	RoleTrader  = "trader"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+const (
+	PermOrdersRead     = "orders:read"     // own orders
+	PermOrdersWrite    = "orders:write"    // place and cancel own orders
+	PermOrdersReadAny  = "orders:read:any" // any user's orders
+	PermCopyLead       = "copy:lead"       // publish trades for copy trading
+	PermUsersRead      = "users:read"
+	PermUsersManage    = "users:manage" // change roles, suspend
+	PermSessionsRevoke = "sessions:revoke"
+	PermAdminAccess    = "admin:access" // enter the /v1/admin group
+)
+
+var userPermissions = []string{PermOrdersRead, PermOrdersWrite}
+
+var rolePermissions = map[string][]string{
+	RoleUser:   userPermissions,
+	RoleTrader: append([]string{PermCopyLead}, userPermissions...),
+	RoleSupport: {
+		PermOrdersRead, PermOrdersReadAny, PermUsersRead, PermSessionsRevoke, PermAdminAccess,
+	},
+	RoleAdmin: {
+		PermOrdersRead, PermOrdersWrite, PermOrdersReadAny, PermCopyLead,
+		PermUsersRead, PermUsersManage, PermSessionsRevoke, PermAdminAccess,
+	},
+}
+
+// Roles lists the defined roles in increasing order of privilege.
+var Roles = []string{RoleUser, RoleTrader, RoleSupport, RoleAdmin}
+
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// NormalizeRole maps a stored or legacy role string to a defined role.
+// Anything unknown gets the least privileged role.
+func NormalizeRole(role string) string {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if IsRole(role) {
+		return role
+	}
+	return RoleUser
+}
+
+func Permissions(role string) []string {
+	return rolePermissions[NormalizeRole(role)]
+}
+
+func HasPermission(role, permission string) bool {
+	for _, p := range Permissions(role) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// API key scopes. A key acts with the permissions of its owner's role,
+// narrowed to what its scopes allow; admin access is never granted to a key.
+const (
+	ScopeRead  = "read"
+	ScopeTrade = "trade"
+)
+
+var scopePermissions = map[string][]string{
+	ScopeRead:  {PermOrdersRead},
+	ScopeTrade: {PermOrdersRead, PermOrdersWrite},
+}
+
+func IsScope(scope string) bool {
+	_, ok := scopePermissions[scope]
+	return ok
+}
+
+// ScopesAllow reports whether any of the scopes grants the permission.
+func ScopesAllow(scopes []string, permission string) bool {
+	for _, s := range scopes {
+		for _, p := range scopePermissions[s] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package response
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -19,9 +21,20 @@ type Connections struct {
 }
 
 type Error struct {
-	Code   int    `json:"code"`
-	Status bool   `json:"status"`
-	Error  string `json:"error"`
+	Code      int    `json:"code"`
+	Status    bool   `json:"status"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// Errors that know how they should be reported, such as exchange
+// rejections, override the status passed to ErrorMessage.
+type httpStatusError interface {
+	HTTPStatus() int
+}
+
+type codedError interface {
+	ErrorCode() string
 }
 
 type Warning struct {
@@ -49,11 +62,19 @@ func SuccessResponse(c *fiber.Ctx, message string, data interface{}) error {
 }
 
 func ErrorMessage(c *fiber.Ctx, code int, e error) error {
+	var se httpStatusError
+	if errors.As(e, &se) {
+		code = se.HTTPStatus()
+	}
 	errMsg := Error{
 		Status: false,
 		Code:   code,
 		Error:  e.Error(),
 	}
+	var ce codedError
+	if errors.As(e, &ce) {
+		errMsg.ErrorCode = ce.ErrorCode()
+	}
 	return JsonResponse(c, code, errMsg)
 }
 
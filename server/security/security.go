@@ -1,8 +1,15 @@
 package security
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"io"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -27,3 +34,72 @@ func GenerateAPIKey() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// HashToken returns the hex SHA-256 of a high-entropy token, for storing
+// secrets that only ever need to be looked up, never recovered.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign returns the hex HMAC-SHA256 of payload keyed with secret.
+func Sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature compares a hex signature against the expected one in
+// constant time.
+func VerifySignature(secret, payload, signature string) bool {
+	expected := Sign(secret, payload)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Encrypt seals plaintext with AES-256-GCM under a key derived from
+// passphrase. The result is base64 of nonce followed by ciphertext.
+func Encrypt(passphrase, plaintext string) (string, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with the same passphrase.
+func Decrypt(passphrase, encoded string) (string, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(passphrase string) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("encryption key is not configured")
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -8,15 +8,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
-const (
-	BaseURL   = "https://testnet.binance.vision"
-	APIKey    = "b5J1Wa8FEi7TVeeO6jLOsWBdEdmPsGmwB4gxJXT0F4YKlQx3VXmTtSeVn4Wuhwta"
-	SecretKey = "npG6JapFCZ9B3iA39ODdpdOkLOOzqoK8S1O5E7I9vgXJ1Axh28ICpcf3c2Rmfd8f"
+const BaseURL = "https://testnet.binance.vision"
+
+// Testnet keys come from the same variables the server reads.
+var (
+	APIKey    = os.Getenv("EXDEX_BINANCE_APIKEY")
+	SecretKey = os.Getenv("EXDEX_BINANCE_APISECRET")
 )
 
 type OrderResponse struct {
@@ -231,6 +234,10 @@ func GetSymbolInfo(symbol string) (map[string]interface{}, error) {
 func main() {
 	fmt.Println("Binance Order Management System")
 	fmt.Println("===============================")
+	if APIKey == "" || SecretKey == "" {
+		fmt.Println("Set EXDEX_BINANCE_APIKEY and EXDEX_BINANCE_APISECRET to your testnet keys")
+		os.Exit(1)
+	}
 
 	// Example usage - uncomment to test
 	/*
@@ -2,8 +2,9 @@ module main
 
 go 1.24.1
 
+require github.com/gorilla/websocket v1.5.3
+
 require (
-	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/tidwall/gjson v1.18.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
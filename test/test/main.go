@@ -18,12 +18,16 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// Configuration - Hardcoded for Testnet
+// Configuration - Testnet endpoints; the keys come from the same variables
+// the server reads.
 const (
 	BaseURL   = "https://testnet.binance.vision"
 	WSBaseURL = "wss://stream.testnet.binance.vision:9443/ws/"
-	APIKey    = "b5J1Wa8FEi7TVeeO6jLOsWBdEdmPsGmwB4gxJXT0F4YKlQx3VXmTtSeVn4Wuhwta"
-	SecretKey = "npG6JapFCZ9B3iA39ODdpdOkLOOzqoK8S1O5E7I9vgXJ1Axh28ICpcf3c2Rmfd8f"
+)
+
+var (
+	APIKey    = os.Getenv("EXDEX_BINANCE_APIKEY")
+	SecretKey = os.Getenv("EXDEX_BINANCE_APISECRET")
 )
 
 type ListenKeyResponse struct {
@@ -266,6 +270,9 @@ func handleExecutionReport(report ExecutionReport) {
 }
 
 func main() {
+	if APIKey == "" || SecretKey == "" {
+		log.Fatal("Set EXDEX_BINANCE_APIKEY and EXDEX_BINANCE_APISECRET to your testnet keys")
+	}
 	client := NewBinanceClient()
 
 	log.Println("Getting listen key...")